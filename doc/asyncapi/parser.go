@@ -0,0 +1,185 @@
+package asyncapi
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/tencent-go/pkg/doc/natsdoc"
+	"github.com/tencent-go/pkg/doc/openapi"
+	"github.com/tencent-go/pkg/doc/schema"
+	"github.com/tencent-go/pkg/doc/wsdoc"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	natsServer      = "nats"
+	websocketServer = "websocket"
+
+	natsHeadersSchemaKey = "natsx.Header"
+)
+
+// invalidKeyChars are not allowed in component keys, e.g. the braces of subject placeholders.
+var invalidKeyChars = regexp.MustCompile(`[^a-zA-Z0-9.\-_]+`)
+
+// invalidIDChars are replaced in operation ids, so that they can be used as identifiers by code generators.
+var invalidIDChars = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+func NewDefault() *AsyncAPI {
+	schemas := openapi.NewDefault()
+	return &AsyncAPI{
+		AsyncAPI: "2.6.0",
+		Info: Info{
+			Title:   "Async API Documentation",
+			Version: "1.0.0",
+		},
+		Servers:  make(map[string]*Server),
+		Channels: make(map[string]*ChannelItem),
+		Components: &Components{
+			Schemas:  schemas.Components.Schemas,
+			Messages: make(map[string]*Message),
+		},
+		schemas: schemas,
+	}
+}
+
+// ParseNats adds the subjects as channels of the nats server, every subject can be published and subscribed
+// by the services sharing its definition. Placeholders of the subject become channel parameters.
+func (spec *AsyncAPI) ParseNats(subjects []natsdoc.Subject) *AsyncAPI {
+	if len(subjects) == 0 {
+		return spec
+	}
+	spec.Servers[natsServer] = &Server{
+		URL:       "{host}",
+		Protocol:  "nats",
+		Variables: map[string]ServerVariable{"host": {Default: "localhost:4222"}},
+	}
+	spec.Tags = append(spec.Tags, Tag{Name: natsServer})
+	for _, subject := range subjects {
+		if _, ok := spec.Channels[subject.Subject]; ok {
+			logrus.Warnf("asyncapi channel %s is defined twice", subject.Subject)
+			continue
+		}
+		msg := &Message{
+			Name:        subject.Subject,
+			Description: subject.Description,
+			ContentType: "application/json",
+			Headers:     &openapi.Schema{Ref: "#/components/schemas/" + spec.natsHeadersSchema()},
+			Payload:     spec.payloadSchema(subject.Type),
+		}
+		ch := &ChannelItem{
+			Description: subject.Description,
+			Servers:     []string{natsServer},
+			Stream:      subject.Stream,
+			Subscribe:   spec.operation("subscribe", natsServer, subject.Subject, msg),
+			Publish:     spec.operation("publish", natsServer, subject.Subject, msg),
+		}
+		for _, placeholder := range subject.Placeholders {
+			if ch.Parameters == nil {
+				ch.Parameters = make(map[string]Parameter)
+			}
+			ch.Parameters[placeholder] = Parameter{Schema: &openapi.Schema{Type: "string"}}
+		}
+		spec.Channels[subject.Subject] = ch
+	}
+	return spec
+}
+
+// ParseWebsocket adds the topics of the websocket server, subscribable channels are sent to the clients
+// and publishable channels are sent by them. Messages are msgpack encoded and wrapped with their topic.
+func (spec *AsyncAPI) ParseWebsocket(subscribable []wsdoc.EventChannel, publishable []wsdoc.EventChannel) *AsyncAPI {
+	if len(subscribable) == 0 && len(publishable) == 0 {
+		return spec
+	}
+	spec.Servers[websocketServer] = &Server{
+		URL:       "{host}",
+		Protocol:  "ws",
+		Variables: map[string]ServerVariable{"host": {Default: "localhost"}},
+	}
+	spec.Tags = append(spec.Tags, Tag{Name: websocketServer})
+	channel := func(channel wsdoc.EventChannel) *ChannelItem {
+		ch, ok := spec.Channels[channel.Topic]
+		if !ok {
+			ch = &ChannelItem{
+				Description: channel.Description,
+				Servers:     []string{websocketServer},
+			}
+			for _, placeholder := range channel.Placeholders {
+				if ch.Parameters == nil {
+					ch.Parameters = make(map[string]Parameter)
+				}
+				ch.Parameters[placeholder] = Parameter{Schema: &openapi.Schema{Type: "string"}}
+			}
+			spec.Channels[channel.Topic] = ch
+		} else if ch.Servers[0] != websocketServer {
+			logrus.Warnf("asyncapi channel %s is defined twice", channel.Topic)
+			return nil
+		}
+		return ch
+	}
+	message := func(channel wsdoc.EventChannel) *Message {
+		topic := &openapi.Schema{Type: "string", Enum: []any{channel.Topic}}
+		if len(channel.Placeholders) > 0 {
+			topic = &openapi.Schema{Type: "string", Description: "topic with the placeholders replaced"}
+		}
+		return &Message{
+			Name:        channel.Topic,
+			Description: channel.Description,
+			ContentType: "application/msgpack",
+			Payload: &openapi.Schema{
+				Type:     "object",
+				Required: []string{"topic", "data"},
+				Properties: map[string]*openapi.Schema{
+					"topic": topic,
+					"data":  spec.payloadSchema(channel.Type),
+				},
+			},
+		}
+	}
+	for _, c := range subscribable {
+		if ch := channel(c); ch != nil {
+			ch.Subscribe = spec.operation("send", websocketServer, c.Topic, message(c))
+		}
+	}
+	for _, c := range publishable {
+		if ch := channel(c); ch != nil {
+			ch.Publish = spec.operation("receive", websocketServer, c.Topic, message(c))
+		}
+	}
+	return spec
+}
+
+func (spec *AsyncAPI) operation(action, server, channel string, msg *Message) *Operation {
+	key := invalidKeyChars.ReplaceAllString(server+"."+channel, "_")
+	spec.Components.Messages[key] = msg
+	return &Operation{
+		OperationID: strings.Trim(invalidIDChars.ReplaceAllString(action+"_"+server+"_"+channel, "_"), "_"),
+		Summary:     msg.Description,
+		Tags:        []Tag{{Name: server}},
+		Message:     &Message{Ref: "#/components/messages/" + key},
+	}
+}
+
+func (spec *AsyncAPI) payloadSchema(t *schema.Type) *openapi.Schema {
+	if t == nil {
+		return &openapi.Schema{}
+	}
+	return spec.schemas.TypeSchema(*t)
+}
+
+// natsHeadersSchema describes the headers set by the natsx publishers.
+func (spec *AsyncAPI) natsHeadersSchema() string {
+	schemas := spec.Components.Schemas
+	if _, ok := schemas[natsHeadersSchemaKey]; !ok {
+		schemas[natsHeadersSchemaKey] = &openapi.Schema{
+			Type: "object",
+			Properties: map[string]*openapi.Schema{
+				"traceId":     {Type: "string"},
+				"operator":    {Type: "string"},
+				"caller":      {Type: "string"},
+				"locale":      {Type: "string"},
+				"Nats-Msg-Id": {Type: "string", Description: "Deduplication id of stream messages"},
+			},
+		}
+	}
+	return natsHeadersSchemaKey
+}
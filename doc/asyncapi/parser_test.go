@@ -0,0 +1,98 @@
+package asyncapi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tencent-go/pkg/doc/natsdoc"
+	"github.com/tencent-go/pkg/doc/schema"
+	"github.com/tencent-go/pkg/doc/wsdoc"
+	"github.com/tencent-go/pkg/util"
+	"github.com/stretchr/testify/assert"
+)
+
+type orderPaid struct {
+	OrderID string `json:"orderId"`
+	Amount  int64  `json:"amount"`
+}
+
+func TestParseNats(t *testing.T) {
+	typ, _ := schema.NewCollection().ParseAndGetType(reflect.TypeOf(orderPaid{}), util.TagJson)
+	spec := NewDefault().ParseNats([]natsdoc.Subject{
+		{Subject: "order.{orderId}.paid", Placeholders: []string{"orderId"}, Stream: "ORDER", Type: typ, Description: "訂單已付款"},
+		{Subject: "order.{orderId}.paid", Description: "重複定義"},
+	})
+
+	assert.Equal(t, "nats", spec.Servers[natsServer].Protocol)
+	assert.Len(t, spec.Channels, 1)
+	ch := spec.Channels["order.{orderId}.paid"]
+	if !assert.NotNil(t, ch) {
+		return
+	}
+
+	t.Run("操作方向", func(t *testing.T) {
+		assert.Equal(t, "subscribe_nats_order_orderId_paid", ch.Subscribe.OperationID)
+		assert.Equal(t, "publish_nats_order_orderId_paid", ch.Publish.OperationID)
+		assert.Equal(t, "訂單已付款", ch.Subscribe.Summary)
+	})
+
+	t.Run("參數與訊息", func(t *testing.T) {
+		assert.Equal(t, "ORDER", ch.Stream)
+		if assert.Contains(t, ch.Parameters, "orderId") {
+			assert.Equal(t, "string", ch.Parameters["orderId"].Schema.Type)
+		}
+
+		assert.Equal(t, "#/components/messages/nats.order._orderId_.paid", ch.Subscribe.Message.Ref)
+		msg := spec.Components.Messages["nats.order._orderId_.paid"]
+		if assert.NotNil(t, msg) {
+			assert.Equal(t, "#/components/schemas/"+natsHeadersSchemaKey, msg.Headers.Ref)
+			assert.NotNil(t, msg.Payload)
+		}
+		assert.Contains(t, spec.Components.Schemas, natsHeadersSchemaKey)
+	})
+}
+
+func TestParseWebsocket(t *testing.T) {
+	sc := schema.NewCollection()
+	typ, _ := sc.ParseAndGetType(reflect.TypeOf(orderPaid{}), util.TagJson)
+	spec := NewDefault().ParseWebsocket(
+		[]wsdoc.EventChannel{{Topic: "order.{orderId}", Placeholders: []string{"orderId"}, Type: typ}},
+		[]wsdoc.EventChannel{{Topic: "order.{orderId}", Placeholders: []string{"orderId"}, Type: typ}, {Topic: "chat"}},
+	)
+
+	assert.Len(t, spec.Channels, 2)
+	ch := spec.Channels["order.{orderId}"]
+	if !assert.NotNil(t, ch) {
+		return
+	}
+	assert.Equal(t, "send_websocket_order_orderId", ch.Subscribe.OperationID)
+	assert.Equal(t, "receive_websocket_order_orderId", ch.Publish.OperationID)
+	assert.Contains(t, ch.Parameters, "orderId")
+
+	t.Run("主題含佔位符", func(t *testing.T) {
+		msg := spec.Components.Messages["websocket.order._orderId_"]
+		if assert.NotNil(t, msg) {
+			assert.Nil(t, msg.Payload.Properties["topic"].Enum)
+		}
+	})
+
+	t.Run("固定主題", func(t *testing.T) {
+		chat := spec.Channels["chat"]
+		if assert.NotNil(t, chat) {
+			assert.Nil(t, chat.Subscribe)
+			assert.Equal(t, "receive_websocket_chat", chat.Publish.OperationID)
+		}
+		msg := spec.Components.Messages["websocket.chat"]
+		if assert.NotNil(t, msg) {
+			assert.Equal(t, []any{"chat"}, msg.Payload.Properties["topic"].Enum)
+		}
+	})
+
+	t.Run("與nats重複的通道", func(t *testing.T) {
+		spec := NewDefault().
+			ParseNats([]natsdoc.Subject{{Subject: "chat"}}).
+			ParseWebsocket([]wsdoc.EventChannel{{Topic: "chat"}}, nil)
+		assert.Equal(t, []string{natsServer}, spec.Channels["chat"].Servers)
+		assert.Equal(t, "subscribe_nats_chat", spec.Channels["chat"].Subscribe.OperationID)
+	})
+}
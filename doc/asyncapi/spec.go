@@ -0,0 +1,94 @@
+package asyncapi
+
+import "github.com/tencent-go/pkg/doc/openapi"
+
+// AsyncAPI represents the root of an AsyncAPI 2.6 document
+type AsyncAPI struct {
+	AsyncAPI           string                  `json:"asyncapi"` // Version of AsyncAPI spec
+	Info               Info                    `json:"info"`
+	Servers            map[string]*Server      `json:"servers,omitempty"`
+	DefaultContentType string                  `json:"defaultContentType,omitempty"`
+	Channels           map[string]*ChannelItem `json:"channels"`
+	Components         *Components             `json:"components,omitempty"`
+	Tags               []Tag                   `json:"tags,omitempty"`
+	ExternalDocs       *ExternalDocumentation  `json:"externalDocs,omitempty"`
+
+	schemas *openapi.OpenAPI
+}
+
+// Info represents metadata about the API
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// Server represents a message broker or endpoint the channels are reached through
+type Server struct {
+	URL         string                    `json:"url"`
+	Protocol    string                    `json:"protocol"`
+	Description string                    `json:"description,omitempty"`
+	Variables   map[string]ServerVariable `json:"variables,omitempty"`
+}
+
+// ServerVariable represents a server variable for URL template substitution
+type ServerVariable struct {
+	Default     string `json:"default,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// ChannelItem describes the operations available on a single channel
+type ChannelItem struct {
+	Description string               `json:"description,omitempty"`
+	Servers     []string             `json:"servers,omitempty"`
+	Parameters  map[string]Parameter `json:"parameters,omitempty"`
+	Subscribe   *Operation           `json:"subscribe,omitempty"` // Messages the application sends
+	Publish     *Operation           `json:"publish,omitempty"`   // Messages the application receives
+	Stream      string               `json:"x-stream,omitempty"`  // JetStream stream storing the channel
+}
+
+// Parameter describes a placeholder of the channel name
+type Parameter struct {
+	Description string          `json:"description,omitempty"`
+	Schema      *openapi.Schema `json:"schema,omitempty"`
+	Location    string          `json:"location,omitempty"`
+}
+
+// Operation describes a publish or subscribe operation of a channel
+type Operation struct {
+	OperationID string   `json:"operationId,omitempty"`
+	Summary     string   `json:"summary,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Tags        []Tag    `json:"tags,omitempty"`
+	Message     *Message `json:"message,omitempty"`
+}
+
+// Message describes a message sent through a channel
+type Message struct {
+	Ref         string          `json:"$ref,omitempty"`
+	Name        string          `json:"name,omitempty"`
+	Title       string          `json:"title,omitempty"`
+	Summary     string          `json:"summary,omitempty"`
+	Description string          `json:"description,omitempty"`
+	ContentType string          `json:"contentType,omitempty"`
+	Headers     *openapi.Schema `json:"headers,omitempty"`
+	Payload     *openapi.Schema `json:"payload,omitempty"`
+}
+
+// Components holds a set of reusable objects
+type Components struct {
+	Schemas  map[string]*openapi.Schema `json:"schemas,omitempty"`
+	Messages map[string]*Message        `json:"messages,omitempty"`
+}
+
+// Tag adds metadata to a single tag
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// ExternalDocumentation allows referencing external documentation
+type ExternalDocumentation struct {
+	Description string `json:"description,omitempty"`
+	URL         string `json:"url"`
+}
@@ -0,0 +1,361 @@
+package godoc
+
+import (
+	"path"
+
+	"github.com/tencent-go/pkg/util"
+)
+
+// NewClientFile generates the package "client" shared by the rest and rpc clients. Its JSON
+// fields are matched case-insensitively so it needs no struct tags.
+func NewClientFile(parentDir ...string) util.DataFile {
+	var clientTmp = generatedHeader + `
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Violation is one failed validation rule of a request field.
+type Violation struct {
+	Field   string
+	Rule    string
+	Params  map[string]string
+	Message string
+}
+
+// Error is the error answered by the server. Type is one of internal, not_found, validation,
+// authentication, authorization, rate_limit, network, timeout, concurrency, business or conflict.
+type Error struct {
+	Status     int
+	Code       int
+	Message    string
+	Type       string
+	Violations []Violation
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s error (status %d)", e.Type, e.Status)
+	}
+	return e.Message
+}
+
+// Client sends the requests to BaseURL. Header is added to every request, e.g. Authorization.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	Header     http.Header
+}
+
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: http.DefaultClient,
+		Header:     make(http.Header),
+	}
+}
+
+// Request describes a rest call, PathParams, Query, Header and Body are structs tagged
+// with path, query, header and json or form.
+type Request struct {
+	Method              string
+	Path                string
+	PathParams          any
+	Query               any
+	Header              any
+	Body                any
+	ContentType         string
+	ResponseContentType string
+	Wrapped             bool
+}
+
+// Do sends a rest request and decodes the response into out, a *[]byte for binary content.
+func (c *Client) Do(ctx context.Context, req Request, out any) error {
+	p := req.Path
+	if req.PathParams != nil {
+		for k, vs := range encodeValues(req.PathParams, "path") {
+			p = strings.ReplaceAll(p, "{"+k+"}", url.PathEscape(vs[0]))
+		}
+	}
+	u := strings.TrimRight(c.BaseURL, "/") + p
+	if req.Query != nil {
+		if q := encodeValues(req.Query, "query"); len(q) > 0 {
+			u += "?" + q.Encode()
+		}
+	}
+	var body []byte
+	if req.Body != nil {
+		switch req.ContentType {
+		case "application/json":
+			b, err := json.Marshal(req.Body)
+			if err != nil {
+				return err
+			}
+			body = b
+		case "application/x-www-form-urlencoded":
+			body = []byte(encodeValues(req.Body, "form").Encode())
+		default:
+			b, ok := req.Body.([]byte)
+			if !ok {
+				return fmt.Errorf("body of %s must be []byte", req.ContentType)
+			}
+			body = b
+		}
+	}
+	header := make(http.Header)
+	if req.Header != nil {
+		for k, vs := range encodeValues(req.Header, "header") {
+			for _, v := range vs {
+				header.Add(k, v)
+			}
+		}
+	}
+	if body != nil {
+		header.Set("Content-Type", req.ContentType)
+	}
+	status, data, err := c.send(ctx, req.Method, u, header, body)
+	if err != nil {
+		return err
+	}
+	if req.Wrapped {
+		var w struct {
+			Success bool
+			Data    json.RawMessage
+			Error   *Error
+		}
+		if err = json.Unmarshal(data, &w); err != nil {
+			if status >= http.StatusBadRequest {
+				return statusError(status, data)
+			}
+			return err
+		}
+		if !w.Success {
+			if w.Error == nil {
+				return statusError(status, data)
+			}
+			w.Error.Status = status
+			return w.Error
+		}
+		data = w.Data
+	} else if status >= http.StatusBadRequest {
+		return statusError(status, data)
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	switch req.ResponseContentType {
+	case "application/json":
+		return json.Unmarshal(data, out)
+	case "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(data))
+		if err != nil {
+			return err
+		}
+		return decodeValues(values, "form", out)
+	}
+	b, ok := out.(*[]byte)
+	if !ok {
+		return fmt.Errorf("output of %s must be []byte", req.ResponseContentType)
+	}
+	*b = data
+	return nil
+}
+
+// Call sends a rpc request as JSON, the server must be reachable through a json transcoder.
+func (c *Client) Call(ctx context.Context, path string, in any, out any) error {
+	var body []byte
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = b
+	}
+	header := make(http.Header)
+	header.Set("Content-Type", "application/json")
+	status, data, err := c.send(ctx, http.MethodPost, strings.TrimRight(c.BaseURL, "/")+path, header, body)
+	if err != nil {
+		return err
+	}
+	if status >= http.StatusBadRequest {
+		e := &Error{}
+		if err = json.Unmarshal(data, e); err != nil {
+			return statusError(status, data)
+		}
+		e.Status = status
+		return e
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+func (c *Client) send(ctx context.Context, method, u string, header http.Header, body []byte) (int, []byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	r, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return 0, nil, err
+	}
+	for k, vs := range c.Header {
+		for _, v := range vs {
+			r.Header.Add(k, v)
+		}
+	}
+	for k, vs := range header {
+		r.Header[k] = vs
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, data, err
+}
+
+func statusError(status int, body []byte) *Error {
+	e := &Error{Status: status, Type: "business", Message: strings.TrimSpace(string(body))}
+	switch {
+	case status == http.StatusUnauthorized:
+		e.Type = "authentication"
+	case status == http.StatusForbidden:
+		e.Type = "authorization"
+	case status == http.StatusNotFound:
+		e.Type = "not_found"
+	case status == http.StatusTooManyRequests:
+		e.Type = "rate_limit"
+	case status >= http.StatusInternalServerError:
+		e.Type = "internal"
+	}
+	if e.Message == "" {
+		e.Message = http.StatusText(status)
+	}
+	return e
+}
+
+func encodeValues(v any, tag string) url.Values {
+	res := make(url.Values)
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return res
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return res
+	}
+	for i := 0; i < rv.NumField(); i++ {
+		name, opts, _ := strings.Cut(rv.Type().Field(i).Tag.Get(tag), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		f := rv.Field(i)
+		if f.Kind() == reflect.Ptr {
+			if f.IsNil() {
+				continue
+			}
+			f = f.Elem()
+		}
+		if opts == "omitempty" && f.IsZero() {
+			continue
+		}
+		if f.Kind() == reflect.Slice || f.Kind() == reflect.Array {
+			for j := 0; j < f.Len(); j++ {
+				res.Add(name, fmt.Sprint(f.Index(j).Interface()))
+			}
+			continue
+		}
+		res.Add(name, fmt.Sprint(f.Interface()))
+	}
+	return res
+}
+
+func decodeValues(values url.Values, tag string, out any) error {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("output must be a pointer to struct")
+	}
+	rv = rv.Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		name, _, _ := strings.Cut(rv.Type().Field(i).Tag.Get(tag), ",")
+		vs, ok := values[name]
+		if name == "" || !ok {
+			continue
+		}
+		f := rv.Field(i)
+		if f.Kind() == reflect.Slice {
+			s := reflect.MakeSlice(f.Type(), len(vs), len(vs))
+			for j, v := range vs {
+				if err := setValue(s.Index(j), v); err != nil {
+					return err
+				}
+			}
+			f.Set(s)
+			continue
+		}
+		if err := setValue(f, vs[0]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setValue(f reflect.Value, s string) error {
+	if f.Kind() == reflect.Ptr {
+		f.Set(reflect.New(f.Type().Elem()))
+		f = f.Elem()
+	}
+	switch {
+	case f.Kind() == reflect.String:
+		f.SetString(s)
+	case f.Kind() == reflect.Bool:
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		f.SetBool(v)
+	case f.CanInt():
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		f.SetInt(v)
+	case f.CanUint():
+		v, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		f.SetUint(v)
+	case f.CanFloat():
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		f.SetFloat(v)
+	default:
+		return fmt.Errorf("unsupported form field type %s", f.Type())
+	}
+	return nil
+}
+`
+	return newGoFile(path.Join(append(parentDir, "client")...), "client.go", []byte(clientTmp))
+}
@@ -0,0 +1,130 @@
+package godoc
+
+import (
+	"fmt"
+	"go/format"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/tencent-go/pkg/doc/schema"
+	"github.com/tencent-go/pkg/util"
+	"github.com/sirupsen/logrus"
+)
+
+const generatedHeader = "// Code generated by doc/godoc. DO NOT EDIT.\n"
+
+// goName converts names such as "get_item", "user-list" or "chat.message" to an exported Go identifier.
+func goName(s string) string {
+	res := camel(s)
+	if res == "" || unicode.IsDigit(rune(res[0])) {
+		res = "X" + res
+	}
+	return res
+}
+
+func camel(s string) string {
+	parts := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b strings.Builder
+	for _, p := range parts {
+		runes := []rune(p)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+	return b.String()
+}
+
+func typeName(pkg *schema.Package, name string) string {
+	if pkg == nil {
+		return goName(name)
+	}
+	return goName(pkg.Name) + goName(name)
+}
+
+// goType returns the Go type of t, rt is the original type used to keep the kind of numbers.
+// Classes and enums are prefixed by qualifier, e.g. "schema.".
+func goType(t schema.Type, rt reflect.Type, qualifier string) string {
+	for rt != nil && rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	var res string
+	if t.Enum != nil {
+		res = qualifier + typeName(t.Enum.Package, t.Enum.Name)
+	} else {
+		switch t.BaseType {
+		case schema.BaseTypeArray:
+			res = "[]any"
+			if t.Array != nil {
+				res = "[]" + goType(*t.Array, elemType(rt), qualifier)
+			}
+		case schema.BaseTypeMap:
+			res = "map[string]any"
+			if t.Map != nil {
+				var kt reflect.Type
+				if rt != nil && rt.Kind() == reflect.Map {
+					kt = rt.Key()
+				}
+				res = fmt.Sprintf("map[%s]%s", goType(t.Map.KeyType, kt, qualifier), goType(t.Map.ValueType, elemType(rt), qualifier))
+			}
+		case schema.BaseTypeClass:
+			res = "map[string]any"
+			if t.Class != nil {
+				res = qualifier + typeName(t.Class.Package, t.Class.Name)
+			}
+		case schema.BaseTypeString:
+			res = "string"
+		case schema.BaseTypeNumber:
+			res = numberType(rt)
+		case schema.BaseTypeBoolean:
+			res = "bool"
+		case schema.BaseTypeNull:
+			res = "struct{}"
+		default:
+			return "any"
+		}
+	}
+	if t.Nullable && t.BaseType != schema.BaseTypeArray && t.BaseType != schema.BaseTypeMap {
+		res = "*" + res
+	}
+	return res
+}
+
+func elemType(rt reflect.Type) reflect.Type {
+	if rt == nil {
+		return nil
+	}
+	switch rt.Kind() {
+	case reflect.Array, reflect.Slice, reflect.Map:
+		return rt.Elem()
+	}
+	return nil
+}
+
+func numberType(rt reflect.Type) string {
+	if rt != nil {
+		if k := rt.Kind(); k >= reflect.Int && k <= reflect.Float64 && k != reflect.Uintptr {
+			return k.String()
+		}
+	}
+	return "float64"
+}
+
+// newGoFile formats src, a file which does not compile is kept as is to be inspected.
+func newGoFile(dir, name string, src []byte) util.DataFile {
+	data, err := format.Source(src)
+	if err != nil {
+		logrus.WithError(err).Errorf("format generated go file %s/%s failed", dir, name)
+		data = src
+	}
+	return util.DataFile{
+		Dir:  dir,
+		Name: name,
+		Data: data,
+	}
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
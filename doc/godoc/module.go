@@ -0,0 +1,41 @@
+package godoc
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/tencent-go/pkg/doc/restdoc"
+	"github.com/tencent-go/pkg/doc/rpcdoc"
+	"github.com/tencent-go/pkg/doc/schema"
+	"github.com/tencent-go/pkg/doc/wsdoc"
+	"github.com/tencent-go/pkg/util"
+)
+
+// Module is a standalone Go module generated from the doc models. It depends on the standard
+// library only, so that services outside this repository can use it.
+type Module struct {
+	Path      string // module path of go.mod, e.g. "example.com/shop/apiclient"
+	Packages  []*schema.Package
+	Rest      []restdoc.Group
+	Rpc       []rpcdoc.Group
+	Websocket []wsdoc.EventChannel
+}
+
+func NewModuleFiles(m Module, parentDir ...string) []util.DataFile {
+	dir := path.Join(parentDir...)
+	res := []util.DataFile{
+		{
+			Dir:  dir,
+			Name: "go.mod",
+			Data: []byte(fmt.Sprintf("module %s\n\ngo 1.21\n", m.Path)),
+		},
+		NewClientFile(parentDir...),
+	}
+	res = append(res, NewSchemaFiles(m.Packages, parentDir...)...)
+	res = append(res, NewRestApiFiles(m.Path, m.Rest, parentDir...)...)
+	res = append(res, NewRpcApiFiles(m.Path, m.Rpc, parentDir...)...)
+	if len(m.Websocket) > 0 {
+		res = append(res, NewEventFile(m.Path, m.Websocket, parentDir...))
+	}
+	return res
+}
@@ -0,0 +1,91 @@
+package godoc
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tencent-go/pkg/doc/restdoc"
+	"github.com/tencent-go/pkg/doc/rpcdoc"
+	"github.com/tencent-go/pkg/doc/schema"
+	"github.com/tencent-go/pkg/doc/wsdoc"
+	"github.com/tencent-go/pkg/rest/api"
+	"github.com/tencent-go/pkg/rpc"
+	"github.com/tencent-go/pkg/types"
+	"github.com/tencent-go/pkg/wsx"
+	"github.com/stretchr/testify/assert"
+)
+
+type temperature int
+
+func (temperature) Enum() types.Enum {
+	return types.RegisterEnum[temperature](-1, 0, 1)
+}
+
+type weatherQuery struct {
+	City   string       `query:"city"`
+	Locale types.Locale `query:"locale"`
+}
+
+type weather struct {
+	City        string      `json:"city"`
+	Temperature temperature `json:"temperature"`
+	Tags        []string    `json:"tags"`
+}
+
+type weatherUpdate struct {
+	City string `json:"city"`
+}
+
+func TestEnumValueName(t *testing.T) {
+	assert.Equal(t, "ZhCN", enumValueName("zh-CN"))
+	assert.Equal(t, "OrderPaid", enumValueName("order.paid"))
+	assert.Equal(t, "Neg1", enumValueName(temperature(-1)))
+	assert.Equal(t, "1Dot5", enumValueName(1.5))
+	assert.Equal(t, "Empty", enumValueName(""))
+}
+
+func TestModuleBuilds(t *testing.T) {
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go toolchain not found")
+	}
+	sc := schema.NewCollection()
+	get := api.NewEndpoint[weatherQuery, weather]().WithPath("weather").WithName("get").WithMethod(api.MethodGet)
+	group := api.DefaultGroup().WithPath("api").WithChildren(get)
+	m := Module{
+		Path: "example.com/weatherclient",
+		Rest: restdoc.NewGroups(sc, group.Routes(), nil),
+		Rpc: rpcdoc.NewGroup(sc, []rpc.Group{{
+			Path:   "weather",
+			Routes: []rpc.Route{rpc.ProxyRoute("", rpc.NewMethod[weatherQuery, weather]("get"))},
+		}}),
+		Websocket: wsdoc.NewGroups(sc, []wsx.EventChannel{
+			wsx.NewEventChannel[weatherUpdate]("weather.{city}"),
+		}),
+	}
+	m.Packages = sc.Packages()
+
+	dir := t.TempDir()
+	var src strings.Builder
+	for _, f := range NewModuleFiles(m) {
+		p := filepath.Join(dir, f.Dir, f.Name)
+		if !assert.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755)) {
+			return
+		}
+		if !assert.NoError(t, os.WriteFile(p, f.Data, 0o644)) {
+			return
+		}
+		src.Write(f.Data)
+	}
+	assert.Contains(t, src.String(), "TypesLocaleEnumZhCN")
+	assert.Contains(t, src.String(), "GodocTemperatureEnumNeg1")
+
+	cmd := exec.Command(gobin, "vet", "./...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOWORK=off", "GOFLAGS=-mod=mod", "GOPROXY=off")
+	out, err := cmd.CombinedOutput()
+	assert.NoError(t, err, string(out))
+}
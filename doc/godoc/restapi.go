@@ -0,0 +1,159 @@
+package godoc
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/tencent-go/pkg/doc/restdoc"
+	"github.com/tencent-go/pkg/doc/schema"
+	"github.com/tencent-go/pkg/rest/api"
+	"github.com/tencent-go/pkg/util"
+	"github.com/sirupsen/logrus"
+)
+
+var groupTmp = generatedHeader + `
+package {{.Package}}
+
+import (
+	"context"
+
+	"{{.Module}}/client"
+{{- if .UseSchema}}
+	"{{.Module}}/schema"
+{{- end}}
+)
+
+{{if .Description}}// {{.Name}} {{.Description}}
+{{end -}}
+type {{.Name}} struct {
+	c *client.Client
+}
+
+func New{{.Name}}(c *client.Client) *{{.Name}} {
+	return &{{.Name}}{c: c}
+}
+{{range .Funcs}}
+{{if .Description}}// {{.Name}} {{.Description}}
+{{end -}}
+func (a *{{$.Name}}) {{.Name}}(ctx context.Context{{.Params}}) {{.Returns}} {
+{{.Body}}
+}
+{{end}}
+`
+
+type groupData struct {
+	Package     string
+	Module      string
+	Name        string
+	Description string
+	UseSchema   bool
+	Funcs       []funcData
+}
+
+type funcData struct {
+	Name        string
+	Description string
+	Params      string
+	Returns     string
+	Body        string
+}
+
+func (g *groupData) execute(t *template.Template, dir, name string) util.DataFile {
+	g.Description = oneLine(g.Description)
+	for i, f := range g.Funcs {
+		g.Funcs[i].Description = oneLine(f.Description)
+		if strings.Contains(f.Params+f.Returns+f.Body, "schema.") {
+			g.UseSchema = true
+		}
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, g); err != nil {
+		logrus.Fatalf("execute go client template failed: %v", err)
+	}
+	return newGoFile(dir, name, buf.Bytes())
+}
+
+// NewRestApiFiles generates a type per group in the package "restapi", e.g. NewUserApi(client.New(url)).GetProfile(ctx, ...).
+func NewRestApiFiles(module string, groups []restdoc.Group, parentDir ...string) []util.DataFile {
+	t, err := template.New("go_restapi").Parse(groupTmp)
+	if err != nil {
+		logrus.Fatalf("parse go client template failed: %v", err)
+	}
+	dir := path.Join(append(parentDir, "restapi")...)
+	var res []util.DataFile
+	for _, group := range groups {
+		g := &groupData{
+			Package:     "restapi",
+			Module:      module,
+			Name:        goName(group.Name) + "Api",
+			Description: group.Description,
+		}
+		for _, endpoint := range group.Endpoints {
+			g.Funcs = append(g.Funcs, newEndpointFunc(endpoint))
+		}
+		res = append(res, g.execute(t, dir, strings.ToLower(goName(group.Name))+".go"))
+	}
+	return res
+}
+
+func isStructured(ct api.ContentType) bool {
+	return ct == api.ContentTypeApplicationJson || ct == api.ContentTypeApplicationFormUrlencoded
+}
+
+func newEndpointFunc(e restdoc.Endpoint) funcData {
+	f := funcData{
+		Name:        goName(e.Name),
+		Description: e.Description,
+	}
+	fields := []string{
+		fmt.Sprintf("Method: %q", e.Method),
+		fmt.Sprintf("Path: %q", path.Join("/", e.Path)),
+	}
+	var params []string
+	if e.Param != nil && len(e.Param.Fields) > 0 {
+		params = append(params, "pathParams schema."+typeName(e.Param.Package, e.Param.Name))
+		fields = append(fields, "PathParams: pathParams")
+	}
+	if e.Query != nil {
+		params = append(params, "query schema."+typeName(e.Query.Package, e.Query.Name))
+		fields = append(fields, "Query: query")
+	}
+	if e.Header != nil {
+		params = append(params, "header schema."+typeName(e.Header.Package, e.Header.Name))
+		fields = append(fields, "Header: header")
+	}
+	switch {
+	case e.Body != nil:
+		params = append(params, "data "+goType(*e.Body, nil, "schema."))
+		fields = append(fields, "Body: data", fmt.Sprintf("ContentType: %q", e.RequestContentType))
+	case e.RequestContentType != "" && !isStructured(e.RequestContentType) && e.Method != api.MethodGet:
+		params = append(params, "data []byte")
+		fields = append(fields, "Body: data", fmt.Sprintf("ContentType: %q", e.RequestContentType))
+	}
+	if len(params) > 0 {
+		f.Params = ", " + strings.Join(params, ", ")
+	}
+	if e.ResponseContentType != "" {
+		fields = append(fields, fmt.Sprintf("ResponseContentType: %q", e.ResponseContentType))
+	}
+	if e.WrapOutput {
+		fields = append(fields, "Wrapped: true")
+	}
+	req := "client.Request{\n" + strings.Join(fields, ",\n") + ",\n}"
+	switch {
+	case e.ResponseContentType != "" && !isStructured(e.ResponseContentType):
+		f.Returns = "([]byte, error)"
+		f.Body = fmt.Sprintf("var res []byte\nerr := a.c.Do(ctx, %s, &res)\nreturn res, err", req)
+	case e.Response != nil && e.Response.BaseType != schema.BaseTypeNull:
+		t := strings.TrimPrefix(goType(*e.Response, nil, "schema."), "*")
+		f.Returns = fmt.Sprintf("(*%s, error)", t)
+		f.Body = fmt.Sprintf("var res %s\nif err := a.c.Do(ctx, %s, &res); err != nil {\nreturn nil, err\n}\nreturn &res, nil", t, req)
+	default:
+		f.Returns = "error"
+		f.Body = fmt.Sprintf("return a.c.Do(ctx, %s, nil)", req)
+	}
+	return f
+}
@@ -0,0 +1,58 @@
+package godoc
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/tencent-go/pkg/doc/rpcdoc"
+	"github.com/tencent-go/pkg/doc/schema"
+	"github.com/tencent-go/pkg/util"
+	"github.com/sirupsen/logrus"
+)
+
+// NewRpcApiFiles generates a type per group in the package "rpc", the methods are called as JSON
+// through rpc.NewJsonTranscoder.
+func NewRpcApiFiles(module string, groups []rpcdoc.Group, parentDir ...string) []util.DataFile {
+	t, err := template.New("go_rpc").Parse(groupTmp)
+	if err != nil {
+		logrus.Fatalf("parse go client template failed: %v", err)
+	}
+	dir := path.Join(append(parentDir, "rpc")...)
+	var res []util.DataFile
+	for _, group := range groups {
+		g := &groupData{
+			Package:     "rpc",
+			Module:      module,
+			Name:        goName(group.Name) + "Rpc",
+			Description: group.Description,
+		}
+		for _, method := range group.Methods {
+			g.Funcs = append(g.Funcs, newRpcFunc(method))
+		}
+		res = append(res, g.execute(t, dir, strings.ToLower(goName(group.Name))+".go"))
+	}
+	return res
+}
+
+func newRpcFunc(m rpcdoc.Method) funcData {
+	f := funcData{
+		Name:        goName(m.Name),
+		Description: m.Description,
+	}
+	in := "nil"
+	if m.RequestType != nil && m.RequestType.BaseType != schema.BaseTypeNull {
+		f.Params = ", data " + goType(*m.RequestType, nil, "schema.")
+		in = "data"
+	}
+	if m.ResponseType != nil && m.ResponseType.BaseType != schema.BaseTypeNull {
+		t := strings.TrimPrefix(goType(*m.ResponseType, nil, "schema."), "*")
+		f.Returns = fmt.Sprintf("(*%s, error)", t)
+		f.Body = fmt.Sprintf("var res %s\nif err := a.c.Call(ctx, %q, %s, &res); err != nil {\nreturn nil, err\n}\nreturn &res, nil", t, m.Path, in)
+	} else {
+		f.Returns = "error"
+		f.Body = fmt.Sprintf("return a.c.Call(ctx, %q, %s, nil)", m.Path, in)
+	}
+	return f
+}
@@ -0,0 +1,116 @@
+package godoc
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/tencent-go/pkg/doc/schema"
+	"github.com/tencent-go/pkg/types"
+	"github.com/tencent-go/pkg/util"
+)
+
+// NewSchemaFiles generates the classes and enums of all packages into the single Go package "schema",
+// the type names are prefixed by the package name, e.g. UserProfile for the class Profile of package User.
+func NewSchemaFiles(schemaPackages []*schema.Package, parentDir ...string) []util.DataFile {
+	dir := path.Join(append(parentDir, "schema")...)
+	var res []util.DataFile
+	for _, pkg := range schemaPackages {
+		var b strings.Builder
+		b.WriteString(generatedHeader)
+		b.WriteString("\npackage schema\n")
+		for _, e := range pkg.Enums {
+			writeEnum(&b, e)
+		}
+		for _, c := range pkg.Classes {
+			writeClass(&b, c)
+		}
+		res = append(res, newGoFile(dir, strings.ToLower(pkg.Name)+".go", []byte(b.String())))
+	}
+	return res
+}
+
+func writeClass(b *strings.Builder, c *schema.Class) {
+	fmt.Fprintf(b, "\ntype %s struct {\n", typeName(c.Package, c.Name))
+	for _, f := range c.Fields {
+		tag := f.Name
+		if f.Optional {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(b, "\t%s %s `%s:%q`\n", f.GoField.Name, goType(f.Type, f.GoField.Type, ""), c.Tag, tag)
+	}
+	b.WriteString("}\n")
+}
+
+func writeEnum(b *strings.Builder, e *schema.Enum) {
+	name := typeName(e.Package, e.Name)
+	underlying := "string"
+	if e.IsNumeric {
+		underlying = numberType(e.GoType)
+	}
+	var descriptions []string
+	for _, it := range e.Items {
+		if len(it.LocalizedInfo) != 0 {
+			if label := it.LocalizedInfo.Get(types.DefaultLocale).Label; label != "" {
+				descriptions = append(descriptions, fmt.Sprintf("%v: %s", it.Value, label))
+			}
+		}
+	}
+	b.WriteString("\n")
+	if len(descriptions) > 0 {
+		fmt.Fprintf(b, "// %s %s\n", name, oneLine(strings.Join(descriptions, ", ")))
+	}
+	fmt.Fprintf(b, "type %s %s\n", name, underlying)
+	if len(e.Items) == 0 {
+		return
+	}
+	b.WriteString("\nconst (\n")
+	used := map[string]bool{}
+	for i, it := range e.Items {
+		constName := name + enumValueName(it.Value)
+		if used[constName] {
+			constName = fmt.Sprintf("%s%d", constName, i)
+		}
+		used[constName] = true
+		value := enumValue(it.Value)
+		fmt.Fprintf(b, "\t%s %s = %s\n", constName, name, value)
+	}
+	b.WriteString(")\n")
+}
+
+// enumValue is the Go literal of v, named types are printed by kind to skip their String method.
+func enumValue(v any) string {
+	rv := reflect.ValueOf(v)
+	switch {
+	case rv.CanInt():
+		return strconv.FormatInt(rv.Int(), 10)
+	case rv.CanUint():
+		return strconv.FormatUint(rv.Uint(), 10)
+	case rv.CanFloat():
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 64)
+	case rv.Kind() == reflect.String:
+		return strconv.Quote(rv.String())
+	}
+	return strconv.Quote(fmt.Sprint(v))
+}
+
+// enumValueName is the suffix of the constant of v: "zh-CN" becomes "ZhCN", -1 "Neg1" and 1.5 "1Dot5".
+func enumValueName(v any) string {
+	rv := reflect.ValueOf(v)
+	var s string
+	if rv.Kind() == reflect.String {
+		s = camel(rv.String())
+	} else {
+		s = enumValue(v)
+		if rest, ok := strings.CutPrefix(s, "-"); ok {
+			s = "Neg" + rest
+		}
+		s = strings.ReplaceAll(s, ".", "Dot")
+	}
+	if s == "" {
+		return "Empty"
+	}
+	return s
+}
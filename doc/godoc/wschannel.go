@@ -0,0 +1,45 @@
+package godoc
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/tencent-go/pkg/doc/schema"
+	"github.com/tencent-go/pkg/doc/wsdoc"
+	"github.com/tencent-go/pkg/util"
+)
+
+// NewEventFile generates the topics of the websocket channels and an alias of their message types
+// in the package "websocket", request channels have an alias of their reply type too.
+func NewEventFile(module string, channels []wsdoc.EventChannel, parentDir ...string) util.DataFile {
+	var b strings.Builder
+	b.WriteString(generatedHeader)
+	b.WriteString("\npackage websocket\n")
+	var useSchema bool
+	for _, c := range channels {
+		for _, t := range []*schema.Type{c.Type, c.ResponseType} {
+			if t != nil && t.BaseType != schema.BaseTypeNull {
+				useSchema = useSchema || strings.Contains(goType(*t, nil, "schema."), "schema.")
+			}
+		}
+	}
+	if useSchema {
+		fmt.Fprintf(&b, "\nimport %q\n", module+"/schema")
+	}
+	for _, c := range channels {
+		name := goName(c.Topic)
+		b.WriteString("\n")
+		if c.Description != "" {
+			fmt.Fprintf(&b, "// Topic%s %s\n", name, oneLine(c.Description))
+		}
+		fmt.Fprintf(&b, "const Topic%s = %q\n", name, c.Topic)
+		if c.Type != nil && c.Type.BaseType != schema.BaseTypeNull {
+			fmt.Fprintf(&b, "\ntype %sMessage = %s\n", name, goType(*c.Type, nil, "schema."))
+		}
+		if c.ResponseType != nil && c.ResponseType.BaseType != schema.BaseTypeNull {
+			fmt.Fprintf(&b, "\ntype %sResponse = %s\n", name, goType(*c.ResponseType, nil, "schema."))
+		}
+	}
+	return newGoFile(path.Join(append(parentDir, "websocket")...), "events.go", []byte(b.String()))
+}
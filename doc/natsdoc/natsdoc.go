@@ -0,0 +1,33 @@
+package natsdoc
+
+import (
+	"github.com/tencent-go/pkg/doc/schema"
+	"github.com/tencent-go/pkg/natsx"
+	"github.com/tencent-go/pkg/util"
+)
+
+type Subject struct {
+	Subject      string
+	Placeholders []string
+	Stream       string
+	Type         *schema.Type
+	Description  string
+}
+
+func NewSubjects(schemaCollection schema.Collection, subjects []natsx.SubjectDefinition) []Subject {
+	res := make([]Subject, len(subjects))
+	for i, subject := range subjects {
+		typ, _ := schemaCollection.ParseAndGetType(subject.MessageType(), util.TagJson)
+		s := Subject{
+			Subject:     subject.Subject(),
+			Stream:      subject.StreamName(),
+			Type:        typ,
+			Description: subject.Description(),
+		}
+		for _, match := range util.PlaceholderRegex.FindAllStringSubmatch(s.Subject, -1) {
+			s.Placeholders = append(s.Placeholders, match[1])
+		}
+		res[i] = s
+	}
+	return res
+}
@@ -0,0 +1,127 @@
+package openapi
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const schemaRefPrefix = "#/components/schemas/"
+
+// Merge combines the documents of several services into one, keyed by service name. Schemas, tags and
+// operation ids are prefixed by the service name to stay unique, a path defined twice is kept once.
+// The documents are copied before being renamed, specs are left unchanged.
+func Merge(title string, specs map[string]*OpenAPI) *OpenAPI {
+	res := NewDefault()
+	res.Info.Title = title
+	services := make([]string, 0, len(specs))
+	for service := range specs {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	for _, service := range services {
+		if specs[service] == nil {
+			continue
+		}
+		// a parsed document shares its schemas between operations, the copy has a schema per reference
+		spec, e := specs[service].clone()
+		if e != nil {
+			logrus.WithError(e).Warnf("copy openapi document of service %s failed", service)
+			continue
+		}
+		rename := func(s *Schema) {
+			if strings.HasPrefix(s.Ref, schemaRefPrefix) {
+				s.Ref = schemaRefPrefix + service + "." + strings.TrimPrefix(s.Ref, schemaRefPrefix)
+			}
+		}
+		if spec.Components != nil {
+			for key, s := range spec.Components.Schemas {
+				s.walk(rename)
+				res.Components.Schemas[service+"."+key] = s
+			}
+		}
+		for _, tag := range spec.Tags {
+			tag.Name = service + "/" + tag.Name
+			res.Tags = append(res.Tags, tag)
+		}
+		for p, item := range spec.Paths {
+			if _, ok := res.Paths[p]; ok {
+				logrus.Warnf("openapi path %s of service %s is already defined", p, service)
+				continue
+			}
+			for _, o := range item.operations() {
+				for i := range o.Tags {
+					o.Tags[i] = service + "/" + o.Tags[i]
+				}
+				if o.OperationID != "" {
+					o.OperationID = service + "_" + o.OperationID
+				}
+				o.walk(rename)
+			}
+			res.Paths[p] = item
+		}
+	}
+	return res
+}
+
+func (o *OpenAPI) clone() (*OpenAPI, error) {
+	data, e := json.Marshal(o)
+	if e != nil {
+		return nil, e
+	}
+	res := &OpenAPI{}
+	if e = json.Unmarshal(data, res); e != nil {
+		return nil, e
+	}
+	return res, nil
+}
+
+func (p *PathItem) operations() []*Operation {
+	var res []*Operation
+	for _, o := range []*Operation{p.Get, p.Put, p.Post, p.Delete, p.Options, p.Head, p.Patch, p.Trace} {
+		if o != nil {
+			res = append(res, o)
+		}
+	}
+	return res
+}
+
+func (o *Operation) walk(fn func(s *Schema)) {
+	for _, p := range o.Parameters {
+		p.Schema.walk(fn)
+	}
+	if o.RequestBody != nil {
+		for _, m := range o.RequestBody.Content {
+			m.Schema.walk(fn)
+		}
+	}
+	for _, r := range o.Responses {
+		for _, m := range r.Content {
+			m.Schema.walk(fn)
+		}
+		for _, h := range r.Headers {
+			h.Schema.walk(fn)
+		}
+	}
+}
+
+// walk calls fn for s and all its nested schemas.
+func (s *Schema) walk(fn func(s *Schema)) {
+	if s == nil {
+		return
+	}
+	fn(s)
+	for _, p := range s.Properties {
+		p.walk(fn)
+	}
+	s.Items.walk(fn)
+	s.AdditionalProperties.walk(fn)
+	s.Not.walk(fn)
+	for _, list := range [][]*Schema{s.AllOf, s.OneOf, s.AnyOf} {
+		for _, it := range list {
+			it.walk(fn)
+		}
+	}
+}
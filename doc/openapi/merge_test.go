@@ -0,0 +1,103 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tencent-go/pkg/doc/rpcdoc"
+	"github.com/tencent-go/pkg/doc/schema"
+	"github.com/tencent-go/pkg/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+	newSpec := func(path string) *OpenAPI {
+		spec := NewDefault()
+		spec.Tags = []Tag{{Name: "order"}}
+		spec.Components.Schemas["Order"] = &Schema{
+			Type:       "object",
+			Properties: map[string]*Schema{"items": {Type: "array", Items: &Schema{Ref: schemaRefPrefix + "Item"}}},
+		}
+		spec.Components.Schemas["Item"] = &Schema{Type: "object"}
+		spec.Paths[path] = &PathItem{Post: &Operation{
+			Tags:        []string{"order"},
+			OperationID: "createOrder",
+			RequestBody: &RequestBody{Content: map[string]MediaType{
+				"application/json": {Schema: &Schema{Ref: schemaRefPrefix + "Order"}},
+			}},
+			Responses: map[string]Response{"200": {Content: map[string]MediaType{
+				"application/json": {Schema: &Schema{AllOf: []*Schema{{Ref: schemaRefPrefix + "Item"}}}},
+			}}},
+		}}
+		return spec
+	}
+	res := Merge("Portal", map[string]*OpenAPI{
+		"shop":  newSpec("/shop/orders"),
+		"admin": newSpec("/admin/orders"),
+		"empty": nil,
+	})
+
+	assert.Equal(t, "Portal", res.Info.Title)
+
+	t.Run("標籤依服務排序並加上前綴", func(t *testing.T) {
+		assert.Equal(t, []Tag{{Name: "admin/order"}, {Name: "shop/order"}}, res.Tags)
+	})
+
+	t.Run("結構加上前綴且引用跟隨改名", func(t *testing.T) {
+		assert.Contains(t, res.Components.Schemas, "shop.Order")
+		assert.Contains(t, res.Components.Schemas, "admin.Item")
+		assert.Equal(t, schemaRefPrefix+"shop.Item", res.Components.Schemas["shop.Order"].Properties["items"].Items.Ref)
+	})
+
+	t.Run("操作", func(t *testing.T) {
+		o := res.Paths["/shop/orders"].Post
+		assert.Equal(t, "shop_createOrder", o.OperationID)
+		assert.Equal(t, []string{"shop/order"}, o.Tags)
+		assert.Equal(t, schemaRefPrefix+"shop.Order", o.RequestBody.Content["application/json"].Schema.Ref)
+		assert.Equal(t, schemaRefPrefix+"shop.Item", o.Responses["200"].Content["application/json"].Schema.AllOf[0].Ref)
+		assert.Equal(t, "admin_createOrder", res.Paths["/admin/orders"].Post.OperationID)
+	})
+
+	t.Run("重複的路徑保留第一個服務", func(t *testing.T) {
+		res := Merge("Portal", map[string]*OpenAPI{
+			"a": newSpec("/orders"),
+			"b": newSpec("/orders"),
+		})
+		assert.Len(t, res.Paths, 1)
+		assert.Equal(t, "a_createOrder", res.Paths["/orders"].Post.OperationID)
+	})
+
+	t.Run("合併解析結果不重複改名且不修改輸入", func(t *testing.T) {
+		newRpc := func() *OpenAPI {
+			typ, _ := schema.NewCollection().ParseAndGetType(reflect.TypeOf(rpcOrder{}), util.TagJson)
+			return NewDefault().ParseRpc([]rpcdoc.Group{{
+				Name: "order",
+				Methods: []rpcdoc.Method{
+					{Name: "get", Path: "/order/get", RequestType: typ, ResponseType: typ},
+					{Name: "list", Path: "/order/list", RequestType: typ, ResponseType: typ},
+				},
+			}})
+		}
+		shop, admin := newRpc(), newRpc()
+		before, _ := json.Marshal(shop)
+		res := Merge("Portal", map[string]*OpenAPI{"shop": shop, "admin": admin})
+		after, _ := json.Marshal(shop)
+		assert.JSONEq(t, string(before), string(after))
+
+		data, _ := json.Marshal(res)
+		assert.Contains(t, string(data), schemaRefPrefix+"shop.")
+		assert.Contains(t, string(data), schemaRefPrefix+"admin.")
+		assert.False(t, strings.Contains(string(data), "shop.shop.") || strings.Contains(string(data), "admin.admin."), string(data))
+		for _, p := range []string{"/order/get", "/order/list"} {
+			for _, r := range res.Paths[p].Post.Responses {
+				for _, m := range r.Content {
+					if ref := m.Schema.Ref; ref != "" {
+						assert.Contains(t, res.Components.Schemas, strings.TrimPrefix(ref, schemaRefPrefix))
+					}
+				}
+			}
+		}
+	})
+}
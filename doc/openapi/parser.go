@@ -13,6 +13,8 @@ import (
 	"github.com/tencent-go/pkg/types"
 )
 
+const bearerAuth = "BearerAuth"
+
 func NewDefault() *OpenAPI {
 	return &OpenAPI{
 		OpenAPI: "3.0.1",
@@ -23,7 +25,7 @@ func NewDefault() *OpenAPI {
 		Paths: make(map[string]*PathItem),
 		Components: &Components{
 			SecuritySchemes: map[string]*SecurityScheme{
-				"BearerAuth": {
+				bearerAuth: {
 					Type:         "http",
 					Scheme:       "bearer",
 					BearerFormat: "JWT",
@@ -32,7 +34,6 @@ func NewDefault() *OpenAPI {
 			Schemas:    make(map[string]*Schema),
 			Parameters: map[string]*Parameter{},
 		},
-		Security: []map[string][]string{{"BearerAuth": []string{}}},
 	}
 }
 
@@ -52,6 +53,7 @@ func (spec *OpenAPI) Parse(groups []restdoc.Group) *OpenAPI {
 			}
 			o := spec.endpoint2Operation(item)
 			o.Tags = []string{group.Name}
+			o.OperationID = spec.operationID(group.Name, item.Name)
 			switch item.Method {
 			case api.MethodGet:
 				pi.Get = o
@@ -71,48 +73,11 @@ func (spec *OpenAPI) Parse(groups []restdoc.Group) *OpenAPI {
 
 func (spec *OpenAPI) endpoint2Operation(endpoint restdoc.Endpoint) *Operation {
 	o := &Operation{
-		//Security: []map[string][]string{{"BearerAuth": []string{}}},
-	}
-	var res *Schema
-	//TODO: implement wrapper
-	// if srv.WrapperType != nil {
-	// 	res = &Schema{}
-	// 	wrapper := &Schema{Ref: fmt.Sprintf("#/components/schemas/%s.%s", srv.WrapperType.PackageName, srv.WrapperType.Name)}
-	// 	res.AllOf = append(res.AllOf, wrapper)
-	// 	if srv.Response != nil {
-	// 		data := &Schema{
-	// 			Properties: map[string]*Schema{
-	// 				srv.WrapperDataField: type2Schema(*srv.Response),
-	// 			},
-	// 		}
-	// 		res.AllOf = append(res.AllOf, data)
-	// 	}
-	// } else {
-
-	// }
-	if endpoint.Response != nil {
-		res = spec.type2Schema(*endpoint.Response)
-	}
-	if res != nil {
-		o.Responses = map[string]Response{
-			"200": {
-				Content: map[string]MediaType{
-					"application/json": {
-						Schema: res,
-					},
-				},
-			},
-		}
+		Responses:   spec.responses(endpoint),
+		RequestBody: spec.requestBody(endpoint),
 	}
-
-	if endpoint.Body != nil {
-		o.RequestBody = &RequestBody{
-			Content: map[string]MediaType{
-				"application/json": {
-					Schema: spec.type2Schema(*endpoint.Body),
-				},
-			},
-		}
+	if endpoint.AuthenticationRequired {
+		o.Security = []map[string][]string{{bearerAuth: []string{}}}
 	}
 	if endpoint.Query != nil {
 		o.Parameters = append(o.Parameters, spec.class2parameters(*endpoint.Query)...)
@@ -144,13 +109,26 @@ func (spec *OpenAPI) endpoint2Operation(endpoint restdoc.Endpoint) *Operation {
 	return o
 }
 
+// operationID is the endpoint name, prefixed by the group name when another group already used it.
+func (spec *OpenAPI) operationID(group, name string) string {
+	if spec.operationIDs == nil {
+		spec.operationIDs = make(map[string]bool)
+	}
+	id := name
+	if spec.operationIDs[id] {
+		id = group + "_" + name
+	}
+	spec.operationIDs[id] = true
+	return id
+}
+
 func (spec *OpenAPI) class2parameters(class schema.Class) []Parameter {
 	var parameters []Parameter
 	for _, field := range class.Fields {
 		p := Parameter{
 			Name:     field.Name,
 			Required: !field.Optional,
-			Schema:   spec.type2Schema(field.Type),
+			Schema:   spec.field2Schema(field),
 		}
 		switch class.Tag {
 		case util.TagQuery:
@@ -169,18 +147,78 @@ func (spec *OpenAPI) class2parameters(class schema.Class) []Parameter {
 
 func (spec *OpenAPI) class2Schema(class schema.Class) *Schema {
 	s := &Schema{
-		Types:      []string{"object"},
+		Type:       "object",
 		Properties: make(map[string]*Schema),
 	}
 	for _, field := range class.Fields {
-		//if !field.Optional {
-		//	s.Required = append(s.Required, field.Name)
-		//}
-		s.Properties[field.Name] = spec.type2Schema(field.Type)
+		if field.Rule != nil && !field.Optional {
+			s.Required = append(s.Required, field.Name)
+		}
+		s.Properties[field.Name] = spec.field2Schema(field)
 	}
 	return s
 }
 
+func (spec *OpenAPI) field2Schema(field schema.Field) *Schema {
+	s := spec.type2Schema(field.Type)
+	applyConstraints(s, field.Constraints)
+	return s
+}
+
+// applyConstraints adds the validation rules of a field, a $ref schema is wrapped in allOf
+// because OpenAPI 3.0 ignores the siblings of $ref.
+func applyConstraints(s *Schema, c *schema.Constraints) {
+	if c.IsEmpty() {
+		return
+	}
+	if s.Ref != "" {
+		ref := &Schema{Ref: s.Ref}
+		s.Ref = ""
+		s.AllOf = append(s.AllOf, ref)
+	}
+	s.Minimum = c.Minimum
+	s.Maximum = c.Maximum
+	s.MinLength = c.MinLength
+	s.MaxLength = c.MaxLength
+	s.MinItems = c.MinItems
+	s.MaxItems = c.MaxItems
+	s.MinProperties = c.MinProperties
+	s.MaxProperties = c.MaxProperties
+	s.Pattern = c.Pattern
+	s.UniqueItems = c.UniqueItems
+	if len(c.Enum) > 0 {
+		s.Enum = c.Enum
+	}
+	var descriptions []string
+	if c.RequiredIf != nil {
+		descriptions = append(descriptions, fmt.Sprintf("Required when %s is %s", c.RequiredIf.Field, strings.Join(c.RequiredIf.Values, " or ")))
+	}
+	if c.RequiredWithout != "" {
+		descriptions = append(descriptions, fmt.Sprintf("Required when %s is empty", c.RequiredWithout))
+	}
+	if c.EqField != "" {
+		descriptions = append(descriptions, fmt.Sprintf("Must equal %s", c.EqField))
+	}
+	if c.GtField != "" {
+		descriptions = append(descriptions, fmt.Sprintf("Must be greater than %s", c.GtField))
+	}
+	if len(descriptions) > 0 {
+		s.Description = strings.Join(descriptions, "; ")
+	}
+	if c.Items != nil {
+		if s.Items != nil {
+			applyConstraints(s.Items, c.Items)
+		} else if s.AdditionalProperties != nil {
+			applyConstraints(s.AdditionalProperties, c.Items)
+		}
+	}
+}
+
+// TypeSchema converts t, the classes and enums it refers to are added to the component schemas.
+func (spec *OpenAPI) TypeSchema(t schema.Type) *Schema {
+	return spec.type2Schema(t)
+}
+
 func (spec *OpenAPI) type2Schema(t schema.Type) *Schema {
 	s := &Schema{}
 	if t.Nullable {
@@ -196,10 +234,10 @@ func (spec *OpenAPI) type2Schema(t schema.Type) *Schema {
 	}
 	switch t.BaseType {
 	case schema.BaseTypeMap:
-		s.Types = []string{"object"}
+		s.Type = "object"
 		s.AdditionalProperties = spec.type2Schema(t.Map.ValueType)
 	case schema.BaseTypeArray:
-		s.Types = []string{"array"}
+		s.Type = "array"
 		s.Items = spec.type2Schema(*t.Array)
 	case schema.BaseTypeClass:
 		key := t.Class.Package.Name + "." + t.Class.Name
@@ -208,17 +246,17 @@ func (spec *OpenAPI) type2Schema(t schema.Type) *Schema {
 			spec.Components.Schemas[key] = spec.class2Schema(*t.Class)
 		}
 	case schema.BaseTypeString:
-		s.Types = []string{"string"}
+		s.Type = "string"
 	case schema.BaseTypeNumber:
-		s.Types = []string{"number"}
+		s.Type = "number"
 	case schema.BaseTypeBoolean:
-		s.Types = []string{"boolean"}
+		s.Type = "boolean"
 	case schema.BaseTypeNull:
 		s.Nullable = true
-		s.Types = []string{"object", "null"}
+		s.Type = "object"
 		//fallthrough
 	default:
-		s.Types = []string{"object"}
+		s.Type = "object"
 	}
 	return s
 }
@@ -228,9 +266,9 @@ func (spec *OpenAPI) enum2Schema(enum schema.Enum) *Schema {
 		Properties: make(map[string]*Schema),
 	}
 	if enum.IsNumeric {
-		s.Types = []string{"integer"}
+		s.Type = "integer"
 	} else {
-		s.Types = []string{"string"}
+		s.Type = "string"
 	}
 	var descriptions []string
 	for _, item := range enum.Items {
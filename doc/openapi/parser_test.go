@@ -0,0 +1,62 @@
+package openapi
+
+import (
+	"testing"
+
+	"github.com/tencent-go/pkg/doc/schema"
+	"github.com/tencent-go/pkg/validation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyConstraints(t *testing.T) {
+	float := func(f float64) *float64 { return &f }
+	integer := func(i int) *int { return &i }
+
+	t.Run("無約束", func(t *testing.T) {
+		s := &Schema{Type: "string"}
+		applyConstraints(s, nil)
+		applyConstraints(s, &schema.Constraints{})
+		assert.Equal(t, &Schema{Type: "string"}, s)
+	})
+
+	t.Run("關鍵字", func(t *testing.T) {
+		s := &Schema{Type: "integer"}
+		applyConstraints(s, &schema.Constraints{Minimum: float(1), Maximum: float(9), Enum: []any{1.0, 9.0}})
+		assert.Equal(t, &Schema{Type: "integer", Minimum: float(1), Maximum: float(9), Enum: []any{1.0, 9.0}}, s)
+
+		s = &Schema{Type: "boolean"}
+		applyConstraints(s, &schema.Constraints{Enum: []any{true}})
+		assert.Equal(t, []any{true}, s.Enum)
+	})
+
+	t.Run("引用改為allOf", func(t *testing.T) {
+		s := &Schema{Ref: "#/components/schemas/Pkg.Status"}
+		applyConstraints(s, &schema.Constraints{Enum: []any{"paid"}})
+		assert.Equal(t, &Schema{
+			AllOf: []*Schema{{Ref: "#/components/schemas/Pkg.Status"}},
+			Enum:  []any{"paid"},
+		}, s)
+	})
+
+	t.Run("跨欄位規則", func(t *testing.T) {
+		s := &Schema{Type: "string"}
+		applyConstraints(s, &schema.Constraints{
+			RequiredIf:      &validation.FieldCondition{Field: "contact", Values: []string{"email", "sms"}},
+			RequiredWithout: "phone",
+			EqField:         "password",
+			GtField:         "start",
+		})
+		assert.Equal(t, "Required when contact is email or sms; Required when phone is empty; Must equal password; Must be greater than start", s.Description)
+	})
+
+	t.Run("元素", func(t *testing.T) {
+		s := &Schema{Type: "array", Items: &Schema{Type: "string"}}
+		applyConstraints(s, &schema.Constraints{MaxItems: integer(3), Items: &schema.Constraints{MaxLength: integer(5)}})
+		assert.Equal(t, integer(3), s.MaxItems)
+		assert.Equal(t, integer(5), s.Items.MaxLength)
+
+		s = &Schema{Type: "object", AdditionalProperties: &Schema{Type: "integer"}}
+		applyConstraints(s, &schema.Constraints{Items: &schema.Constraints{Minimum: float(0)}})
+		assert.Equal(t, float(0), s.AdditionalProperties.Minimum)
+	})
+}
@@ -0,0 +1,151 @@
+package openapi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tencent-go/pkg/doc/restdoc"
+	"github.com/tencent-go/pkg/errx"
+	"github.com/tencent-go/pkg/rest/api"
+)
+
+const (
+	errorTypeSchemaKey    = "errx.Type"
+	violationSchemaKey    = "validation.Violation"
+	errorDetailsSchemaKey = "router.ErrorDetails"
+)
+
+// clientErrorTypes are answered with 400 by the router, internal errors with 500.
+var clientErrorTypes = []errx.Type{
+	errx.TypeValidation,
+	errx.TypeAuthentication,
+	errx.TypeAuthorization,
+	errx.TypeNotFound,
+	errx.TypeConflict,
+	errx.TypeBusiness,
+	errx.TypeRateLimit,
+	errx.TypeConcurrency,
+	errx.TypeTimeout,
+	errx.TypeNetwork,
+}
+
+var binarySchema = &Schema{Type: "string", Format: "binary"}
+
+func (spec *OpenAPI) requestBody(endpoint restdoc.Endpoint) *RequestBody {
+	ct := endpoint.RequestContentType
+	if ct == "" || endpoint.Method == api.MethodGet {
+		return nil
+	}
+	var s *Schema
+	switch {
+	case endpoint.Body != nil:
+		s = spec.type2Schema(*endpoint.Body)
+	case ct == api.ContentTypeApplicationJson || ct == api.ContentTypeApplicationFormUrlencoded:
+		return nil
+	default:
+		s = binarySchema
+	}
+	return &RequestBody{
+		Required: true,
+		Content:  map[string]MediaType{string(ct): {Schema: s}},
+	}
+}
+
+func (spec *OpenAPI) responses(endpoint restdoc.Endpoint) map[string]Response {
+	ok := Response{Description: "OK"}
+	ct := endpoint.ResponseContentType
+	switch {
+	case endpoint.WrapOutput:
+		var data *Schema
+		if endpoint.Response != nil {
+			data = spec.type2Schema(*endpoint.Response)
+		}
+		ok.Content = map[string]MediaType{string(ct): {Schema: spec.envelopeSchema(data)}}
+	case endpoint.Response != nil && (ct == api.ContentTypeApplicationJson || ct == api.ContentTypeApplicationFormUrlencoded):
+		ok.Content = map[string]MediaType{string(ct): {Schema: spec.type2Schema(*endpoint.Response)}}
+	case ct != "" && ct != api.ContentTypeApplicationJson && ct != api.ContentTypeApplicationFormUrlencoded:
+		ok.Content = map[string]MediaType{string(ct): {Schema: binarySchema}}
+	}
+	res := map[string]Response{"200": ok}
+
+	var types []string
+	examples := make(map[string]Example)
+	for _, t := range clientErrorTypes {
+		if t == errx.TypeAuthentication && !endpoint.AuthenticationRequired || t == errx.TypeAuthorization && !endpoint.AuthorizationRequired {
+			continue
+		}
+		types = append(types, string(t))
+		examples[string(t)] = errorExample(t)
+	}
+	badRequest := Response{Description: fmt.Sprintf("Error of type %s", strings.Join(types, ", "))}
+	internal := Response{Description: fmt.Sprintf("Error of type %s", errx.TypeInternal)}
+	if endpoint.WrapOutput {
+		badRequest.Content = map[string]MediaType{string(ct): {Schema: spec.envelopeSchema(nil), Examples: examples}}
+		internal.Content = map[string]MediaType{string(ct): {
+			Schema:   spec.envelopeSchema(nil),
+			Examples: map[string]Example{string(errx.TypeInternal): errorExample(errx.TypeInternal)},
+		}}
+	}
+	res["400"] = badRequest
+	res["500"] = internal
+	return res
+}
+
+func errorExample(t errx.Type) Example {
+	return Example{
+		Summary: string(t),
+		Value: map[string]any{
+			"success": false,
+			"error":   map[string]any{"code": 0, "type": t, "message": ""},
+		},
+	}
+}
+
+// envelopeSchema describes router.JsonResponseWrapper, data is the schema of the wrapped output.
+func (spec *OpenAPI) envelopeSchema(data *Schema) *Schema {
+	s := &Schema{
+		Type:     "object",
+		Required: []string{"success"},
+		Properties: map[string]*Schema{
+			"success": {Type: "boolean"},
+			"error":   {Ref: "#/components/schemas/" + spec.errorDetailsSchema()},
+		},
+	}
+	if data != nil {
+		s.Properties["data"] = data
+	}
+	return s
+}
+
+func (spec *OpenAPI) errorDetailsSchema() string {
+	schemas := spec.Components.Schemas
+	if _, ok := schemas[errorDetailsSchemaKey]; ok {
+		return errorDetailsSchemaKey
+	}
+	errorType := &Schema{Type: "string"}
+	for _, t := range append([]errx.Type{errx.TypeInternal}, clientErrorTypes...) {
+		errorType.Enum = append(errorType.Enum, t)
+	}
+	schemas[errorTypeSchemaKey] = errorType
+	schemas[violationSchemaKey] = &Schema{
+		Type:     "object",
+		Required: []string{"field", "rule", "message"},
+		Properties: map[string]*Schema{
+			"field":   {Type: "string"},
+			"rule":    {Type: "string"},
+			"params":  {Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+			"message": {Type: "string"},
+		},
+	}
+	schemas[errorDetailsSchemaKey] = &Schema{
+		Type:     "object",
+		Required: []string{"code", "message", "type"},
+		Properties: map[string]*Schema{
+			"code":       {Type: "integer"},
+			"message":    {Type: "string"},
+			"type":       {Ref: "#/components/schemas/" + errorTypeSchemaKey},
+			"violations": {Type: "array", Items: &Schema{Ref: "#/components/schemas/" + violationSchemaKey}},
+		},
+	}
+	return errorDetailsSchemaKey
+}
@@ -0,0 +1,69 @@
+package openapi
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/tencent-go/pkg/doc/restdoc"
+	"github.com/tencent-go/pkg/doc/schema"
+	"github.com/tencent-go/pkg/rest/api"
+	"github.com/tencent-go/pkg/util"
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+type createOrderInput struct {
+	Name  string `json:"name" validate:"max=20"`
+	Count int    `json:"count" validate:"min=1"`
+}
+
+type createOrderOutput struct {
+	ID string `json:"id"`
+}
+
+func TestOperationGolden(t *testing.T) {
+	c := schema.NewCollection()
+	body, _ := c.ParseAndGetType(reflect.TypeOf(createOrderInput{}), util.TagJson)
+	output, _ := c.ParseAndGetType(reflect.TypeOf(createOrderOutput{}), util.TagJson)
+	endpoint := restdoc.Endpoint{
+		Name:                   "createOrder",
+		Permission:             "order.create",
+		Description:            "Create an order",
+		Method:                 api.MethodPost,
+		Path:                   "orders",
+		AuthenticationRequired: true,
+		AuthorizationRequired:  true,
+		Body:                   body,
+		Response:               output,
+		RequestContentType:     api.ContentTypeApplicationJson,
+		ResponseContentType:    api.ContentTypeApplicationJson,
+		WrapOutput:             true,
+	}
+	admin := endpoint
+	admin.Path = "admin/orders"
+	spec := NewDefault().Parse([]restdoc.Group{
+		{Name: "order", Endpoints: []restdoc.Endpoint{endpoint}},
+		{Name: "admin", Endpoints: []restdoc.Endpoint{admin}},
+	})
+	assert.Equal(t, "admin_createOrder", spec.Paths["/admin/orders"].Post.OperationID)
+
+	got, err := json.MarshalIndent(map[string]any{
+		"operation": spec.Paths["/orders"].Post,
+		"schemas":   spec.Components.Schemas,
+	}, "", "  ")
+	if !assert.NoError(t, err) {
+		return
+	}
+	golden := "testdata/operation.json"
+	if *update {
+		assert.NoError(t, os.WriteFile(golden, got, 0o644))
+	}
+	want, err := os.ReadFile(golden)
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, string(want), string(got))
+	}
+}
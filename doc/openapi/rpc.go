@@ -0,0 +1,77 @@
+package openapi
+
+import (
+	"github.com/tencent-go/pkg/doc/rpcdoc"
+	"github.com/tencent-go/pkg/errx"
+)
+
+const (
+	rpcContentTypeMsgpack = "application/msgpack"
+	rpcContentTypeJson    = "application/json"
+)
+
+// rpcErrorStatuses are the statuses written by rpc.WriteError, all other error types are answered with 502.
+var rpcErrorStatuses = map[string]errx.Type{
+	"401": errx.TypeAuthentication,
+	"403": errx.TypeAuthorization,
+	"404": errx.TypeNotFound,
+}
+
+// ParseRpc adds every rpc route as a POST operation. The body is msgpack encoded, JSON is accepted
+// when the route is reached through rpc.NewJsonTranscoder.
+func (spec *OpenAPI) ParseRpc(groups []rpcdoc.Group) *OpenAPI {
+	for _, group := range groups {
+		spec.Tags = append(spec.Tags, Tag{
+			Name:        group.Name,
+			Description: group.Description,
+		})
+		for _, method := range group.Methods {
+			o := &Operation{
+				Tags:        []string{group.Name},
+				OperationID: spec.operationID(group.Name, method.Name),
+				Summary:     method.Name,
+				Description: method.Description,
+				Responses:   spec.rpcResponses(method),
+			}
+			if method.Description != "" {
+				o.Summary = method.Name + " " + method.Description
+				o.Description = ""
+			}
+			if method.RequestType != nil {
+				s := spec.type2Schema(*method.RequestType)
+				o.RequestBody = &RequestBody{
+					Required: true,
+					Content: map[string]MediaType{
+						rpcContentTypeMsgpack: {Schema: s},
+						rpcContentTypeJson:    {Schema: s},
+					},
+				}
+			}
+			spec.Paths[method.Path] = &PathItem{Post: o}
+		}
+	}
+	return spec
+}
+
+func (spec *OpenAPI) rpcResponses(method rpcdoc.Method) map[string]Response {
+	ok := Response{Description: "OK"}
+	if method.ResponseType != nil {
+		s := spec.type2Schema(*method.ResponseType)
+		ok.Content = map[string]MediaType{
+			rpcContentTypeMsgpack: {Schema: s},
+			rpcContentTypeJson:    {Schema: s},
+		}
+	}
+	res := map[string]Response{"200": ok}
+	// rpc.ErrorDetail has the same fields as the error of the rest envelope
+	details := &Schema{Ref: "#/components/schemas/" + spec.errorDetailsSchema()}
+	content := map[string]MediaType{
+		rpcContentTypeMsgpack: {Schema: details},
+		rpcContentTypeJson:    {Schema: details},
+	}
+	for status, t := range rpcErrorStatuses {
+		res[status] = Response{Description: "Error of type " + string(t), Content: content}
+	}
+	res["502"] = Response{Description: "Error of any other type", Content: content}
+	return res
+}
@@ -0,0 +1,65 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tencent-go/pkg/doc/rpcdoc"
+	"github.com/tencent-go/pkg/doc/schema"
+	"github.com/tencent-go/pkg/util"
+	"github.com/stretchr/testify/assert"
+)
+
+type rpcOrder struct {
+	ID string `json:"id"`
+}
+
+func TestParseRpc(t *testing.T) {
+	typ, _ := schema.NewCollection().ParseAndGetType(reflect.TypeOf(rpcOrder{}), util.TagJson)
+	spec := NewDefault().ParseRpc([]rpcdoc.Group{{
+		Name:        "order",
+		Description: "訂單",
+		Methods: []rpcdoc.Method{
+			{Name: "get", Path: "/order/get", Description: "查詢訂單", RequestType: typ, ResponseType: typ},
+			{Name: "ping", Path: "/order/ping"},
+		},
+	}})
+
+	assert.Equal(t, []Tag{{Name: "order", Description: "訂單"}}, spec.Tags)
+
+	t.Run("請求與回應", func(t *testing.T) {
+		o := spec.Paths["/order/get"].Post
+		if !assert.NotNil(t, o) {
+			return
+		}
+		assert.Equal(t, "get", o.OperationID)
+		assert.Equal(t, "get 查詢訂單", o.Summary)
+		assert.Empty(t, o.Description)
+		assert.True(t, o.RequestBody.Required)
+		for _, content := range []map[string]MediaType{o.RequestBody.Content, o.Responses["200"].Content} {
+			assert.Contains(t, content, rpcContentTypeMsgpack)
+			assert.Contains(t, content, rpcContentTypeJson)
+			assert.NotNil(t, content[rpcContentTypeJson].Schema)
+		}
+	})
+
+	t.Run("錯誤回應", func(t *testing.T) {
+		o := spec.Paths["/order/get"].Post
+		for _, status := range []string{"401", "403", "404", "502"} {
+			if assert.Contains(t, o.Responses, status) {
+				assert.Equal(t, "#/components/schemas/"+errorDetailsSchemaKey, o.Responses[status].Content[rpcContentTypeJson].Schema.Ref)
+			}
+		}
+		assert.Contains(t, spec.Components.Schemas, errorDetailsSchemaKey)
+	})
+
+	t.Run("無參數", func(t *testing.T) {
+		o := spec.Paths["/order/ping"].Post
+		if !assert.NotNil(t, o) {
+			return
+		}
+		assert.Equal(t, "ping", o.Summary)
+		assert.Nil(t, o.RequestBody)
+		assert.Nil(t, o.Responses["200"].Content)
+	})
+}
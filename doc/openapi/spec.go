@@ -10,6 +10,8 @@ type OpenAPI struct {
 	Tags         []Tag                  `json:"tags,omitempty"`
 	ExternalDocs *ExternalDocumentation `json:"externalDocs,omitempty"`
 	Security     []map[string][]string  `json:"security,omitempty"`
+
+	operationIDs map[string]bool
 }
 
 // Info represents metadata about the API
@@ -0,0 +1,239 @@
+package doc
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tencent-go/pkg/ctxx"
+	"github.com/tencent-go/pkg/doc/openapi"
+	"github.com/tencent-go/pkg/errx"
+	"github.com/tencent-go/pkg/rpc"
+	"github.com/tencent-go/pkg/types"
+	"github.com/sirupsen/logrus"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const specsMethodPath = "doc/specs"
+
+// Specs are the JSON documents a service publishes to the portal.
+type Specs struct {
+	Rest []byte `json:"rest,omitempty"`
+	Rpc  []byte `json:"rpc,omitempty"`
+}
+
+var (
+	specsMethod     = rpc.NewMethod[types.Nil, Specs](specsMethodPath).WithDescription("documents of the service")
+	publishedSpecs  atomic.Pointer[Specs]
+	specsRegistered sync.Once
+	handleSpecs     = specsMethod.Handle
+)
+
+// registerSpecs publishes specs, the method is handled once and serves the documents of the last handler.
+func registerSpecs(specs Specs) {
+	publishedSpecs.Store(&specs)
+	specsRegistered.Do(func() {
+		handleSpecs(func(ctx rpc.Context, params types.Nil) (*Specs, errx.Error) {
+			return publishedSpecs.Load(), nil
+		})
+	})
+}
+
+type PortalConfig struct {
+	Title   string
+	UI      UI
+	Etcd    *clientv3.Client // etcd of the rpc registry, the rpc default etcd when nil
+	Refresh time.Duration    // interval between two discoveries, 1 minute by default
+	Timeout time.Duration    // timeout of a discovery, 10 seconds by default
+}
+
+// NewPortalHttpHandler merges the documents of the services registered with Config.Portal, they are discovered
+// through the rpc etcd registry and reloaded every Refresh. A service which does not answer keeps its last documents.
+func NewPortalHttpHandler(config PortalConfig) http.Handler {
+	if config.Title == "" {
+		config.Title = "API Portal"
+	}
+	if config.Refresh == 0 {
+		config.Refresh = time.Minute
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+	p := &portal{
+		config: config,
+		services: func() []string {
+			return rpc.ListServices(specsMethodPath, config.Etcd)
+		},
+		fetch: func(ctx ctxx.Context, service string) (*Specs, errx.Error) {
+			return specsMethod.WithServiceName(service).WithEtcd(config.Etcd).Call(ctx, types.Nil{})
+		},
+	}
+	mux := http.NewServeMux()
+	handleSpec(mux, "/rest", config.Title+" - Restful API", config.UI, false, func() ([]byte, errx.Error) {
+		return p.load().rest, nil
+	})
+	handleSpec(mux, "/rpc", config.Title+" - RPC API", config.UI, false, func() ([]byte, errx.Error) {
+		return p.load().rpc, nil
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" && r.URL.Path != "/index.html" {
+			http.NotFound(w, r)
+			return
+		}
+		buf := &bytes.Buffer{}
+		data := map[string]any{"Title": config.Title, "Services": p.load().services}
+		if e := portalPage.Execute(buf, data); e != nil {
+			logrus.WithError(e).Error("execute portal page template failed")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(buf.Bytes())
+	})
+	return mux
+}
+
+type portal struct {
+	config   PortalConfig
+	services func() []string
+	fetch    func(ctx ctxx.Context, service string) (*Specs, errx.Error)
+	loading  sync.Mutex        // held by the discovery in progress
+	specs    map[string]*Specs // last documents of every service, guarded by loading
+	mu       sync.Mutex
+	loadedAt time.Time
+	merged   *mergedSpecs
+}
+
+type mergedSpecs struct {
+	services []string
+	rest     []byte
+	rpc      []byte
+}
+
+// load returns the merged documents, requests arriving during a discovery are served the last merge.
+func (p *portal) load() mergedSpecs {
+	if merged, fresh := p.cached(); fresh {
+		return *merged
+	}
+	if !p.loading.TryLock() {
+		if merged, _ := p.cached(); merged != nil {
+			return *merged
+		}
+		p.loading.Lock()
+	}
+	defer p.loading.Unlock()
+	if merged, fresh := p.cached(); fresh {
+		return *merged
+	}
+	return p.discover()
+}
+
+func (p *portal) cached() (*mergedSpecs, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.merged, p.merged != nil && time.Since(p.loadedAt) < p.config.Refresh
+}
+
+// discover fetches the documents of all services concurrently and merges them.
+func (p *portal) discover() mergedSpecs {
+	ctx, cancel := ctxx.WithTimeout(ctxx.Background(), p.config.Timeout)
+	defer cancel()
+	services := p.services()
+	fetched := make([]*Specs, len(services))
+	wg := sync.WaitGroup{}
+	for i, service := range services {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			specs, err := p.fetch(ctx, service)
+			if err != nil {
+				logrus.WithError(err).Warnf("load documents of service %s failed", service)
+				return
+			}
+			fetched[i] = specs
+		}()
+	}
+	wg.Wait()
+
+	restSpecs := make(map[string]*openapi.OpenAPI)
+	rpcSpecs := make(map[string]*openapi.OpenAPI)
+	current := make(map[string]*Specs)
+	merged := mergedSpecs{}
+	for i, service := range services {
+		specs := fetched[i]
+		if specs == nil {
+			if specs = p.specs[service]; specs == nil {
+				continue
+			}
+		}
+		current[service] = specs
+		merged.services = append(merged.services, service)
+		decodeSpec(service, specs.Rest, restSpecs)
+		decodeSpec(service, specs.Rpc, rpcSpecs)
+	}
+	p.specs = current
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var last mergedSpecs
+	if p.merged != nil {
+		last = *p.merged
+	}
+	merged.rest = marshalSpec(openapi.Merge(p.config.Title+" - Restful API", restSpecs), last.rest)
+	merged.rpc = marshalSpec(openapi.Merge(p.config.Title+" - RPC API", rpcSpecs), last.rpc)
+	p.merged = &merged
+	p.loadedAt = time.Now()
+	return merged
+}
+
+// marshalSpec falls back to the last document when spec can not be encoded.
+func marshalSpec(spec *openapi.OpenAPI, last []byte) []byte {
+	data, e := json.Marshal(spec)
+	if e != nil {
+		logrus.WithError(e).Errorf("marshal merged document %s failed", spec.Info.Title)
+		return last
+	}
+	return data
+}
+
+func decodeSpec(service string, data []byte, specs map[string]*openapi.OpenAPI) {
+	if len(data) == 0 {
+		return
+	}
+	spec := &openapi.OpenAPI{}
+	if e := json.Unmarshal(data, spec); e != nil {
+		logrus.WithError(e).Warnf("decode document of service %s failed", service)
+		return
+	}
+	specs[service] = spec
+}
+
+var portalPage = template.Must(template.New("portal").Parse(`<!DOCTYPE html>
+<html>
+  <head>
+    <title>{{.Title}}</title>
+    <meta charset="utf-8">
+    <link rel="icon" href="https://cdn.jsdelivr.net/gh/twitter/twemoji/2/72x72/1f600.png" type="image/png">
+    <style>body { font: 14px sans-serif; margin: 24px; }</style>
+  </head>
+  <body>
+    <h2>{{.Title}}</h2>
+    <ul>
+      <li><a href="rest/index.html">Restful API</a></li>
+      <li><a href="rpc/index.html">RPC API</a></li>
+    </ul>
+    <h3>Services</h3>
+    <ul>
+      {{- range .Services}}
+      <li>{{.}}</li>
+      {{- else}}
+      <li>No service found</li>
+      {{- end}}
+    </ul>
+  </body>
+</html>`))
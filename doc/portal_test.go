@@ -0,0 +1,137 @@
+package doc
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tencent-go/pkg/ctxx"
+	"github.com/tencent-go/pkg/doc/openapi"
+	"github.com/tencent-go/pkg/errx"
+	"github.com/tencent-go/pkg/rpc"
+	"github.com/tencent-go/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPortalLoad(t *testing.T) {
+	newSpecs := func(path string) *Specs {
+		spec := openapi.NewDefault()
+		spec.Paths[path] = &openapi.PathItem{Get: &openapi.Operation{OperationID: "get"}}
+		data, _ := json.Marshal(spec)
+		return &Specs{Rest: data}
+	}
+	paths := func(data []byte) []string {
+		spec := &openapi.OpenAPI{}
+		_ = json.Unmarshal(data, spec)
+		var res []string
+		for p := range spec.Paths {
+			res = append(res, p)
+		}
+		return res
+	}
+
+	t.Run("並行取得且受逾時限制", func(t *testing.T) {
+		var running, maxRunning atomic.Int32
+		p := &portal{
+			config:   PortalConfig{Title: "Portal", Refresh: time.Minute, Timeout: 50 * time.Millisecond},
+			services: func() []string { return []string{"a", "b", "slow"} },
+			fetch: func(ctx ctxx.Context, service string) (*Specs, errx.Error) {
+				n := running.Add(1)
+				defer running.Add(-1)
+				for m := maxRunning.Load(); n > m && !maxRunning.CompareAndSwap(m, n); m = maxRunning.Load() {
+				}
+				if service == "slow" {
+					<-ctx.Done()
+					return nil, errx.Wrap(ctx.Err()).Err()
+				}
+				time.Sleep(20 * time.Millisecond)
+				return newSpecs("/" + service), nil
+			},
+		}
+		start := time.Now()
+		merged := p.load()
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Equal(t, int32(3), maxRunning.Load())
+		assert.Equal(t, []string{"a", "b"}, merged.services)
+		assert.ElementsMatch(t, []string{"/a", "/b"}, paths(merged.rest))
+	})
+
+	t.Run("取得失敗時沿用上次的文件", func(t *testing.T) {
+		var fail atomic.Bool
+		p := &portal{
+			config:   PortalConfig{Title: "Portal", Refresh: time.Nanosecond, Timeout: time.Second},
+			services: func() []string { return []string{"a"} },
+			fetch: func(ctx ctxx.Context, service string) (*Specs, errx.Error) {
+				if fail.Load() {
+					return nil, errx.New("unavailable")
+				}
+				return newSpecs("/a"), nil
+			},
+		}
+		assert.Equal(t, []string{"/a"}, paths(p.load().rest))
+		fail.Store(true)
+		merged := p.load()
+		assert.Equal(t, []string{"a"}, merged.services)
+		assert.Equal(t, []string{"/a"}, paths(merged.rest))
+	})
+
+	t.Run("載入中的請求取得上次的合併結果", func(t *testing.T) {
+		release := make(chan struct{})
+		var calls atomic.Int32
+		p := &portal{
+			config:   PortalConfig{Title: "Portal", Refresh: time.Nanosecond, Timeout: time.Second},
+			services: func() []string { return []string{"a"} },
+			fetch: func(ctx ctxx.Context, service string) (*Specs, errx.Error) {
+				if calls.Add(1) > 1 {
+					<-release
+				}
+				return newSpecs("/a"), nil
+			},
+		}
+		first := p.load()
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.load()
+		}()
+		for calls.Load() < 2 {
+			time.Sleep(time.Millisecond)
+		}
+		done := make(chan mergedSpecs)
+		go func() {
+			done <- p.load()
+		}()
+		select {
+		case merged := <-done:
+			assert.Equal(t, first.rest, merged.rest)
+		case <-time.After(time.Second):
+			t.Fatal("load blocked by the discovery in progress")
+		}
+		close(release)
+		wg.Wait()
+		assert.Equal(t, int32(2), calls.Load())
+	})
+}
+
+func TestRegisterSpecs(t *testing.T) {
+	var handlers []rpc.Handler[types.Nil, Specs]
+	handleSpecs = func(handler rpc.Handler[types.Nil, Specs]) {
+		handlers = append(handlers, handler)
+	}
+	group := func(path string) []rpc.Group {
+		return []rpc.Group{{Path: path, Routes: []rpc.Route{rpc.ProxyRoute("", rpc.NewMethod[types.Nil, types.Nil]("ping"))}}}
+	}
+	NewSimpleHttpHandler(Config{Rpc: group("a"), Portal: true})
+	NewSimpleHttpHandler(Config{Rpc: group("b"), Portal: true})
+
+	if !assert.Len(t, handlers, 1, "僅註冊一次") {
+		return
+	}
+	specs, err := handlers[0](nil, types.Nil{})
+	assert.Nil(t, err)
+	assert.Contains(t, string(specs.Rpc), "/b/ping", "提供最後建立的文件")
+	assert.NotContains(t, string(specs.Rpc), "/a/ping")
+}
@@ -30,6 +30,9 @@ type Endpoint struct {
 	Header                 *schema.Class
 	Body                   *schema.Type
 	Response               *schema.Type
+	RequestContentType     api.ContentType
+	ResponseContentType    api.ContentType
+	WrapOutput             bool // the response is wrapped in the {success,data,error} envelope
 }
 
 func NewGroups(schemaCollection schema.Collection, routes []api.Route, permCollection api.PermissionProvider) []Group {
@@ -116,6 +119,9 @@ func (f *factory) parseRoute(route api.Route) {
 		Path:                   route.Path(),
 		AuthenticationRequired: route.RequireAuthentication(),
 		AuthorizationRequired:  route.RequireAuthorization(),
+		RequestContentType:     route.RequestContentType(),
+		ResponseContentType:    route.ResponseContentType(),
+		WrapOutput:             route.RequireWrapOutput() && route.ResponseContentType() == api.ContentTypeApplicationJson,
 	}
 	if d := route.Endpoint().Description(); d != nil {
 		end.Description = *d
@@ -8,6 +8,7 @@ import (
 
 	"github.com/tencent-go/pkg/types"
 	"github.com/tencent-go/pkg/util"
+	"github.com/tencent-go/pkg/validation"
 	"github.com/shopspring/decimal"
 	"github.com/sirupsen/logrus"
 )
@@ -231,6 +232,7 @@ func (c *collection) parseAndGetClass(typ reflect.Type, tag util.StructTag) (*Cl
 	p.classMap[name] = class
 	p.Classes = append(p.Classes, class)
 	c.parseFields(typ, tag, &class.Fields)
+	resolveFieldNames(class.Fields)
 	return class, true
 }
 
@@ -268,6 +270,20 @@ func (c *collection) parseFields(typ reflect.Type, _tag util.StructTag, fields *
 			Type:     *t,
 			Optional: optional,
 		}
+		if vTag := goField.Tag.Get("validate"); vTag != "" && vTag != "-" && !strings.HasPrefix(vTag, "ignore") {
+			rule := &validation.Rule{}
+			if err := rule.Parse(vTag); err != nil {
+				logrus.WithError(err).Warnf("parse validate tag of %s.%s failed", typ.Name(), goField.Name)
+			} else {
+				f.Rule = rule
+				f.Constraints = newConstraints(goField.Type, rule)
+				if rule.Required != nil {
+					f.Optional = !*rule.Required
+				} else if rule.RequiredIf != nil || rule.RequiredWithout != nil {
+					f.Optional = true
+				}
+			}
+		}
 		*fields = append(*fields, f)
 	}
 }
@@ -0,0 +1,148 @@
+package schema
+
+import (
+	"reflect"
+	"strconv"
+
+	"github.com/tencent-go/pkg/validation"
+)
+
+// Constraints are the validation rules of a field in JSON Schema terms. Min and max become
+// Minimum/Maximum for numbers, MinLength/MaxLength for strings, MinItems/MaxItems for arrays and
+// MinProperties/MaxProperties for maps. Cross-field references use the field names of the class.
+type Constraints struct {
+	Minimum         *float64
+	Maximum         *float64
+	MinLength       *int
+	MaxLength       *int
+	MinItems        *int
+	MaxItems        *int
+	MinProperties   *int
+	MaxProperties   *int
+	Pattern         string
+	Enum            []any
+	UniqueItems     bool
+	RequiredIf      *validation.FieldCondition
+	RequiredWithout string
+	EqField         string
+	GtField         string
+	Items           *Constraints // rules of the array elements or map values (dive)
+}
+
+func (c *Constraints) IsEmpty() bool {
+	return c == nil || reflect.ValueOf(*c).IsZero()
+}
+
+func newConstraints(typ reflect.Type, rule *validation.Rule) *Constraints {
+	if rule == nil {
+		return nil
+	}
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	c := &Constraints{}
+	kind := typ.Kind()
+	switch {
+	case kind >= reflect.Int && kind <= reflect.Float64:
+		c.Minimum = parseFloat(rule.Min)
+		c.Maximum = parseFloat(rule.Max)
+	case kind == reflect.String:
+		c.MinLength = parseInt(rule.Min)
+		c.MaxLength = parseInt(rule.Max)
+	case kind == reflect.Slice || kind == reflect.Array:
+		c.MinItems = parseInt(rule.Min)
+		c.MaxItems = parseInt(rule.Max)
+		c.UniqueItems = rule.Unique != nil && *rule.Unique
+		if rule.Dive != nil {
+			c.Items = newConstraints(typ.Elem(), rule.Dive)
+		}
+	case kind == reflect.Map:
+		c.MinProperties = parseInt(rule.Min)
+		c.MaxProperties = parseInt(rule.Max)
+		if rule.Dive != nil {
+			c.Items = newConstraints(typ.Elem(), rule.Dive)
+		}
+	}
+	if rule.Pattern != nil && kind == reflect.String {
+		c.Pattern = rule.Pattern.String()
+	}
+	for _, v := range rule.OneOf {
+		switch {
+		case kind >= reflect.Int && kind <= reflect.Float64:
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				c.Enum = append(c.Enum, f)
+			}
+		case kind == reflect.Bool:
+			if b, err := strconv.ParseBool(v); err == nil {
+				c.Enum = append(c.Enum, b)
+			}
+		default:
+			c.Enum = append(c.Enum, v)
+		}
+	}
+	if rule.RequiredIf != nil {
+		cond := *rule.RequiredIf
+		c.RequiredIf = &cond
+	}
+	if rule.RequiredWithout != nil {
+		c.RequiredWithout = *rule.RequiredWithout
+	}
+	if rule.EqField != nil {
+		c.EqField = *rule.EqField
+	}
+	if rule.GtField != nil {
+		c.GtField = *rule.GtField
+	}
+	if c.IsEmpty() {
+		return nil
+	}
+	return c
+}
+
+// resolveFieldNames replaces the Go field names of cross-field rules by the names of the class fields.
+func resolveFieldNames(fields []Field) {
+	names := make(map[string]string, len(fields))
+	for _, f := range fields {
+		names[f.GoField.Name] = f.Name
+	}
+	resolve := func(s string) string {
+		if n, ok := names[s]; ok {
+			return n
+		}
+		return s
+	}
+	for _, f := range fields {
+		c := f.Constraints
+		if c == nil {
+			continue
+		}
+		if c.RequiredIf != nil {
+			c.RequiredIf.Field = resolve(c.RequiredIf.Field)
+		}
+		c.RequiredWithout = resolve(c.RequiredWithout)
+		c.EqField = resolve(c.EqField)
+		c.GtField = resolve(c.GtField)
+	}
+}
+
+func parseFloat(s *string) *float64 {
+	if s == nil {
+		return nil
+	}
+	f, err := strconv.ParseFloat(*s, 64)
+	if err != nil {
+		return nil
+	}
+	return &f
+}
+
+func parseInt(s *string) *int {
+	if s == nil {
+		return nil
+	}
+	i, err := strconv.Atoi(*s)
+	if err != nil {
+		return nil
+	}
+	return &i
+}
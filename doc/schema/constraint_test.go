@@ -0,0 +1,34 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tencent-go/pkg/validation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewConstraints(t *testing.T) {
+	rule := func(tag string) *validation.Rule {
+		r := &validation.Rule{}
+		if err := r.Parse(tag); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	float := func(f float64) *float64 { return &f }
+	integer := func(i int) *int { return &i }
+
+	assert.Nil(t, newConstraints(reflect.TypeOf(0), nil))
+	assert.Nil(t, newConstraints(reflect.TypeOf(0), rule("required")))
+	assert.Equal(t, &Constraints{Minimum: float(1), Maximum: float(10)}, newConstraints(reflect.TypeOf(0), rule("min=1,max=10")))
+	assert.Equal(t, &Constraints{MinLength: integer(2), Pattern: "^a+$"}, newConstraints(reflect.TypeOf(new(string)), rule("min=2,pattern=^a+$")))
+	assert.Equal(t, &Constraints{Enum: []any{1.0, 2.0}}, newConstraints(reflect.TypeOf(int8(0)), rule("oneof=1|2|x")))
+	assert.Equal(t, &Constraints{Enum: []any{true}}, newConstraints(reflect.TypeOf(false), rule("oneof=true")))
+	assert.Equal(t, &Constraints{Enum: []any{"a", "b"}}, newConstraints(reflect.TypeOf(""), rule("oneof=a|b")))
+	assert.Equal(t, &Constraints{
+		MaxItems:    integer(3),
+		UniqueItems: true,
+		Items:       &Constraints{MaxLength: integer(5)},
+	}, newConstraints(reflect.TypeOf([]string{}), rule("max=3,unique,dive,max=5")))
+}
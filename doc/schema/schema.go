@@ -3,6 +3,7 @@ package schema
 import (
 	"github.com/tencent-go/pkg/types"
 	"github.com/tencent-go/pkg/util"
+	"github.com/tencent-go/pkg/validation"
 	"reflect"
 )
 
@@ -25,8 +26,10 @@ type Field struct {
 	GoField reflect.StructField
 	Name    string
 	//Generic  string
-	Type     Type
-	Optional bool
+	Type        Type
+	Optional    bool
+	Rule        *validation.Rule // parsed validate tag, nil when the field has none
+	Constraints *Constraints
 }
 
 type Enum struct {
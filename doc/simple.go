@@ -1,17 +1,20 @@
 package doc
 
 import (
-	"bytes"
 	"encoding/json"
-	"html/template"
 	"net/http"
 
+	"github.com/tencent-go/pkg/doc/asyncapi"
+	"github.com/tencent-go/pkg/doc/godoc"
+	"github.com/tencent-go/pkg/doc/natsdoc"
 	"github.com/tencent-go/pkg/doc/openapi"
 	"github.com/tencent-go/pkg/doc/restdoc"
 	"github.com/tencent-go/pkg/doc/rpcdoc"
 	"github.com/tencent-go/pkg/doc/schema"
+	"github.com/tencent-go/pkg/doc/snapshot"
 	"github.com/tencent-go/pkg/doc/tsdoc"
 	"github.com/tencent-go/pkg/doc/wsdoc"
+	"github.com/tencent-go/pkg/natsx"
 	"github.com/tencent-go/pkg/rest/api"
 	"github.com/tencent-go/pkg/rpc"
 	"github.com/tencent-go/pkg/util"
@@ -19,22 +22,29 @@ import (
 )
 
 type Config struct {
-	Rest      []api.Route
-	Rpc       []rpc.Group
-	Websocket []wsx.EventChannel
+	Rest                 []api.Route
+	Rpc                  []rpc.Group
+	Websocket            []wsx.EventChannel // channels the clients can subscribe
+	WebsocketPublishable []wsx.EventChannel // channels the clients can publish
+	Nats                 []natsx.SubjectDefinition
+	GoModule             string // module path of the generated Go client, "apiclient" by default
+	UI                   UI     // default viewer of the OpenAPI documents, swagger by default
+	Portal               bool   // publish the documents through the rpc registry for NewPortalHttpHandler
 }
 
 func NewSimpleHttpHandler(config Config) http.Handler {
 	mux := http.NewServeMux()
 	sc := schema.NewCollection()
 	var tsFiles []util.DataFile
-	swaggerTemp, e := template.New("swagger").Parse(swaggerHtml)
-	if e != nil {
-		panic(e)
+	var specs Specs
+	goModule := godoc.Module{Path: config.GoModule}
+	if goModule.Path == "" {
+		goModule.Path = "apiclient"
 	}
 	if rest := config.Rest; len(rest) > 0 {
 		model := restdoc.NewGroups(sc, rest, nil)
 		tsFiles = append(tsFiles, tsdoc.NewRestApiFiles(model, "restapi")...)
+		goModule.Rest = model
 		swagger := openapi.NewDefault()
 		swagger.ExternalDocs = &openapi.ExternalDocumentation{
 			Description: "Typescript",
@@ -46,96 +56,56 @@ func NewSimpleHttpHandler(config Config) http.Handler {
 		if e != nil {
 			panic(e)
 		}
-		var swaggerData []byte
-		{
-			buf := &bytes.Buffer{}
-			if e = swaggerTemp.Execute(buf, swagger.Info.Title); e != nil {
-				panic(e)
-			}
-			swaggerData = buf.Bytes()
-		}
-		mux.HandleFunc("/rest", func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Location", "rest/index.html")
-			w.WriteHeader(http.StatusFound)
-		})
-		mux.HandleFunc("/rest/", func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Location", "index.html")
-			w.WriteHeader(http.StatusFound)
-		})
-		mux.HandleFunc("/rest/index.html", func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			w.WriteHeader(http.StatusOK)
-			_, _ = w.Write(swaggerData)
-		})
-		mux.HandleFunc("/rest/doc.json", func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			_, _ = w.Write(jsonFile)
-		})
+		specs.Rest = jsonFile
+		handleSpec(mux, "/rest", swagger.Info.Title, config.UI, false, staticSpec(jsonFile))
 	}
 	if rpcGroups := config.Rpc; len(rpcGroups) > 0 {
 		model := rpcdoc.NewGroup(sc, rpcGroups)
 		tsFiles = append(tsFiles, tsdoc.NewRpcApiFiles(model, "rpc")...)
+		goModule.Rpc = model
 		swagger := openapi.NewDefault()
 		swagger.ExternalDocs = &openapi.ExternalDocumentation{
 			Description: "Typescript",
 			URL:         "../ts.zip",
 		}
-		var formated []restdoc.Group
-		for _, group := range model {
-			g := restdoc.Group{
-				Name:        group.Name,
-				Description: group.Description,
-			}
-			for _, method := range group.Methods {
-				end := restdoc.Endpoint{
-					Name:        method.Name,
-					Path:        method.Path,
-					Description: method.Description,
-					Method:      api.MethodPost,
-					Body:        method.RequestType,
-					Response:    method.ResponseType,
-				}
-				g.Endpoints = append(g.Endpoints, end)
-			}
-			formated = append(formated, g)
-		}
-		swagger.Parse(formated)
+		swagger.ParseRpc(model)
 		swagger.Info.Title = "RPC API"
 		jsonFile, e := json.Marshal(swagger)
 		if e != nil {
 			panic(e)
 		}
-		var swaggerData []byte
-		{
-			buf := &bytes.Buffer{}
-			if e = swaggerTemp.Execute(buf, swagger.Info.Title); e != nil {
-				panic(e)
+		specs.Rpc = jsonFile
+		handleSpec(mux, "/rpc", swagger.Info.Title, config.UI, false, staticSpec(jsonFile))
+	}
+	asyncSpec := asyncapi.NewDefault()
+	asyncSpec.Info.Title = "Async API"
+	if len(config.Websocket) > 0 || len(config.WebsocketPublishable) > 0 {
+		model := wsdoc.NewGroups(sc, config.Websocket)
+		publishable := wsdoc.NewGroups(sc, config.WebsocketPublishable)
+		tsFiles = append(tsFiles, tsdoc.NewEventFile(model, publishable, "websocket")...)
+		goModule.Websocket = append([]wsdoc.EventChannel{}, model...)
+		// a channel both subscribable and publishable has a single topic constant
+		topics := make(map[string]bool, len(model))
+		for _, c := range model {
+			topics[c.Topic] = true
+		}
+		for _, c := range publishable {
+			if !topics[c.Topic] {
+				topics[c.Topic] = true
+				goModule.Websocket = append(goModule.Websocket, c)
 			}
-			swaggerData = buf.Bytes()
 		}
-		mux.HandleFunc("/rpc", func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Location", "rpc/index.html")
-			w.WriteHeader(http.StatusFound)
-		})
-		mux.HandleFunc("/rpc/", func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Location", "index.html")
-			w.WriteHeader(http.StatusFound)
-		})
-		mux.HandleFunc("/rpc/index.html", func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			w.WriteHeader(http.StatusOK)
-			_, _ = w.Write(swaggerData)
-		})
-		mux.HandleFunc("/rpc/doc.json", func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			_, _ = w.Write(jsonFile)
-		})
+		asyncSpec.ParseWebsocket(model, publishable)
+	}
+	if subjects := config.Nats; len(subjects) > 0 {
+		asyncSpec.ParseNats(natsdoc.NewSubjects(sc, subjects))
 	}
-	if ws := config.Websocket; len(ws) > 0 {
-		model := wsdoc.NewGroups(sc, ws)
-		tsFiles = append(tsFiles, tsdoc.NewEventFile(model, nil, "websocket")...)
+	if len(asyncSpec.Channels) > 0 {
+		jsonFile, e := json.Marshal(asyncSpec)
+		if e != nil {
+			panic(e)
+		}
+		handleSpec(mux, "/async", asyncSpec.Info.Title, config.UI, true, staticSpec(jsonFile))
 	}
 	tsFiles = append(tsFiles, tsdoc.NewDictionariesFile(sc.Packages()))
 	tsFiles = append(tsFiles, tsdoc.NewSchemaFiles(sc.Packages(), "schema")...)
@@ -148,25 +118,29 @@ func NewSimpleHttpHandler(config Config) http.Handler {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write(tsZipFile)
 	})
+	goModule.Packages = sc.Packages()
+	goZipFile, err := util.ZipFilesBytes(godoc.NewModuleFiles(goModule))
+	if err != nil {
+		panic(err)
+	}
+	mux.HandleFunc("/go.zip", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/zip")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(goZipFile)
+	})
+	if config.Portal {
+		registerSpecs(specs)
+	}
 	return mux
 }
 
-var swaggerHtml = `<!DOCTYPE html>
-<html>
-  <head>
-    <title>{{.}}</title>
-    <link rel="icon" href="https://cdn.jsdelivr.net/gh/twitter/twemoji/2/72x72/1f600.png" type="image/png">
-    <link rel="stylesheet" type="text/css" href="https://cdnjs.cloudflare.com/ajax/libs/swagger-ui/4.18.1/swagger-ui.css">
-  </head>
-  <body>
-    <div id="swagger-ui"></div>
-    <script src="https://cdnjs.cloudflare.com/ajax/libs/swagger-ui/4.18.1/swagger-ui-bundle.js"></script>
-    <script>
-      const ui = SwaggerUIBundle({
-        url: "doc.json",
-        dom_id: '#swagger-ui',
-        persistAuthorization: true,
-      });
-    </script>
-  </body>
-</html>`
+// NewSnapshot takes the snapshot of the APIs of config, e.g. to be compared with a committed baseline by
+// snapshot.CompareWithBaseline in a test.
+func NewSnapshot(config Config) *snapshot.Snapshot {
+	sc := schema.NewCollection()
+	rest := restdoc.NewGroups(sc, config.Rest, nil)
+	rpcGroups := rpcdoc.NewGroup(sc, config.Rpc)
+	channels := append(append([]wsx.EventChannel{}, config.Websocket...), config.WebsocketPublishable...)
+	websocket := wsdoc.NewGroups(sc, channels)
+	return snapshot.New(sc.Packages(), rest, rpcGroups, websocket)
+}
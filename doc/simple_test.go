@@ -0,0 +1,63 @@
+package doc
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tencent-go/pkg/wsx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimpleHttpHandler(t *testing.T) {
+	type chatMessage struct {
+		Text string `json:"text"`
+	}
+	handler := NewSimpleHttpHandler(Config{
+		Websocket: []wsx.EventChannel{wsx.NewEventChannel[chatMessage]("chat")},
+		WebsocketPublishable: []wsx.EventChannel{
+			wsx.NewEventChannel[chatMessage]("chat"),
+			wsx.NewEventChannel[chatMessage]("typing"),
+			wsx.NewRequestChannel[chatMessage, chatMessage]("chat.send"),
+		},
+	})
+	files := func(path string) string {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		r, e := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+		if !assert.NoError(t, e) {
+			return ""
+		}
+		var src strings.Builder
+		for _, f := range r.File {
+			if !strings.Contains(f.Name, "websocket") {
+				continue
+			}
+			rc, e := f.Open()
+			if !assert.NoError(t, e) {
+				return ""
+			}
+			_, _ = io.Copy(&src, rc)
+			_ = rc.Close()
+		}
+		return src.String()
+	}
+
+	t.Run("ts包含所有可發布頻道", func(t *testing.T) {
+		src := files("/ts.zip")
+		assert.Contains(t, src, "remoteEventBus.publisherChannel<")
+		assert.Contains(t, src, "('typing')")
+		assert.Contains(t, src, "requestWs('chat.send', data)")
+	})
+
+	t.Run("go每個頻道僅一個常數", func(t *testing.T) {
+		src := files("/go.zip")
+		assert.Equal(t, 1, strings.Count(src, `= "chat"`))
+		assert.Contains(t, src, `= "typing"`)
+		assert.Contains(t, src, `= "chat.send"`)
+	})
+}
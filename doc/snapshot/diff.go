@@ -0,0 +1,298 @@
+package snapshot
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/tencent-go/pkg/errx"
+)
+
+type Severity string
+
+const (
+	SeverityBreaking    Severity = "breaking"
+	SeverityNonBreaking Severity = "non_breaking"
+)
+
+type Change struct {
+	Severity Severity `json:"severity"`
+	Location string   `json:"location"` // e.g. rest.user.create.body.address.city
+	Message  string   `json:"message"`
+	Old      string   `json:"old,omitempty"`
+	New      string   `json:"new,omitempty"`
+}
+
+type Report struct {
+	Changes []Change `json:"changes"`
+}
+
+func (r *Report) Breaking() []Change {
+	var res []Change
+	for _, c := range r.Changes {
+		if c.Severity == SeverityBreaking {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+func (r *Report) HasBreaking() bool {
+	return len(r.Breaking()) > 0
+}
+
+// CompareWithBaseline compares current with the snapshot saved in the baseline file, the baseline is
+// created from current when it does not exist yet.
+func CompareWithBaseline(baseline string, current *Snapshot) (*Report, errx.Error) {
+	old, err := Load(baseline)
+	if err != nil {
+		if err.Type() != errx.TypeNotFound {
+			return nil, err
+		}
+		return &Report{}, current.Save(baseline)
+	}
+	return Compare(old, current), nil
+}
+
+// Compare lists the changes from old to new. The severity of a field or enum change depends on who reads
+// the type, e.g. a new required field breaks the requests but not the responses. A class used at several
+// locations is compared once per direction, at the first location reaching it.
+func Compare(old, new *Snapshot) *Report {
+	d := &differ{old: old, new: new, visited: make(map[string]bool), report: &Report{}}
+	for _, key := range unionKeys(old.Rest, new.Rest) {
+		o, inOld := old.Rest[key]
+		n, inNew := new.Rest[key]
+		loc := "rest." + key
+		switch {
+		case !inNew:
+			d.add(SeverityBreaking, loc, "endpoint removed", o.Method+" "+o.Path, "")
+		case !inOld:
+			d.add(SeverityNonBreaking, loc, "endpoint added", "", n.Method+" "+n.Path)
+		default:
+			d.compareEndpoint(loc, o, n)
+		}
+	}
+	for _, key := range unionKeys(old.Rpc, new.Rpc) {
+		o, inOld := old.Rpc[key]
+		n, inNew := new.Rpc[key]
+		loc := "rpc." + key
+		switch {
+		case !inNew:
+			d.add(SeverityBreaking, loc, "method removed", o.Path, "")
+		case !inOld:
+			d.add(SeverityNonBreaking, loc, "method added", "", n.Path)
+		default:
+			d.compareValue(loc, "path changed", o.Path, n.Path)
+			d.compareType(loc+".request", request, o.Request, n.Request)
+			d.compareType(loc+".response", response, o.Response, n.Response)
+		}
+	}
+	for _, key := range unionKeys(old.Websocket, new.Websocket) {
+		o, inOld := old.Websocket[key]
+		n, inNew := new.Websocket[key]
+		loc := "websocket." + key
+		switch {
+		case !inNew:
+			d.add(SeverityBreaking, loc, "channel removed", "", "")
+		case !inOld:
+			d.add(SeverityNonBreaking, loc, "channel added", "", "")
+		default:
+			// the type of a request channel is sent by the clients, the other channels can be both published and subscribed
+			dir := bidirectional
+			if o.Response != nil || n.Response != nil {
+				dir = request
+			}
+			d.compareType(loc, dir, o.Type, n.Type)
+			d.compareType(loc+".response", response, o.Response, n.Response)
+		}
+	}
+	return d.report
+}
+
+// direction tells who decodes a type, the server decodes the requests and the clients the responses.
+type direction int
+
+const (
+	request direction = 1 << iota
+	response
+	bidirectional = request | response
+)
+
+// severity is breaking when the change breaks the readers of one of the directions in breaks.
+func (dir direction) severity(breaks direction) Severity {
+	if dir&breaks != 0 {
+		return SeverityBreaking
+	}
+	return SeverityNonBreaking
+}
+
+type differ struct {
+	old, new *Snapshot
+	visited  map[string]bool
+	report   *Report
+}
+
+func (d *differ) add(severity Severity, location, message, old, new string) {
+	d.report.Changes = append(d.report.Changes, Change{
+		Severity: severity,
+		Location: location,
+		Message:  message,
+		Old:      old,
+		New:      new,
+	})
+}
+
+func (d *differ) compareValue(location, message, old, new string) {
+	if old != new {
+		d.add(SeverityBreaking, location, message, old, new)
+	}
+}
+
+func (d *differ) compareEndpoint(loc string, o, n Endpoint) {
+	d.compareValue(loc, "method changed", o.Method, n.Method)
+	d.compareValue(loc, "path changed", o.Path, n.Path)
+	d.compareValue(loc, "request content type changed", o.RequestContentType, n.RequestContentType)
+	d.compareValue(loc, "response content type changed", o.ResponseContentType, n.ResponseContentType)
+	d.compareValue(loc, "response envelope changed", fmt.Sprint(o.WrapOutput), fmt.Sprint(n.WrapOutput))
+	if o.AuthenticationRequired != n.AuthenticationRequired {
+		severity := SeverityNonBreaking
+		if n.AuthenticationRequired {
+			severity = SeverityBreaking
+		}
+		d.add(severity, loc, "authentication requirement changed", fmt.Sprint(o.AuthenticationRequired), fmt.Sprint(n.AuthenticationRequired))
+	}
+	d.compareClass(loc+".query", request, o.Query, n.Query)
+	d.compareClass(loc+".param", request, o.Param, n.Param)
+	d.compareClass(loc+".header", request, o.Header, n.Header)
+	d.compareType(loc+".body", request, o.Body, n.Body)
+	d.compareType(loc+".response", response, o.Response, n.Response)
+}
+
+func (d *differ) compareType(loc string, dir direction, o, n *Type) {
+	if o == nil && n == nil {
+		return
+	}
+	if o == nil || n == nil || o.Kind != n.Kind {
+		d.add(SeverityBreaking, loc, "type changed", typeString(o), typeString(n))
+		return
+	}
+	if o.Nullable != n.Nullable {
+		// null is a new value for the readers of the responses and no longer accepted from the requests
+		breaks := request
+		if n.Nullable {
+			breaks = response
+		}
+		d.add(dir.severity(breaks), loc, "nullability changed", typeString(o), typeString(n))
+	}
+	switch o.Kind {
+	case "class":
+		d.compareClass(loc, dir, o.Ref, n.Ref)
+	case "enum":
+		d.compareEnum(loc, dir, o.Ref, n.Ref)
+	case "array":
+		d.compareType(loc+"[]", dir, o.Elem, n.Elem)
+	case "map":
+		d.compareType(loc+"{key}", dir, o.Key, n.Key)
+		d.compareType(loc+"{}", dir, o.Elem, n.Elem)
+	}
+}
+
+// compareClass compares the fields, an empty ref is a class without fields. The servers ignore the fields
+// they no longer read, while the clients may miss the fields no longer sent.
+func (d *differ) compareClass(loc string, dir direction, oRef, nRef string) {
+	if oRef == "" && nRef == "" {
+		return
+	}
+	visitKey := fmt.Sprintf("class:%d:%s>%s", dir, oRef, nRef)
+	if d.visited[visitKey] {
+		return
+	}
+	d.visited[visitKey] = true
+	o, n := d.old.Classes[oRef], d.new.Classes[nRef]
+	for _, name := range unionKeys(o.Fields, n.Fields) {
+		of, inOld := o.Fields[name]
+		nf, inNew := n.Fields[name]
+		fieldLoc := loc + "." + name
+		switch {
+		case !inNew:
+			d.add(dir.severity(response), fieldLoc, "field removed", of.Type.String(), "")
+		case !inOld && nf.Optional:
+			d.add(SeverityNonBreaking, fieldLoc, "optional field added", "", nf.Type.String())
+		case !inOld:
+			d.add(dir.severity(request), fieldLoc, "required field added", "", nf.Type.String())
+		default:
+			if of.Optional != nf.Optional {
+				breaks := response
+				if !nf.Optional {
+					breaks = request
+				}
+				d.add(dir.severity(breaks), fieldLoc, "optionality changed", optionality(of.Optional), optionality(nf.Optional))
+			}
+			d.compareType(fieldLoc, dir, &of.Type, &nf.Type)
+		}
+	}
+}
+
+// compareEnum compares the items, a removed item is rejected from the requests and an added one may be
+// unknown to the readers of the responses.
+func (d *differ) compareEnum(loc string, dir direction, oRef, nRef string) {
+	visitKey := fmt.Sprintf("enum:%d:%s>%s", dir, oRef, nRef)
+	if d.visited[visitKey] {
+		return
+	}
+	d.visited[visitKey] = true
+	o, n := d.old.Enums[oRef], d.new.Enums[nRef]
+	if o.Numeric != n.Numeric {
+		d.add(SeverityBreaking, loc, "enum value type changed", enumKind(o.Numeric), enumKind(n.Numeric))
+		return
+	}
+	items := make(map[string]bool, len(n.Items))
+	for _, it := range n.Items {
+		items[it] = true
+	}
+	for _, it := range o.Items {
+		if !items[it] {
+			d.add(dir.severity(request), loc, "enum item removed", it, "")
+		}
+		delete(items, it)
+	}
+	for _, it := range n.Items {
+		if items[it] {
+			d.add(dir.severity(response), loc, "enum item added", "", it)
+		}
+	}
+}
+
+func typeString(t *Type) string {
+	if t == nil {
+		return "none"
+	}
+	return t.String()
+}
+
+func optionality(optional bool) string {
+	if optional {
+		return "optional"
+	}
+	return "required"
+}
+
+func enumKind(numeric bool) string {
+	if numeric {
+		return "number"
+	}
+	return "string"
+}
+
+func unionKeys[V any](a, b map[string]V) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
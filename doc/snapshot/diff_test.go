@@ -0,0 +1,169 @@
+package snapshot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompare(t *testing.T) {
+	str := Type{Kind: "string"}
+	status := Field{Type: Type{Kind: "enum", Ref: "pkg.Status"}}
+	class := func(fields map[string]Field) Class {
+		res := Class{Fields: map[string]Field{"status": status}}
+		for k, v := range fields {
+			res.Fields[k] = v
+		}
+		return res
+	}
+	enum := func(items ...string) Enum {
+		return Enum{Items: items}
+	}
+	orderRef := &Type{Kind: "class", Ref: "pkg.Order"}
+	newSnapshot := func(c Class, e Enum, m Method) *Snapshot {
+		return &Snapshot{
+			Classes: map[string]Class{"pkg.Order": c},
+			Enums:   map[string]Enum{"pkg.Status": e},
+			Rpc:     map[string]Method{"order.save": m},
+		}
+	}
+
+	cases := []struct {
+		name               string
+		oldClass, newClass Class
+		oldEnum, newEnum   Enum
+		location, message  string
+		request, response  Severity
+	}{
+		{
+			name:     "新增選填欄位",
+			oldClass: class(nil), newClass: class(map[string]Field{"note": {Type: str, Optional: true}}),
+			location: "note", message: "optional field added",
+			request: SeverityNonBreaking, response: SeverityNonBreaking,
+		},
+		{
+			name:     "新增必填欄位",
+			oldClass: class(nil), newClass: class(map[string]Field{"note": {Type: str}}),
+			location: "note", message: "required field added",
+			request: SeverityBreaking, response: SeverityNonBreaking,
+		},
+		{
+			name:     "移除欄位",
+			oldClass: class(map[string]Field{"note": {Type: str}}), newClass: class(nil),
+			location: "note", message: "field removed",
+			request: SeverityNonBreaking, response: SeverityBreaking,
+		},
+		{
+			name:     "選填改為必填",
+			oldClass: class(map[string]Field{"note": {Type: str, Optional: true}}), newClass: class(map[string]Field{"note": {Type: str}}),
+			location: "note", message: "optionality changed",
+			request: SeverityBreaking, response: SeverityNonBreaking,
+		},
+		{
+			name:     "必填改為選填",
+			oldClass: class(map[string]Field{"note": {Type: str}}), newClass: class(map[string]Field{"note": {Type: str, Optional: true}}),
+			location: "note", message: "optionality changed",
+			request: SeverityNonBreaking, response: SeverityBreaking,
+		},
+		{
+			name:     "改為可空",
+			oldClass: class(map[string]Field{"note": {Type: str}}), newClass: class(map[string]Field{"note": {Type: Type{Kind: "string", Nullable: true}}}),
+			location: "note", message: "nullability changed",
+			request: SeverityNonBreaking, response: SeverityBreaking,
+		},
+		{
+			name:     "改為不可空",
+			oldClass: class(map[string]Field{"note": {Type: Type{Kind: "string", Nullable: true}}}), newClass: class(map[string]Field{"note": {Type: str}}),
+			location: "note", message: "nullability changed",
+			request: SeverityBreaking, response: SeverityNonBreaking,
+		},
+		{
+			name:     "類型變更",
+			oldClass: class(map[string]Field{"note": {Type: str}}), newClass: class(map[string]Field{"note": {Type: Type{Kind: "number"}}}),
+			location: "note", message: "type changed",
+			request: SeverityBreaking, response: SeverityBreaking,
+		},
+		{
+			name:    "新增列舉值",
+			oldEnum: enum("paid"), newEnum: enum("paid", "refunded"),
+			location: "status", message: "enum item added",
+			request: SeverityNonBreaking, response: SeverityBreaking,
+		},
+		{
+			name:    "移除列舉值",
+			oldEnum: enum("paid", "refunded"), newEnum: enum("paid"),
+			location: "status", message: "enum item removed",
+			request: SeverityBreaking, response: SeverityNonBreaking,
+		},
+	}
+	for _, c := range cases {
+		if c.oldClass.Fields == nil {
+			c.oldClass, c.newClass = class(nil), class(nil)
+		}
+		if c.oldEnum.Items == nil {
+			c.oldEnum, c.newEnum = enum("paid"), enum("paid")
+		}
+		t.Run(c.name, func(t *testing.T) {
+			report := Compare(
+				newSnapshot(c.oldClass, c.oldEnum, Method{Path: "/order/save", Request: orderRef}),
+				newSnapshot(c.newClass, c.newEnum, Method{Path: "/order/save", Request: orderRef}),
+			)
+			if assert.Len(t, report.Changes, 1) {
+				assert.Equal(t, "rpc.order.save.request."+c.location, report.Changes[0].Location)
+				assert.Equal(t, c.message, report.Changes[0].Message)
+				assert.Equal(t, c.request, report.Changes[0].Severity, "request")
+			}
+
+			report = Compare(
+				newSnapshot(c.oldClass, c.oldEnum, Method{Path: "/order/save", Response: orderRef}),
+				newSnapshot(c.newClass, c.newEnum, Method{Path: "/order/save", Response: orderRef}),
+			)
+			if assert.Len(t, report.Changes, 1) {
+				assert.Equal(t, "rpc.order.save.response."+c.location, report.Changes[0].Location)
+				assert.Equal(t, c.response, report.Changes[0].Severity, "response")
+			}
+		})
+	}
+
+	t.Run("請求與回應共用的類別各比較一次", func(t *testing.T) {
+		m := Method{Path: "/order/save", Request: orderRef, Response: orderRef}
+		report := Compare(
+			newSnapshot(class(nil), enum("paid"), m),
+			newSnapshot(class(map[string]Field{"note": {Type: str}}), enum("paid"), m),
+		)
+		assert.Equal(t, []Change{
+			{Severity: SeverityBreaking, Location: "rpc.order.save.request.note", Message: "required field added", New: "string"},
+			{Severity: SeverityNonBreaking, Location: "rpc.order.save.response.note", Message: "required field added", New: "string"},
+		}, report.Changes)
+	})
+
+	t.Run("websocket頻道", func(t *testing.T) {
+		newChannels := func(c Class, response *Type) *Snapshot {
+			s := newSnapshot(c, enum("paid"), Method{})
+			s.Rpc = nil
+			s.Websocket = map[string]Channel{"order": {Type: orderRef, Response: response}}
+			return s
+		}
+		withNote, withoutNote := class(map[string]Field{"note": {Type: str}}), class(nil)
+		report := Compare(newChannels(withNote, nil), newChannels(withoutNote, nil))
+		assert.True(t, report.HasBreaking(), "a channel can be both published and subscribed")
+
+		report = Compare(newChannels(withNote, &str), newChannels(withoutNote, &str))
+		if assert.Len(t, report.Changes, 1) {
+			assert.Equal(t, SeverityNonBreaking, report.Changes[0].Severity, "the type of a request channel is sent by the clients")
+		}
+	})
+
+	t.Run("rest查詢參數", func(t *testing.T) {
+		newRest := func(c Class) *Snapshot {
+			return &Snapshot{
+				Classes: map[string]Class{"pkg.Query": c},
+				Rest:    map[string]Endpoint{"order.list": {Method: "GET", Path: "/orders", Query: "pkg.Query"}},
+			}
+		}
+		report := Compare(newRest(Class{Fields: map[string]Field{"page": {Type: str}}}), newRest(Class{}))
+		assert.Equal(t, []Change{
+			{Severity: SeverityNonBreaking, Location: "rest.order.list.query.page", Message: "field removed", Old: "string"},
+		}, report.Changes)
+	})
+}
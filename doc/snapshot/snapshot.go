@@ -0,0 +1,264 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+
+	"github.com/tencent-go/pkg/doc/restdoc"
+	"github.com/tencent-go/pkg/doc/rpcdoc"
+	"github.com/tencent-go/pkg/doc/schema"
+	"github.com/tencent-go/pkg/doc/wsdoc"
+	"github.com/tencent-go/pkg/errx"
+)
+
+// Snapshot is the wire contract of the APIs, maps are keyed by stable names so its JSON is stable too.
+// Classes and enums are keyed by "<package>.<name>".
+type Snapshot struct {
+	Classes   map[string]Class    `json:"classes"`
+	Enums     map[string]Enum     `json:"enums"`
+	Rest      map[string]Endpoint `json:"rest"`      // keyed by "<group>.<endpoint>"
+	Rpc       map[string]Method   `json:"rpc"`       // keyed by "<group>.<method>"
+	Websocket map[string]Channel  `json:"websocket"` // keyed by topic
+}
+
+type Class struct {
+	Fields map[string]Field `json:"fields"` // keyed by the JSON, query, path or header name
+}
+
+type Field struct {
+	Type     Type `json:"type"`
+	Optional bool `json:"optional,omitempty"`
+}
+
+type Enum struct {
+	Numeric bool     `json:"numeric,omitempty"`
+	Items   []string `json:"items"`
+}
+
+type Type struct {
+	Kind     string `json:"kind"` // null, any, number, string, boolean, class, enum, array or map
+	Nullable bool   `json:"nullable,omitempty"`
+	Ref      string `json:"ref,omitempty"` // key of the class or enum
+	Elem     *Type  `json:"elem,omitempty"`
+	Key      *Type  `json:"key,omitempty"`
+}
+
+type Endpoint struct {
+	Method                 string `json:"method"`
+	Path                   string `json:"path"`
+	Query                  string `json:"query,omitempty"`
+	Param                  string `json:"param,omitempty"`
+	Header                 string `json:"header,omitempty"`
+	Body                   *Type  `json:"body,omitempty"`
+	Response               *Type  `json:"response,omitempty"`
+	RequestContentType     string `json:"requestContentType,omitempty"`
+	ResponseContentType    string `json:"responseContentType,omitempty"`
+	WrapOutput             bool   `json:"wrapOutput,omitempty"`
+	AuthenticationRequired bool   `json:"authenticationRequired,omitempty"`
+}
+
+type Method struct {
+	Path     string `json:"path"`
+	Request  *Type  `json:"request,omitempty"`
+	Response *Type  `json:"response,omitempty"`
+}
+
+type Channel struct {
+	Type     *Type `json:"type,omitempty"`
+	Response *Type `json:"response,omitempty"` // reply of a request channel
+}
+
+func (t Type) String() string {
+	var res string
+	switch t.Kind {
+	case "class", "enum":
+		res = t.Ref
+	case "array":
+		res = "[]" + t.Elem.String()
+	case "map":
+		res = fmt.Sprintf("map[%s]%s", t.Key.String(), t.Elem.String())
+	default:
+		res = t.Kind
+	}
+	if t.Nullable {
+		res = "?" + res
+	}
+	return res
+}
+
+// New takes the snapshot of the doc models, the packages are those of the collection used to build them.
+func New(packages []*schema.Package, rest []restdoc.Group, rpc []rpcdoc.Group, websocket []wsdoc.EventChannel) *Snapshot {
+	s := &Snapshot{
+		Classes:   make(map[string]Class),
+		Enums:     make(map[string]Enum),
+		Rest:      make(map[string]Endpoint),
+		Rpc:       make(map[string]Method),
+		Websocket: make(map[string]Channel),
+	}
+	for _, pkg := range packages {
+		for _, c := range pkg.Classes {
+			s.Classes[classKey(c)] = newClass(c)
+		}
+		for _, e := range pkg.Enums {
+			s.Enums[enumKey(e)] = newEnum(e)
+		}
+	}
+	for _, g := range rest {
+		for _, e := range g.Endpoints {
+			endpoint := Endpoint{
+				Method:                 string(e.Method),
+				Path:                   path.Join("/", e.Path),
+				Body:                   newTypePtr(e.Body),
+				Response:               newTypePtr(e.Response),
+				RequestContentType:     string(e.RequestContentType),
+				ResponseContentType:    string(e.ResponseContentType),
+				WrapOutput:             e.WrapOutput,
+				AuthenticationRequired: e.AuthenticationRequired,
+			}
+			// parameters are classes of their own, which are not listed by the packages
+			if e.Query != nil {
+				endpoint.Query = classKey(e.Query)
+				s.Classes[endpoint.Query] = newClass(e.Query)
+			}
+			if e.Param != nil {
+				endpoint.Param = classKey(e.Param)
+				s.Classes[endpoint.Param] = newClass(e.Param)
+			}
+			if e.Header != nil {
+				endpoint.Header = classKey(e.Header)
+				s.Classes[endpoint.Header] = newClass(e.Header)
+			}
+			s.Rest[g.Name+"."+e.Name] = endpoint
+		}
+	}
+	for _, g := range rpc {
+		for _, m := range g.Methods {
+			s.Rpc[g.Name+"."+m.Name] = Method{
+				Path:     m.Path,
+				Request:  newTypePtr(m.RequestType),
+				Response: newTypePtr(m.ResponseType),
+			}
+		}
+	}
+	for _, c := range websocket {
+		s.Websocket[c.Topic] = Channel{Type: newTypePtr(c.Type), Response: newTypePtr(c.ResponseType)}
+	}
+	return s
+}
+
+func classKey(c *schema.Class) string {
+	if c.Package == nil {
+		return c.Name
+	}
+	return c.Package.Name + "." + c.Name
+}
+
+func enumKey(e *schema.Enum) string {
+	if e.Package == nil {
+		return e.Name
+	}
+	return e.Package.Name + "." + e.Name
+}
+
+func newClass(c *schema.Class) Class {
+	res := Class{Fields: make(map[string]Field, len(c.Fields))}
+	for _, f := range c.Fields {
+		res.Fields[f.Name] = Field{Type: newType(f.Type), Optional: f.Optional}
+	}
+	return res
+}
+
+func newEnum(e *schema.Enum) Enum {
+	res := Enum{Numeric: e.IsNumeric}
+	for _, it := range e.Items {
+		res.Items = append(res.Items, fmt.Sprint(it.Value))
+	}
+	sort.Strings(res.Items)
+	return res
+}
+
+func newTypePtr(t *schema.Type) *Type {
+	if t == nil {
+		return nil
+	}
+	res := newType(*t)
+	return &res
+}
+
+func newType(t schema.Type) Type {
+	res := Type{Nullable: t.Nullable}
+	if t.Enum != nil {
+		res.Kind = "enum"
+		res.Ref = enumKey(t.Enum)
+		return res
+	}
+	switch t.BaseType {
+	case schema.BaseTypeNull:
+		res.Kind = "null"
+	case schema.BaseTypeNumber:
+		res.Kind = "number"
+	case schema.BaseTypeString:
+		res.Kind = "string"
+	case schema.BaseTypeBoolean:
+		res.Kind = "boolean"
+	case schema.BaseTypeClass:
+		res.Kind = "any"
+		if t.Class != nil {
+			res.Kind = "class"
+			res.Ref = classKey(t.Class)
+		}
+	case schema.BaseTypeArray:
+		res.Kind = "array"
+		res.Elem = &Type{Kind: "any"}
+		if t.Array != nil {
+			res.Elem = newTypePtr(t.Array)
+		}
+	case schema.BaseTypeMap:
+		res.Kind = "map"
+		res.Key, res.Elem = &Type{Kind: "string"}, &Type{Kind: "any"}
+		if t.Map != nil {
+			res.Key, res.Elem = newTypePtr(&t.Map.KeyType), newTypePtr(&t.Map.ValueType)
+		}
+	default:
+		res.Kind = "any"
+	}
+	return res
+}
+
+func (s *Snapshot) Marshal() ([]byte, errx.Error) {
+	data, e := json.MarshalIndent(s, "", "  ")
+	if e != nil {
+		return nil, errx.Wrap(e).AppendMsg("marshal api snapshot failed").Err()
+	}
+	return append(data, '\n'), nil
+}
+
+// Save writes the snapshot to the file, e.g. the baseline committed next to a test.
+func (s *Snapshot) Save(file string) errx.Error {
+	data, err := s.Marshal()
+	if err != nil {
+		return err
+	}
+	if e := os.WriteFile(file, data, 0644); e != nil {
+		return errx.Wrap(e).AppendMsgf("write api snapshot %s failed", file).Err()
+	}
+	return nil
+}
+
+func Load(file string) (*Snapshot, errx.Error) {
+	data, e := os.ReadFile(file)
+	if e != nil {
+		if os.IsNotExist(e) {
+			return nil, errx.Wrap(e).WithType(errx.TypeNotFound).AppendMsgf("api snapshot %s not found", file).Err()
+		}
+		return nil, errx.Wrap(e).AppendMsgf("read api snapshot %s failed", file).Err()
+	}
+	s := &Snapshot{}
+	if e = json.Unmarshal(data, s); e != nil {
+		return nil, errx.Wrap(e).AppendMsgf("parse api snapshot %s failed", file).Err()
+	}
+	return s, nil
+}
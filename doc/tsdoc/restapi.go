@@ -19,6 +19,20 @@ func NewRestApiFiles(groups []restdoc.Group, parentDir ...string) []util.DataFil
 		return nil
 	}
 	var providerTmp = `
+export interface Violation {
+  field: string;
+  rule: string;
+  params?: Record<string, string>;
+  message: string;
+}
+
+export interface ErrorDetails {
+  code: number;
+  message: string;
+  type: string;
+  violations?: Violation[];
+}
+
 export interface RequestProps {
   ignoreAuth?: boolean;
   method: 'DELETE' | 'GET' | 'POST' | 'PUT' | 'PATCH' | 'OPTIONS' | 'HEAD';
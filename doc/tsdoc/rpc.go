@@ -15,6 +15,20 @@ func NewRpcApiFiles(groups []rpcdoc.Group, parentDir ...string) []util.DataFile
 		return nil
 	}
 	var providerTmp = `
+export interface Violation {
+  field: string;
+  rule: string;
+  params?: Record<string, string>;
+  message: string;
+}
+
+export interface ErrorDetails {
+  code: number;
+  message: string;
+  type: string;
+  violations?: Violation[];
+}
+
 export type RpcCaller = (path: string, params: any) => Promise<any>;
 
 let provider: RpcCaller | undefined;
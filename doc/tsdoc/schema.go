@@ -60,11 +60,17 @@ declare namespace {{.Namespace}} {
 					separator = "?:"
 				}
 				tp := parseType(f.Type, pkg)
+				if c := f.Constraints; c != nil && len(c.Enum) > 0 && f.Type.Enum == nil {
+					tp = literalUnion(c.Enum, f.Type.Nullable)
+				}
 				name := f.Name
 				if strings.Contains(name, "-") || strings.Contains(name, "_") {
 					name = fmt.Sprintf("'%s'", name)
 				}
 				str := fmt.Sprintf("        %s%s %s", name, separator, tp)
+				if doc := constraintsDoc(f.Constraints); doc != "" {
+					str = doc + str
+				}
 				ps = append(ps, str)
 			}
 			item.Properties = strings.Join(ps, ";\n")
@@ -130,3 +136,69 @@ type enumItem struct {
 	Values      string
 	Description string
 }
+
+// constraintsDoc renders the validation rules as a JSDoc comment with one tag per line, using the JSON
+// Schema keyword names.
+func constraintsDoc(c *schema.Constraints) string {
+	if c.IsEmpty() {
+		return ""
+	}
+	var tags []string
+	addNumber := func(name string, v any) {
+		switch n := v.(type) {
+		case *float64:
+			if n != nil {
+				tags = append(tags, fmt.Sprintf("@%s %v", name, *n))
+			}
+		case *int:
+			if n != nil {
+				tags = append(tags, fmt.Sprintf("@%s %d", name, *n))
+			}
+		}
+	}
+	addNumber("minimum", c.Minimum)
+	addNumber("maximum", c.Maximum)
+	addNumber("minLength", c.MinLength)
+	addNumber("maxLength", c.MaxLength)
+	addNumber("minItems", c.MinItems)
+	addNumber("maxItems", c.MaxItems)
+	addNumber("minProperties", c.MinProperties)
+	addNumber("maxProperties", c.MaxProperties)
+	if c.Pattern != "" {
+		tags = append(tags, "@pattern "+strings.ReplaceAll(c.Pattern, "*/", "*\\/"))
+	}
+	if c.UniqueItems {
+		tags = append(tags, "@uniqueItems")
+	}
+	if c.RequiredIf != nil {
+		tags = append(tags, fmt.Sprintf("@requiredIf %s=%s", c.RequiredIf.Field, strings.Join(c.RequiredIf.Values, "|")))
+	}
+	if c.RequiredWithout != "" {
+		tags = append(tags, "@requiredWithout "+c.RequiredWithout)
+	}
+	if c.EqField != "" {
+		tags = append(tags, "@eqField "+c.EqField)
+	}
+	if c.GtField != "" {
+		tags = append(tags, "@gtField "+c.GtField)
+	}
+	if len(tags) == 0 {
+		return ""
+	}
+	return "        /**\n         * " + strings.Join(tags, "\n         * ") + "\n         */\n"
+}
+
+func literalUnion(values []any, nullable bool) string {
+	arr := make([]string, 0, len(values)+1)
+	for _, v := range values {
+		if str, ok := v.(string); ok {
+			arr = append(arr, fmt.Sprintf("'%s'", strings.ReplaceAll(str, "'", "\\'")))
+		} else {
+			arr = append(arr, fmt.Sprint(v))
+		}
+	}
+	if nullable {
+		arr = append(arr, "null")
+	}
+	return strings.Join(arr, " | ")
+}
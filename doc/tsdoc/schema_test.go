@@ -0,0 +1,54 @@
+package tsdoc
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tencent-go/pkg/doc/schema"
+	"github.com/tencent-go/pkg/util"
+	"github.com/stretchr/testify/assert"
+)
+
+type constrainedOrder struct {
+	ID     string   `json:"id" validate:"min=1,max=32,pattern=^[a-z]+$"`
+	Paid   bool     `json:"paid" validate:"oneof=true"`
+	Status string   `json:"status,omitempty" validate:"oneof=new|paid"`
+	Tags   []string `json:"tags" validate:"max=3,unique"`
+	Note   string   `json:"note"`
+}
+
+func TestSchemaFiles(t *testing.T) {
+	c := schema.NewCollection()
+	_, ok := c.ParseAndGetType(reflect.TypeOf(constrainedOrder{}), util.TagJson)
+	if !assert.True(t, ok) {
+		return
+	}
+	files := NewSchemaFiles(c.Packages(), "types")
+	if !assert.Len(t, files, 1) {
+		return
+	}
+	assert.Equal(t, "types", files[0].Dir)
+	assert.Equal(t, "Tsdoc.d.ts", files[0].Name)
+	assert.Equal(t, `
+declare namespace Tsdoc {
+
+    interface constrainedOrder {
+        /**
+         * @minLength 1
+         * @maxLength 32
+         * @pattern ^[a-z]+$
+         */
+        id: string;
+        paid: true;
+        status?: 'new' | 'paid';
+        /**
+         * @maxItems 3
+         * @uniqueItems
+         */
+        tags: string[] | null;
+        note: string;
+    }
+
+}
+`, string(files[0].Data))
+}
@@ -3,6 +3,7 @@ package tsdoc
 import (
 	"bytes"
 	"path"
+	"strings"
 	"text/template"
 
 	"github.com/tencent-go/pkg/doc/wsdoc"
@@ -13,7 +14,94 @@ import (
 func NewEventFile(subscribers []wsdoc.EventChannel, publishers []wsdoc.EventChannel, parentDir ...string) []util.DataFile {
 	temp := `
 import { remoteEventBus } from '@tencent-app/ts-event-bus';
+{{if .Requests}}
+export interface WsErrorDetail {
+  code: number;
+  message: string;
+  type: string;
+  violations?: { field: string; rule: string; message: string }[];
+}
+
+// frames of the request channels, the reply has the id of its request
+export interface WsFrame {
+  topic: string;
+  id?: string;
+  data?: any;
+  error?: WsErrorDetail;
+}
+
+export class WsRequestError extends Error {
+  constructor(readonly topic: string, readonly detail?: WsErrorDetail) {
+    super(detail?.message || detail?.type || ` + "`Websocket request ${topic} failed.`" + `);
+  }
+}
+
+interface PendingRequest {
+  topic: string;
+  resolve: (data: any) => void;
+  reject: (err: Error) => void;
+  timer: ReturnType<typeof setTimeout>;
+}
+
+let wsSend: ((frame: WsFrame) => void) | undefined;
+let wsTimeout = 10000;
+let wsNextId = 0;
+const wsPending = new Map<string, PendingRequest>();
+
+// setWsSender binds the requests to a connection, send encodes and writes a frame in the format of the
+// connection and the frames received are passed to handleWsFrame. Requests time out after timeoutMs.
+export function setWsSender(send: (frame: WsFrame) => void, timeoutMs = 10000): void {
+  wsSend = send;
+  wsTimeout = timeoutMs;
+}
+
+// handleWsFrame settles the request replied by frame, it returns false for the frames which are not replies.
+export function handleWsFrame(frame: WsFrame): boolean {
+  const p = frame.id ? wsPending.get(frame.id) : undefined;
+  if (!p) {
+    return false;
+  }
+  wsPending.delete(frame.id!);
+  clearTimeout(p.timer);
+  if (frame.error) {
+    p.reject(new WsRequestError(p.topic, frame.error));
+  } else {
+    p.resolve(frame.data);
+  }
+  return true;
+}
+
+// rejectWsRequests fails the pending requests, e.g. when the connection is closed.
+export function rejectWsRequests(reason = 'Websocket connection closed.'): void {
+  wsPending.forEach((p) => {
+    clearTimeout(p.timer);
+    p.reject(new WsRequestError(p.topic, { code: 0, message: reason, type: 'network' }));
+  });
+  wsPending.clear();
+}
 
+function requestWs(topic: string, data?: any): Promise<any> {
+  const send = wsSend;
+  if (!send) {
+    return Promise.reject(new Error('Websocket sender is not initialized.'));
+  }
+  const id = String(++wsNextId);
+  return new Promise((resolve, reject) => {
+    const timer = setTimeout(() => {
+      wsPending.delete(id);
+      reject(new WsRequestError(topic, { code: 0, message: ` + "`Websocket request ${topic} timed out.`" + `, type: 'timeout' }));
+    }, wsTimeout);
+    wsPending.set(id, { topic, resolve, reject, timer });
+    try {
+      send({ topic, id, data });
+    } catch (e) {
+      wsPending.delete(id);
+      clearTimeout(timer);
+      reject(e instanceof Error ? e : new Error(String(e)));
+    }
+  });
+}
+{{end}}
 /**
  * subscriber channels
  */
@@ -21,7 +109,11 @@ import { remoteEventBus } from '@tencent-app/ts-event-bus';
 {{- if .Description}}
 // {{.Description}}
 {{end}}
+{{- if .Params}}
+export const {{.FuncName}} = ({{.Params}}) => remoteEventBus.subscriberChannel<{{.Type}}>(` + "`{{.TopicExpr}}`" + `);
+{{- else}}
 export const {{.FuncName}} = remoteEventBus.subscriberChannel<{{.Type}}>('{{.Topic}}');
+{{- end}}
 {{end}}
 /**
  * publisher channels
@@ -32,20 +124,36 @@ export const {{.FuncName}} = remoteEventBus.subscriberChannel<{{.Type}}>('{{.Top
 {{end}}
 export const {{.FuncName}} = remoteEventBus.publisherChannel<{{.Type}}>('{{.Topic}}');
 {{end}}
+{{- if .Requests}}
+/**
+ * request channels
+ */
+{{range .Requests}}
+{{- if .Description}}
+// {{.Description}}
+{{end}}
+export function {{.FuncName}}(data: {{.Type}}): Promise<{{.ResponseType}}> {
+  return requestWs('{{.Topic}}', data);
+}
+{{end}}
+{{- end}}
 `
 	t, err := template.New("typescript_events").Parse(temp)
 	if err != nil {
 		logrus.Fatalf("parse interface template failed: %v", err)
 	}
 	data := eventData{
-		Emitters:  make([]eventFunc, len(publishers)),
 		Listeners: make([]eventFunc, len(subscribers)),
 	}
 	for i, d := range subscribers {
 		data.Listeners[i] = newEventFunc(d)
 	}
-	for i, d := range publishers {
-		data.Emitters[i] = newEventFunc(d)
+	for _, d := range publishers {
+		if d.Request {
+			data.Requests = append(data.Requests, newEventFunc(d))
+		} else {
+			data.Emitters = append(data.Emitters, newEventFunc(d))
+		}
 	}
 	var buf bytes.Buffer
 	if err = t.Execute(&buf, data); err != nil {
@@ -63,6 +171,7 @@ export const {{.FuncName}} = remoteEventBus.publisherChannel<{{.Type}}>('{{.Topi
 type eventData struct {
 	Emitters  []eventFunc
 	Listeners []eventFunc
+	Requests  []eventFunc // publishers replying to every message
 }
 
 func newEventFunc(doc wsdoc.EventChannel) eventFunc {
@@ -74,13 +183,35 @@ func newEventFunc(doc wsdoc.EventChannel) eventFunc {
 	if doc.Type != nil {
 		res.Type = parseType(*doc.Type, nil)
 	}
-	res.FuncName = convertName(doc.Topic)
+	if doc.Request {
+		res.ResponseType = "void"
+		if doc.ResponseType != nil {
+			if t := parseType(*doc.ResponseType, nil); t != "" && t != "null" {
+				res.ResponseType = t
+			}
+		}
+	}
+	res.FuncName = convertName(topicNameReplacer.Replace(doc.Topic))
+	if len(doc.Placeholders) > 0 {
+		params := make([]string, len(doc.Placeholders))
+		for i, p := range doc.Placeholders {
+			params[i] = p + ": string"
+		}
+		res.Params = strings.Join(params, ", ")
+		res.TopicExpr = util.PlaceholderRegex.ReplaceAllString(doc.Topic, "$${$1}")
+	}
 	return res
 }
 
+// topicNameReplacer turns the separators of topics into word breaks of convertName.
+var topicNameReplacer = strings.NewReplacer(".", "_", "{", "_", "}", "_")
+
 type eventFunc struct {
-	FuncName    string
-	Type        string
-	Topic       string
-	Description string
+	FuncName     string
+	Params       string // arguments of a topic template
+	TopicExpr    string // template literal of a topic template
+	Type         string
+	ResponseType string
+	Topic        string
+	Description  string
 }
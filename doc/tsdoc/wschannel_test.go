@@ -0,0 +1,39 @@
+package tsdoc
+
+import (
+	"testing"
+
+	"github.com/tencent-go/pkg/doc/wsdoc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventFile(t *testing.T) {
+	t.Run("請求頻道產生關聯請求者", func(t *testing.T) {
+		files := NewEventFile(nil, []wsdoc.EventChannel{{Topic: "order.create", Request: true}, {Topic: "chat"}})
+		if !assert.Len(t, files, 1) {
+			return
+		}
+		src := string(files[0].Data)
+		assert.Contains(t, src, "export function orderCreate(data: undefined): Promise<void> {\n  return requestWs('order.create', data);\n}")
+		assert.Contains(t, src, "export const chat = remoteEventBus.publisherChannel<undefined>('chat');")
+		for _, s := range []string{
+			"export function setWsSender(",
+			"export function handleWsFrame(frame: WsFrame): boolean {",
+			"export function rejectWsRequests(",
+			"const id = String(++wsNextId);",
+			"wsPending.set(id, { topic, resolve, reject, timer });",
+			"return Promise.reject(new Error('Websocket sender is not initialized.'));",
+		} {
+			assert.Contains(t, src, s)
+		}
+		assert.NotContains(t, src, "throw ")
+		assert.NotContains(t, src, "setWsRequester")
+	})
+
+	t.Run("無請求頻道", func(t *testing.T) {
+		files := NewEventFile([]wsdoc.EventChannel{{Topic: "order.{orderId}", Placeholders: []string{"orderId"}}}, nil)
+		src := string(files[0].Data)
+		assert.Contains(t, src, "export const orderOrderId = (orderId: string) => remoteEventBus.subscriberChannel<undefined>(`order.${orderId}`);")
+		assert.NotContains(t, src, "requestWs")
+	})
+}
@@ -0,0 +1,182 @@
+package doc
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+
+	"github.com/tencent-go/pkg/errx"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// UI is the viewer of an OpenAPI document, it can be switched with the query parameter ui, e.g. index.html?ui=redoc.
+type UI string
+
+const (
+	UISwagger UI = "swagger"
+	UIRedoc   UI = "redoc"
+	UIScalar  UI = "scalar"
+)
+
+var uis = []UI{UISwagger, UIRedoc, UIScalar}
+
+// specLoader returns the document as JSON, it is called for every download.
+type specLoader func() ([]byte, errx.Error)
+
+func staticSpec(data []byte) specLoader {
+	return func() ([]byte, errx.Error) {
+		return data, nil
+	}
+}
+
+type pageData struct {
+	Title string
+	UI    UI
+	UIs   []UI
+}
+
+// handleSpec serves the viewer at prefix/index.html and the document at prefix/doc.json and prefix/doc.yaml.
+// The AsyncAPI documents have a single viewer.
+func handleSpec(mux *http.ServeMux, prefix, title string, defaultUI UI, async bool, load specLoader) {
+	page := openApiPage
+	if async {
+		page = asyncApiPage
+	}
+	mux.HandleFunc(prefix, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", prefix[1:]+"/index.html")
+		w.WriteHeader(http.StatusFound)
+	})
+	mux.HandleFunc(prefix+"/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "index.html")
+		w.WriteHeader(http.StatusFound)
+	})
+	mux.HandleFunc(prefix+"/index.html", func(w http.ResponseWriter, r *http.Request) {
+		data := pageData{Title: title, UI: defaultUI, UIs: uis}
+		if ui := UI(r.URL.Query().Get("ui")); ui != "" {
+			data.UI = ui
+		}
+		if data.UI == "" {
+			data.UI = UISwagger
+		}
+		buf := &bytes.Buffer{}
+		if e := page.Execute(buf, data); e != nil {
+			logrus.WithError(e).Error("execute doc page template failed")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(buf.Bytes())
+	})
+	mux.HandleFunc(prefix+"/doc.json", func(w http.ResponseWriter, r *http.Request) {
+		data, err := load()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(data)
+	})
+	mux.HandleFunc(prefix+"/doc.yaml", func(w http.ResponseWriter, r *http.Request) {
+		data, err := load()
+		if err == nil {
+			data, err = jsonToYaml(data)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/yaml")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(data)
+	})
+}
+
+// jsonToYaml converts a JSON document keeping the order of its keys, JSON being a subset of YAML
+// only the flow styles have to be reset.
+func jsonToYaml(data []byte) ([]byte, errx.Error) {
+	var node yaml.Node
+	if e := yaml.Unmarshal(data, &node); e != nil {
+		return nil, errx.Wrap(e).AppendMsg("parse json document failed").Err()
+	}
+	var reset func(n *yaml.Node)
+	reset = func(n *yaml.Node) {
+		n.Style = 0
+		for _, c := range n.Content {
+			reset(c)
+		}
+	}
+	reset(&node)
+	res, e := yaml.Marshal(&node)
+	if e != nil {
+		return nil, errx.Wrap(e).AppendMsg("marshal yaml document failed").Err()
+	}
+	return res, nil
+}
+
+var openApiPage = template.Must(template.New("openapi").Parse(`<!DOCTYPE html>
+<html>
+  <head>
+    <title>{{.Title}}</title>
+    <meta charset="utf-8">
+    <link rel="icon" href="https://cdn.jsdelivr.net/gh/twitter/twemoji/2/72x72/1f600.png" type="image/png">
+    {{- if eq .UI "swagger"}}
+    <link rel="stylesheet" type="text/css" href="https://cdnjs.cloudflare.com/ajax/libs/swagger-ui/4.18.1/swagger-ui.css">
+    {{- end}}
+    <style>
+      .doc-bar { display: flex; gap: 12px; align-items: center; padding: 6px 16px; font: 13px sans-serif; border-bottom: 1px solid #ddd; }
+      .doc-bar span { flex: 1; font-weight: bold; }
+    </style>
+  </head>
+  <body>
+    <div class="doc-bar">
+      <span>{{.Title}}</span>
+      <select onchange="location.search = '?ui=' + this.value">
+        {{- range .UIs}}
+        <option value="{{.}}"{{if eq . $.UI}} selected{{end}}>{{.}}</option>
+        {{- end}}
+      </select>
+      <a href="doc.json" download>JSON</a>
+      <a href="doc.yaml" download>YAML</a>
+    </div>
+    {{- if eq .UI "redoc"}}
+    <redoc spec-url="doc.json"></redoc>
+    <script src="https://cdn.redoc.ly/redoc/latest/bundles/redoc.standalone.js"></script>
+    {{- else if eq .UI "scalar"}}
+    <script id="api-reference" data-url="doc.json"></script>
+    <script src="https://cdn.jsdelivr.net/npm/@scalar/api-reference"></script>
+    {{- else}}
+    <div id="swagger-ui"></div>
+    <script src="https://cdnjs.cloudflare.com/ajax/libs/swagger-ui/4.18.1/swagger-ui-bundle.js"></script>
+    <script>
+      const ui = SwaggerUIBundle({
+        url: "doc.json",
+        dom_id: '#swagger-ui',
+        persistAuthorization: true,
+      });
+    </script>
+    {{- end}}
+  </body>
+</html>`))
+
+var asyncApiPage = template.Must(template.New("asyncapi").Parse(`<!DOCTYPE html>
+<html>
+  <head>
+    <title>{{.Title}}</title>
+    <meta charset="utf-8">
+    <link rel="icon" href="https://cdn.jsdelivr.net/gh/twitter/twemoji/2/72x72/1f600.png" type="image/png">
+    <link rel="stylesheet" type="text/css" href="https://unpkg.com/@asyncapi/react-component@1/styles/default.min.css">
+  </head>
+  <body>
+    <div id="asyncapi"></div>
+    <script src="https://unpkg.com/@asyncapi/react-component@1/browser/standalone/index.js"></script>
+    <script>
+      AsyncApiStandalone.render({
+        schema: { url: "doc.json", options: { method: "GET", mode: "cors" } },
+        config: { show: { sidebar: true } },
+      }, document.getElementById("asyncapi"));
+    </script>
+  </body>
+</html>`))
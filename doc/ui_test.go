@@ -0,0 +1,26 @@
+package doc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonToYaml(t *testing.T) {
+	t.Run("保留鍵的順序", func(t *testing.T) {
+		res, err := jsonToYaml([]byte(`{"openapi":"3.0.0","info":{"title":"API","version":"1.0.0"},"tags":[{"name":"order"}],"paths":{}}`))
+		assert.Nil(t, err)
+		assert.Equal(t, "openapi: 3.0.0\ninfo:\n    title: API\n    version: 1.0.0\ntags:\n    - name: order\npaths: {}\n", string(res))
+	})
+
+	t.Run("字串保持字串", func(t *testing.T) {
+		res, err := jsonToYaml([]byte(`{"version":"1.0","enum":["true","null"],"n":1}`))
+		assert.Nil(t, err)
+		assert.Equal(t, "version: \"1.0\"\nenum:\n    - \"true\"\n    - \"null\"\nn: 1\n", string(res))
+	})
+
+	t.Run("無效的文件", func(t *testing.T) {
+		_, err := jsonToYaml([]byte(`{"a":`))
+		assert.NotNil(t, err)
+	})
+}
@@ -7,9 +7,12 @@ import (
 )
 
 type EventChannel struct {
-	Topic       string
-	Type        *schema.Type
-	Description string
+	Topic        string
+	Placeholders []string // names of the placeholders of a topic template, e.g. orderId of order.{orderId}
+	Type         *schema.Type
+	Description  string
+	Request      bool         // wsx.RequestChannel, the server replies to every message
+	ResponseType *schema.Type // type of the reply of a request channel
 }
 
 func NewGroups(schemaCollection schema.Collection, channels []wsx.EventChannel) []EventChannel {
@@ -17,8 +20,16 @@ func NewGroups(schemaCollection schema.Collection, channels []wsx.EventChannel)
 	for i, channel := range channels {
 		typ, _ := schemaCollection.ParseAndGetType(channel.MessageType(), util.TagJson)
 		res[i] = EventChannel{
-			Topic: channel.Topic(),
-			Type:  typ,
+			Topic:       channel.Topic(),
+			Type:        typ,
+			Description: channel.Description(),
+		}
+		for _, match := range util.PlaceholderRegex.FindAllStringSubmatch(channel.Topic(), -1) {
+			res[i].Placeholders = append(res[i].Placeholders, match[1])
+		}
+		if rc, ok := channel.(wsx.RequestChannel); ok {
+			res[i].Request = true
+			res[i].ResponseType, _ = schemaCollection.ParseAndGetType(rc.ResponseType(), util.TagJson)
 		}
 	}
 	return res
@@ -25,6 +25,9 @@ type Builder interface {
 	AppendMsgf(format string, a ...any) Builder
 	WithCode(int) Builder
 	WithType(Type) Builder
+	// WithLocaleMsg registers template as the message of locale, keyed by the code of the builder
+	// or by its current message when it has no code, see Localize.
+	WithLocaleMsg(locale Locale, template string) Builder
 	Err() Error
 }
 
@@ -13,6 +13,7 @@ import (
 type impl struct {
 	cause error
 	msg   string
+	parts []msgPart
 	code  int
 	stack Stack
 	typ   Type
@@ -61,6 +62,7 @@ func (i *impl) copy() *impl {
 	return &impl{
 		cause: i,
 		msg:   i.msg,
+		parts: i.parts,
 		code:  i.code,
 		stack: i.stack,
 		typ:   i.typ,
@@ -70,12 +72,14 @@ func (i *impl) copy() *impl {
 func (i *impl) WithMsg(s string) Builder {
 	c := i.copy()
 	c.msg = s
+	c.parts = []msgPart{{format: s}}
 	return c
 }
 
 func (i *impl) WithMsgf(format string, a ...any) Builder {
 	c := i.copy()
 	c.msg = fmt.Sprintf(format, a...)
+	c.parts = []msgPart{{format: format, args: a, formatted: true}}
 	return c
 }
 
@@ -86,6 +90,7 @@ func (i *impl) AppendMsg(appendMsg string) Builder {
 	} else {
 		c.msg = fmt.Sprintf("%s: %s", appendMsg, c.msg)
 	}
+	c.parts = c.prependPart(msgPart{format: appendMsg})
 	return c
 }
 
@@ -97,6 +102,7 @@ func (i *impl) AppendMsgf(format string, a ...any) Builder {
 	} else {
 		c.msg = fmt.Sprintf("%s: %s", appendMsg, c.msg)
 	}
+	c.parts = c.prependPart(msgPart{format: format, args: a, formatted: true})
 	return c
 }
 
@@ -0,0 +1,158 @@
+package errx
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Locale is a BCP 47 language tag such as "zh-TW", implemented by types.Locale.
+type Locale interface {
+	String() string
+}
+
+// LocalizedArg is a message argument that renders itself in the requested locale, e.g. a field label.
+type LocalizedArg interface {
+	Localize(locale Locale) string
+}
+
+type msgPart struct {
+	format    string
+	args      []any
+	formatted bool
+}
+
+func (i *impl) prependPart(p msgPart) []msgPart {
+	parts := make([]msgPart, 0, len(i.parts)+1)
+	parts = append(parts, p)
+	return append(parts, i.parts...)
+}
+
+var catalogue = struct {
+	sync.RWMutex
+	codes    map[int]map[string]string
+	messages map[string]map[string]string
+}{
+	codes:    make(map[int]map[string]string),
+	messages: make(map[string]map[string]string),
+}
+
+func (i *impl) WithLocaleMsg(l Locale, template string) Builder {
+	if i.code != 0 {
+		RegisterCodeMsg(i.code, l, template)
+	} else if len(i.parts) > 0 {
+		RegisterLocaleMsg(i.parts[len(i.parts)-1].format, l, template)
+	}
+	return i
+}
+
+// RegisterCodeMsg registers template as the message of code in locale, the arguments of WithMsgf are applied to it.
+func RegisterCodeMsg(code int, l Locale, template string) {
+	catalogue.Lock()
+	defer catalogue.Unlock()
+	m, ok := catalogue.codes[code]
+	if !ok {
+		m = make(map[string]string)
+		catalogue.codes[code] = m
+	}
+	m[l.String()] = template
+}
+
+// RegisterLocaleMsg registers the translation of msg in locale, msg is the plain message or the
+// format passed to WithMsgf and AppendMsgf.
+func RegisterLocaleMsg(msg string, l Locale, template string) {
+	catalogue.Lock()
+	defer catalogue.Unlock()
+	m, ok := catalogue.messages[msg]
+	if !ok {
+		m = make(map[string]string)
+		catalogue.messages[msg] = m
+	}
+	m[l.String()] = template
+}
+
+// UnregisterCodeMsg removes the message of code in locale, e.g. in the cleanup of a test.
+func UnregisterCodeMsg(code int, l Locale) {
+	catalogue.Lock()
+	defer catalogue.Unlock()
+	if m, ok := catalogue.codes[code]; ok {
+		delete(m, l.String())
+		if len(m) == 0 {
+			delete(catalogue.codes, code)
+		}
+	}
+}
+
+// UnregisterLocaleMsg removes the translation of msg in locale, e.g. in the cleanup of a test.
+func UnregisterLocaleMsg(msg string, l Locale) {
+	catalogue.Lock()
+	defer catalogue.Unlock()
+	if m, ok := catalogue.messages[msg]; ok {
+		delete(m, l.String())
+		if len(m) == 0 {
+			delete(catalogue.messages, msg)
+		}
+	}
+}
+
+// Localize renders the message of err in locale. The base message is looked up by the error code
+// first and every message by its own text, trying the exact locale and then its language
+// ("zh-TW" then "zh"). Messages without a translation keep their original text.
+func Localize(err error, l Locale) string {
+	if err == nil {
+		return ""
+	}
+	i, ok := err.(*impl)
+	if !ok || l == nil || l.String() == "" {
+		return err.Error()
+	}
+	locale := l.String()
+	parts := i.parts
+	if len(parts) == 0 {
+		parts = []msgPart{{}}
+	}
+	catalogue.RLock()
+	defer catalogue.RUnlock()
+	var msgs []string
+	for n, p := range parts {
+		format := p.format
+		if t, ok := lookupLocale(catalogue.messages[p.format], locale); ok {
+			format = t
+		}
+		if n == len(parts)-1 && i.code != 0 {
+			if t, ok := lookupLocale(catalogue.codes[i.code], locale); ok {
+				format = t
+			}
+		}
+		if !p.formatted {
+			if format != "" {
+				msgs = append(msgs, format)
+			}
+			continue
+		}
+		args := make([]any, len(p.args))
+		for j, a := range p.args {
+			if la, ok := a.(LocalizedArg); ok {
+				args[j] = la.Localize(l)
+			} else {
+				args[j] = a
+			}
+		}
+		msgs = append(msgs, fmt.Sprintf(format, args...))
+	}
+	return strings.Join(msgs, ": ")
+}
+
+func lookupLocale(m map[string]string, locale string) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+	if t, ok := m[locale]; ok {
+		return t, true
+	}
+	if i := strings.IndexAny(locale, "-_"); i > 0 {
+		t, ok := m[locale[:i]]
+		return t, ok
+	}
+	return "", false
+}
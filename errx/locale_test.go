@@ -0,0 +1,83 @@
+package errx
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testLocale string
+
+func (l testLocale) String() string {
+	return string(l)
+}
+
+type testLabel map[string]string
+
+func (l testLabel) Localize(locale Locale) string {
+	return l[locale.String()]
+}
+
+func TestLocalize(t *testing.T) {
+	zhTW, zh, en := testLocale("zh-TW"), testLocale("zh"), testLocale("en-US")
+	register := func(msg string, l Locale, template string) {
+		RegisterLocaleMsg(msg, l, template)
+		t.Cleanup(func() { UnregisterLocaleMsg(msg, l) })
+	}
+
+	t.Run("訊息", func(t *testing.T) {
+		register("order not found", zh, "訂單不存在")
+		err := NotFound.WithMsg("order not found").Err()
+		assert.Equal(t, "訂單不存在", Localize(err, zhTW), "退回語言")
+		assert.Equal(t, "訂單不存在", Localize(err, zh))
+		assert.Equal(t, "order not found", Localize(err, en), "無翻譯時保留原文")
+		assert.Equal(t, "order not found", Localize(err, nil))
+		assert.Equal(t, "order not found", Localize(err, testLocale("")))
+	})
+
+	t.Run("完整地區優先", func(t *testing.T) {
+		register("color", zh, "颜色")
+		register("color", zhTW, "顏色")
+		err := New("color")
+		assert.Equal(t, "顏色", Localize(err, zhTW))
+		assert.Equal(t, "颜色", Localize(err, testLocale("zh_CN")))
+	})
+
+	t.Run("格式及參數", func(t *testing.T) {
+		register("field %s invalid", zh, "欄位%s無效")
+		err := Newf("field %s invalid", testLabel{"zh-TW": "名稱"})
+		assert.Equal(t, "欄位名稱無效", Localize(err, zhTW))
+	})
+
+	t.Run("附加的訊息", func(t *testing.T) {
+		register("save failed", zh, "儲存失敗")
+		err := Wrap(errors.New("disk full")).AppendMsg("save failed").Err()
+		assert.Equal(t, "儲存失敗: disk full", Localize(err, zhTW))
+	})
+
+	t.Run("錯誤碼", func(t *testing.T) {
+		RegisterCodeMsg(40401, zh, "找不到%s")
+		t.Cleanup(func() { UnregisterCodeMsg(40401, zh) })
+		err := Define().WithCode(40401).WithMsgf("%s missing", "user").Err()
+		assert.Equal(t, "找不到user", Localize(err, zhTW))
+		assert.Equal(t, "user missing", Localize(err, en))
+	})
+
+	t.Run("WithLocaleMsg", func(t *testing.T) {
+		b := Define().WithMsg("quota exceeded").WithLocaleMsg(zh, "額度不足")
+		t.Cleanup(func() { UnregisterLocaleMsg("quota exceeded", zh) })
+		assert.Equal(t, "額度不足", Localize(b.Err(), zhTW))
+	})
+
+	t.Run("移除", func(t *testing.T) {
+		RegisterLocaleMsg("temporary", zh, "暫時")
+		UnregisterLocaleMsg("temporary", zh)
+		assert.Equal(t, "temporary", Localize(New("temporary"), zh))
+	})
+
+	t.Run("非errx錯誤", func(t *testing.T) {
+		assert.Equal(t, "plain", Localize(errors.New("plain"), zh))
+		assert.Equal(t, "", Localize(nil, zh))
+	})
+}
@@ -52,6 +52,10 @@ func (e *emptyError) WithType(t Type) Builder {
 	return rootError.WithType(t)
 }
 
+func (e *emptyError) WithLocaleMsg(l Locale, template string) Builder {
+	return rootError.WithLocaleMsg(l, template)
+}
+
 func (e *emptyError) Err() Error {
 	return nil
 }
@@ -69,6 +73,7 @@ func Wrap(err error) Builder {
 		return &impl{
 			cause: e,
 			msg:   e.Error(),
+			parts: []msgPart{{format: e.Error()}},
 			stack: e.Stack(),
 			code:  e.Code(),
 			typ:   e.Type(),
@@ -84,6 +89,7 @@ func Wrap(err error) Builder {
 		cause: err,
 		typ:   t,
 		msg:   err.Error(),
+		parts: []msgPart{{format: err.Error()}},
 		stack: parseStack(fmt.Sprintf("%+v", err)),
 	}
 }
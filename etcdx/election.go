@@ -0,0 +1,345 @@
+package etcdx
+
+import (
+	"context"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/tencent-go/pkg/errx"
+	"github.com/sirupsen/logrus"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+const electionKeyPrefix = "/election"
+
+// Election elects one leader among the processes campaigning for the same name. The candidates of a
+// client share the lease of NewClientLeaseIDTracker, so the elections of the same client and name are one
+// candidate: they share the leadership and the last Close ends it.
+type Election interface {
+	// Campaign blocks until the process is the leader or ctx is done, it returns at once when already leader.
+	Campaign(ctx context.Context) errx.Error
+	Resign(ctx context.Context) errx.Error
+	IsLeader() bool
+	// Observe sends the leader on every change, the channel is closed by Close.
+	Observe() <-chan LeaderInfo
+	// RunAsLeader campaigns, then runs fn until it returns or the leadership is lost, in which case the
+	// context of fn is canceled. The leadership is resigned after fn, callers loop to campaign again. The
+	// calls of a candidate run one at a time, the next one waits until ctx is done.
+	RunAsLeader(ctx context.Context, fn func(ctx context.Context) errx.Error) errx.Error
+	Close()
+}
+
+type LeaderInfo struct {
+	Value    string // 領導者的識別, 預設為POD_NAME
+	Revision int64  // 每屆領導者遞增, 可作為fencing token
+}
+
+type electionOptions struct {
+	value string
+}
+
+type ElectionOption func(*electionOptions)
+
+// WithElectionValue sets the value the leader proclaims, the default is POD_NAME. The elections sharing a
+// candidate keep the value of the first one.
+func WithElectionValue(value string) ElectionOption {
+	return func(o *electionOptions) {
+		o.value = value
+	}
+}
+
+func NewElection(cli *clientv3.Client, name string, opts ...ElectionOption) Election {
+	o := electionOptions{value: config.PodName}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	k := electionKey{cli: cli, name: name}
+	electionsMu.Lock()
+	defer electionsMu.Unlock()
+	e, ok := elections[k]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		e = &election{
+			cli:     cli,
+			name:    name,
+			key:     path.Join(electionKeyPrefix, name),
+			value:   o.value,
+			tracker: NewClientLeaseIDTracker(cli),
+			ctx:     ctx,
+			cancel:  cancel,
+			running: make(chan struct{}, 1),
+			changed: make(chan struct{}),
+		}
+		go e.trackLease(e.tracker.Track())
+		elections[k] = e
+	}
+	e.refs++
+	ctx, cancel := context.WithCancel(e.ctx)
+	return &electionHandle{election: e, ctx: ctx, cancel: cancel}
+}
+
+type electionKey struct {
+	cli  *clientv3.Client
+	name string
+}
+
+var (
+	electionsMu sync.Mutex
+	elections   = make(map[electionKey]*election) // 同一lease的候選人會共用key, 故每個client及name僅一個
+)
+
+// electionHandle is an Election returned by NewElection, its observers stop with its Close.
+type electionHandle struct {
+	*election
+	ctx    context.Context
+	cancel context.CancelFunc
+	once   sync.Once
+}
+
+func (h *electionHandle) Observe() <-chan LeaderInfo {
+	return h.observe(h.ctx)
+}
+
+// Close stops the observers of h, the last Close of a candidate resigns the leadership.
+func (h *electionHandle) Close() {
+	h.once.Do(func() {
+		h.cancel()
+		electionsMu.Lock()
+		h.refs--
+		last := h.refs == 0
+		if last {
+			delete(elections, electionKey{cli: h.cli, name: h.name})
+		}
+		electionsMu.Unlock()
+		if last {
+			h.close()
+		}
+	})
+}
+
+type election struct {
+	cli      *clientv3.Client
+	name     string
+	key      string
+	value    string
+	tracker  LeaseIdTracker
+	ctx      context.Context
+	cancel   context.CancelFunc
+	refs     int           // handles not closed, guarded by electionsMu
+	campaign sync.Mutex    // 同一lease的候選人會共用key, 不可同時競選
+	running  chan struct{} // RunAsLeader一次僅一個
+
+	mu      sync.Mutex
+	session *concurrency.Session
+	changed chan struct{} // closed when the session is replaced
+	leader  *leadership
+}
+
+type leadership struct {
+	election *concurrency.Election
+	lost     chan struct{}
+	once     sync.Once
+}
+
+func (l *leadership) end() {
+	l.once.Do(func() { close(l.lost) })
+}
+
+// trackLease wraps every lease of the tracker in a session, the session of an expired lease is done and
+// ends the leadership gained with it.
+func (e *election) trackLease(track chan clientv3.LeaseID) {
+	for id := range track {
+		s, err := concurrency.NewSession(e.cli, concurrency.WithLease(id))
+		if err != nil {
+			logrus.WithError(err).WithField("election", e.name).Error("create etcd election session failed")
+			continue
+		}
+		e.mu.Lock()
+		previous := e.session
+		e.session = s
+		close(e.changed)
+		e.changed = make(chan struct{})
+		e.mu.Unlock()
+		if previous != nil {
+			// 不可Close, lease由tracker管理
+			previous.Orphan()
+		}
+		if e.ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+func (e *election) currentSession(ctx context.Context) (*concurrency.Session, errx.Error) {
+	for {
+		e.mu.Lock()
+		s, changed := e.session, e.changed
+		e.mu.Unlock()
+		if s != nil {
+			select {
+			case <-s.Done():
+			default:
+				return s, nil
+			}
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, errx.Wrap(ctx.Err()).WithType(errx.TypeTimeout).AppendMsgf("wait etcd lease of election %s failed", e.name).Err()
+		case <-e.ctx.Done():
+			return nil, errx.Newf("election %s closed", e.name)
+		}
+	}
+}
+
+func (e *election) Campaign(ctx context.Context) errx.Error {
+	e.campaign.Lock()
+	defer e.campaign.Unlock()
+	if e.IsLeader() {
+		return nil
+	}
+	s, err := e.currentSession(ctx)
+	if err != nil {
+		return err
+	}
+	el := concurrency.NewElection(s, e.key)
+	if er := el.Campaign(ctx, e.value); er != nil {
+		if ctx.Err() != nil {
+			return errx.Wrap(er).WithType(errx.TypeTimeout).AppendMsgf("campaign election %s canceled", e.name).Err()
+		}
+		return errx.Wrap(er).WithType(errx.TypeNetwork).AppendMsgf("campaign election %s failed", e.name).Err()
+	}
+	l := &leadership{election: el, lost: make(chan struct{})}
+	e.mu.Lock()
+	e.leader = l
+	e.mu.Unlock()
+	go func() {
+		select {
+		case <-s.Done():
+			logrus.WithField("election", e.name).Warn("etcd election leadership lost")
+			l.end()
+		case <-l.lost:
+		}
+	}()
+	return nil
+}
+
+func (e *election) Resign(ctx context.Context) errx.Error {
+	e.mu.Lock()
+	l := e.leader
+	e.leader = nil
+	e.mu.Unlock()
+	if l == nil {
+		return nil
+	}
+	l.end()
+	if err := l.election.Resign(ctx); err != nil {
+		return errx.Wrap(err).WithType(errx.TypeNetwork).AppendMsgf("resign election %s failed", e.name).Err()
+	}
+	return nil
+}
+
+func (e *election) IsLeader() bool {
+	e.mu.Lock()
+	l := e.leader
+	e.mu.Unlock()
+	if l == nil {
+		return false
+	}
+	select {
+	case <-l.lost:
+		return false
+	default:
+		return true
+	}
+}
+
+// observe sends the leader until ctx is done.
+func (e *election) observe(ctx context.Context) <-chan LeaderInfo {
+	ch := make(chan LeaderInfo)
+	go func() {
+		defer close(ch)
+		for {
+			s, err := e.currentSession(ctx)
+			if err != nil {
+				return
+			}
+			for res := range concurrency.NewElection(s, e.key).Observe(ctx) {
+				kv := res.Kvs[0]
+				select {
+				case ch <- LeaderInfo{Value: string(kv.Value), Revision: kv.CreateRevision}:
+				case <-ctx.Done():
+					return
+				}
+			}
+			// observe ended by a watch error, start again
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+		}
+	}()
+	return ch
+}
+
+func (e *election) RunAsLeader(ctx context.Context, fn func(ctx context.Context) errx.Error) errx.Error {
+	select {
+	case e.running <- struct{}{}:
+	case <-ctx.Done():
+		return errx.Wrap(ctx.Err()).WithType(errx.TypeTimeout).AppendMsgf("wait leader run of election %s canceled", e.name).Err()
+	}
+	defer func() { <-e.running }()
+	if err := e.Campaign(ctx); err != nil {
+		return err
+	}
+	e.mu.Lock()
+	l := e.leader
+	e.mu.Unlock()
+	if l == nil {
+		return errx.Define().WithType(errx.TypeConcurrency).WithMsgf("leadership of election %s lost", e.name).Err()
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-l.lost:
+			cancel()
+		case <-runCtx.Done():
+		}
+	}()
+	err := fn(runCtx)
+	select {
+	case <-l.lost:
+		if err != nil {
+			return errx.Wrap(err).WithType(errx.TypeConcurrency).AppendMsgf("leadership of election %s lost", e.name).Err()
+		}
+		return errx.Define().WithType(errx.TypeConcurrency).WithMsgf("leadership of election %s lost", e.name).Err()
+	default:
+	}
+	resignCtx, resignCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer resignCancel()
+	if er := e.Resign(resignCtx); er != nil {
+		logrus.WithError(er).WithField("election", e.name).Error("resign election failed")
+	}
+	return err
+}
+
+// close resigns the leadership and stops the observers.
+func (e *election) close() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := e.Resign(ctx); err != nil {
+		logrus.WithError(err).WithField("election", e.name).Error("resign election failed")
+	}
+	e.cancel()
+	e.tracker.Close()
+	e.mu.Lock()
+	s := e.session
+	e.mu.Unlock()
+	if s != nil {
+		s.Orphan()
+	}
+}
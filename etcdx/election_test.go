@@ -0,0 +1,160 @@
+package etcdx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tencent-go/pkg/errx"
+	"github.com/tencent-go/pkg/internal/etcdtest"
+	"github.com/stretchr/testify/assert"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func TestElection(t *testing.T) {
+	srv := etcdtest.NewServer()
+	t.Cleanup(srv.Close)
+	newElection := func(value string) Election {
+		cli, err := srv.Client()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = cli.Close() })
+		e := NewElection(cli, "test", WithElectionValue(value))
+		t.Cleanup(e.Close)
+		return e
+	}
+	a, b := newElection("a"), newElection("b")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("campaign", func(t *testing.T) {
+		assert.NoError(t, a.Campaign(ctx))
+		assert.True(t, a.IsLeader())
+		short, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+		defer cancel()
+		err := b.Campaign(short)
+		if assert.Error(t, err) {
+			assert.Equal(t, errx.TypeTimeout, err.Type())
+		}
+		assert.False(t, b.IsLeader())
+	})
+
+	t.Run("observe", func(t *testing.T) {
+		observed := b.Observe()
+		assert.Equal(t, "a", (<-observed).Value)
+		assert.NoError(t, a.Resign(ctx))
+		assert.False(t, a.IsLeader())
+		assert.NoError(t, b.Campaign(ctx))
+		info := <-observed
+		assert.Equal(t, "b", info.Value)
+		assert.NoError(t, b.Resign(ctx))
+	})
+
+	t.Run("run as leader", func(t *testing.T) {
+		assert.NoError(t, a.RunAsLeader(ctx, func(ctx context.Context) errx.Error {
+			assert.True(t, a.IsLeader())
+			return nil
+		}))
+		assert.False(t, a.IsLeader())
+
+		started := make(chan struct{})
+		done := make(chan errx.Error)
+		go func() {
+			done <- a.RunAsLeader(ctx, func(ctx context.Context) errx.Error {
+				close(started)
+				<-ctx.Done()
+				return nil
+			})
+		}()
+		<-started
+		srv.ExpireLeases()
+		select {
+		case err := <-done:
+			if assert.Error(t, err) {
+				assert.Equal(t, errx.TypeConcurrency, err.Type())
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("leadership loss not detected")
+		}
+		assert.False(t, a.IsLeader())
+		assert.NoError(t, a.Campaign(ctx), "campaign again with the next lease")
+	})
+
+	t.Run("run as leader one at a time", func(t *testing.T) {
+		release := make(chan struct{})
+		started := make(chan string, 2)
+		done := make(chan errx.Error, 2)
+		run := func(name string) {
+			done <- a.RunAsLeader(ctx, func(ctx context.Context) errx.Error {
+				started <- name
+				<-release
+				return nil
+			})
+		}
+		go run("first")
+		assert.Equal(t, "first", <-started)
+		go run("second")
+		select {
+		case name := <-started:
+			t.Fatalf("%s runs while the first holds the leadership", name)
+		case <-time.After(200 * time.Millisecond):
+		}
+		short, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		err := a.RunAsLeader(short, func(ctx context.Context) errx.Error {
+			t.Error("ran while another run holds the leadership")
+			return nil
+		})
+		if assert.Error(t, err) {
+			assert.Equal(t, errx.TypeTimeout, err.Type())
+		}
+		release <- struct{}{}
+		assert.NoError(t, <-done)
+		assert.Equal(t, "second", <-started)
+		release <- struct{}{}
+		assert.NoError(t, <-done)
+		assert.False(t, a.IsLeader())
+	})
+
+	t.Run("same client and name", func(t *testing.T) {
+		client := func() *clientv3.Client {
+			cli, err := srv.Client()
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { _ = cli.Close() })
+			return cli
+		}
+		cli := client()
+		first := NewElection(cli, "shared", WithElectionValue("first"))
+		second := NewElection(cli, "shared", WithElectionValue("second"))
+		other := NewElection(client(), "shared", WithElectionValue("other"))
+		t.Cleanup(other.Close)
+
+		assert.NoError(t, first.Campaign(ctx))
+		assert.True(t, second.IsLeader(), "one candidate")
+		assert.NoError(t, second.Campaign(ctx))
+		short, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+		defer cancel()
+		err := other.Campaign(short)
+		if assert.Error(t, err) {
+			assert.Equal(t, errx.TypeTimeout, err.Type())
+		}
+
+		closed := first.Observe()
+		first.Close()
+		assert.True(t, second.IsLeader(), "kept until the last close")
+		assert.Eventually(t, func() bool {
+			select {
+			case _, ok := <-closed:
+				return !ok
+			default:
+				return false
+			}
+		}, 3*time.Second, 10*time.Millisecond, "observers stop with their election")
+		assert.Equal(t, "first", (<-second.Observe()).Value)
+		second.Close()
+		assert.NoError(t, other.Campaign(ctx))
+	})
+}
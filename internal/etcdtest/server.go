@@ -0,0 +1,578 @@
+// Package etcdtest provides an in-memory stand-in of the etcd KV, Lease and Watch services for tests, it
+// covers what the concurrency package of the client uses: transactions on single keys, ranges sorted by
+// revision, watches from a past revision and leases with keepalive.
+package etcdtest
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc"
+)
+
+// Server is started by NewServer and stopped by Close, like httptest.Server.
+type Server struct {
+	pb.UnimplementedKVServer
+	pb.UnimplementedLeaseServer
+	pb.UnimplementedWatchServer
+
+	listener net.Listener
+	grpc     *grpc.Server
+	done     chan struct{}
+
+	mu        sync.Mutex
+	rev       int64
+	kvs       map[string]*mvccpb.KeyValue
+	history   []*mvccpb.Event
+	leases    map[int64]*lease
+	nextLease int64
+	watchers  map[*watcher]struct{}
+	nextWatch int64
+}
+
+type lease struct {
+	ttl     int64
+	expires time.Time
+	keys    map[string]struct{}
+}
+
+type watcher struct {
+	id     int64
+	key    []byte
+	end    []byte
+	prevKv bool
+	noPut  bool
+	noDel  bool
+	out    chan *pb.WatchResponse
+}
+
+// NewServer listens on a random local port, it panics when the port can't be opened.
+func NewServer() *Server {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic("etcdtest: failed to listen: " + err.Error())
+	}
+	s := &Server{
+		listener:  l,
+		grpc:      grpc.NewServer(),
+		done:      make(chan struct{}),
+		rev:       1,
+		kvs:       make(map[string]*mvccpb.KeyValue),
+		leases:    make(map[int64]*lease),
+		nextLease: 1000,
+		watchers:  make(map[*watcher]struct{}),
+	}
+	pb.RegisterKVServer(s.grpc, s)
+	pb.RegisterLeaseServer(s.grpc, s)
+	pb.RegisterWatchServer(s.grpc, s)
+	go func() { _ = s.grpc.Serve(l) }()
+	go s.expireLoop()
+	return s
+}
+
+func (s *Server) Endpoints() []string {
+	return []string{s.listener.Addr().String()}
+}
+
+// Client connects to the server, the caller closes it.
+func (s *Server) Client() (*clientv3.Client, error) {
+	return clientv3.New(clientv3.Config{Endpoints: s.Endpoints(), DialTimeout: 5 * time.Second})
+}
+
+func (s *Server) Close() {
+	close(s.done)
+	s.grpc.Stop()
+}
+
+// ExpireLeases revokes every lease as if their TTL passed, e.g. to simulate a partition of the clients.
+func (s *Server) ExpireLeases() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id := range s.leases {
+		s.revoke(id)
+	}
+}
+
+func (s *Server) expireLoop() {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+		s.mu.Lock()
+		now := time.Now()
+		for id, l := range s.leases {
+			if now.After(l.expires) {
+				s.revoke(id)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *Server) header() *pb.ResponseHeader {
+	return &pb.ResponseHeader{ClusterId: 1, MemberId: 1, Revision: s.rev, RaftTerm: 1}
+}
+
+func inRange(key, start, end []byte) bool {
+	switch {
+	case len(end) == 0:
+		return bytes.Equal(key, start)
+	case len(end) == 1 && end[0] == 0:
+		return bytes.Compare(key, start) >= 0
+	default:
+		return bytes.Compare(key, start) >= 0 && bytes.Compare(key, end) < 0
+	}
+}
+
+func (s *Server) Range(_ context.Context, r *pb.RangeRequest) (*pb.RangeResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rangeKeys(r), nil
+}
+
+func (s *Server) rangeKeys(r *pb.RangeRequest) *pb.RangeResponse {
+	var kvs []*mvccpb.KeyValue
+	for _, kv := range s.kvs {
+		if !inRange(kv.Key, r.Key, r.RangeEnd) ||
+			(r.MinCreateRevision > 0 && kv.CreateRevision < r.MinCreateRevision) ||
+			(r.MaxCreateRevision > 0 && kv.CreateRevision > r.MaxCreateRevision) ||
+			(r.MinModRevision > 0 && kv.ModRevision < r.MinModRevision) ||
+			(r.MaxModRevision > 0 && kv.ModRevision > r.MaxModRevision) {
+			continue
+		}
+		c := *kv
+		if r.KeysOnly {
+			c.Value = nil
+		}
+		kvs = append(kvs, &c)
+	}
+	less := func(a, b *mvccpb.KeyValue) bool {
+		switch r.SortTarget {
+		case pb.RangeRequest_VERSION:
+			return a.Version < b.Version
+		case pb.RangeRequest_CREATE:
+			return a.CreateRevision < b.CreateRevision
+		case pb.RangeRequest_MOD:
+			return a.ModRevision < b.ModRevision
+		case pb.RangeRequest_VALUE:
+			return bytes.Compare(a.Value, b.Value) < 0
+		}
+		return bytes.Compare(a.Key, b.Key) < 0
+	}
+	sort.Slice(kvs, func(i, j int) bool {
+		if r.SortOrder == pb.RangeRequest_DESCEND {
+			return less(kvs[j], kvs[i])
+		}
+		return less(kvs[i], kvs[j])
+	})
+	res := &pb.RangeResponse{Header: s.header(), Count: int64(len(kvs))}
+	if r.Limit > 0 && int64(len(kvs)) > r.Limit {
+		kvs = kvs[:r.Limit]
+		res.More = true
+	}
+	if !r.CountOnly {
+		res.Kvs = kvs
+	}
+	return res
+}
+
+func (s *Server) Put(_ context.Context, r *pb.PutRequest) (*pb.PutResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.Lease != 0 && s.leases[r.Lease] == nil {
+		return nil, rpctypes.ErrGRPCLeaseNotFound
+	}
+	s.rev++
+	res := s.put(r)
+	res.Header = s.header()
+	return res, nil
+}
+
+func (s *Server) put(r *pb.PutRequest) *pb.PutResponse {
+	prev := s.kvs[string(r.Key)]
+	kv := &mvccpb.KeyValue{Key: r.Key, Value: r.Value, CreateRevision: s.rev, ModRevision: s.rev, Version: 1, Lease: r.Lease}
+	if prev != nil {
+		kv.CreateRevision = prev.CreateRevision
+		kv.Version = prev.Version + 1
+		if r.IgnoreValue {
+			kv.Value = prev.Value
+		}
+		if r.IgnoreLease {
+			kv.Lease = prev.Lease
+		}
+		if l := s.leases[prev.Lease]; l != nil && prev.Lease != kv.Lease {
+			delete(l.keys, string(r.Key))
+		}
+	}
+	if l := s.leases[kv.Lease]; l != nil {
+		l.keys[string(r.Key)] = struct{}{}
+	}
+	s.kvs[string(r.Key)] = kv
+	s.notify(&mvccpb.Event{Type: mvccpb.PUT, Kv: kv, PrevKv: prev})
+	res := &pb.PutResponse{}
+	if r.PrevKv {
+		res.PrevKv = prev
+	}
+	return res
+}
+
+func (s *Server) DeleteRange(_ context.Context, r *pb.DeleteRangeRequest) (*pb.DeleteRangeResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.countRange(r.Key, r.RangeEnd) > 0 {
+		s.rev++
+	}
+	res := s.deleteRange(r)
+	res.Header = s.header()
+	return res, nil
+}
+
+func (s *Server) countRange(key, end []byte) int {
+	n := 0
+	for _, kv := range s.kvs {
+		if inRange(kv.Key, key, end) {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *Server) deleteRange(r *pb.DeleteRangeRequest) *pb.DeleteRangeResponse {
+	res := &pb.DeleteRangeResponse{}
+	var keys []string
+	for key, kv := range s.kvs {
+		if inRange(kv.Key, r.Key, r.RangeEnd) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		prev := s.del(key)
+		res.Deleted++
+		if r.PrevKv {
+			res.PrevKvs = append(res.PrevKvs, prev)
+		}
+	}
+	return res
+}
+
+// del removes key at the current revision.
+func (s *Server) del(key string) *mvccpb.KeyValue {
+	prev := s.kvs[key]
+	delete(s.kvs, key)
+	if l := s.leases[prev.Lease]; l != nil {
+		delete(l.keys, key)
+	}
+	s.notify(&mvccpb.Event{Type: mvccpb.DELETE, Kv: &mvccpb.KeyValue{Key: prev.Key, ModRevision: s.rev}, PrevKv: prev})
+	return prev
+}
+
+func (s *Server) Txn(_ context.Context, r *pb.TxnRequest) (*pb.TxnResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	succeeded := s.compare(r.Compare)
+	ops := r.Failure
+	if succeeded {
+		ops = r.Success
+	}
+	if s.writes(ops) {
+		s.rev++
+	}
+	res, err := s.txn(ops)
+	if err != nil {
+		return nil, err
+	}
+	res.Succeeded = succeeded
+	res.Header = s.header()
+	return res, nil
+}
+
+func (s *Server) compare(cmps []*pb.Compare) bool {
+	for _, c := range cmps {
+		kv := s.kvs[string(c.Key)]
+		if kv == nil {
+			if c.Target == pb.Compare_VALUE {
+				return false
+			}
+			kv = &mvccpb.KeyValue{}
+		}
+		var n int
+		switch c.Target {
+		case pb.Compare_VERSION:
+			n = cmpInt(kv.Version, c.GetVersion())
+		case pb.Compare_CREATE:
+			n = cmpInt(kv.CreateRevision, c.GetCreateRevision())
+		case pb.Compare_MOD:
+			n = cmpInt(kv.ModRevision, c.GetModRevision())
+		case pb.Compare_VALUE:
+			n = bytes.Compare(kv.Value, c.GetValue())
+		case pb.Compare_LEASE:
+			n = cmpInt(kv.Lease, c.GetLease())
+		}
+		ok := false
+		switch c.Result {
+		case pb.Compare_EQUAL:
+			ok = n == 0
+		case pb.Compare_GREATER:
+			ok = n > 0
+		case pb.Compare_LESS:
+			ok = n < 0
+		case pb.Compare_NOT_EQUAL:
+			ok = n != 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func cmpInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// writes reports whether the operations change the store, a transaction takes one revision for all of them.
+func (s *Server) writes(ops []*pb.RequestOp) bool {
+	for _, op := range ops {
+		switch r := op.Request.(type) {
+		case *pb.RequestOp_RequestPut:
+			return true
+		case *pb.RequestOp_RequestDeleteRange:
+			if s.countRange(r.RequestDeleteRange.Key, r.RequestDeleteRange.RangeEnd) > 0 {
+				return true
+			}
+		case *pb.RequestOp_RequestTxn:
+			t := r.RequestTxn
+			if s.compare(t.Compare) && s.writes(t.Success) || !s.compare(t.Compare) && s.writes(t.Failure) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *Server) txn(ops []*pb.RequestOp) (*pb.TxnResponse, error) {
+	res := &pb.TxnResponse{}
+	for _, op := range ops {
+		switch r := op.Request.(type) {
+		case *pb.RequestOp_RequestRange:
+			res.Responses = append(res.Responses, &pb.ResponseOp{Response: &pb.ResponseOp_ResponseRange{ResponseRange: s.rangeKeys(r.RequestRange)}})
+		case *pb.RequestOp_RequestPut:
+			if l := r.RequestPut.Lease; l != 0 && s.leases[l] == nil {
+				return nil, rpctypes.ErrGRPCLeaseNotFound
+			}
+			put := s.put(r.RequestPut)
+			put.Header = s.header()
+			res.Responses = append(res.Responses, &pb.ResponseOp{Response: &pb.ResponseOp_ResponsePut{ResponsePut: put}})
+		case *pb.RequestOp_RequestDeleteRange:
+			del := s.deleteRange(r.RequestDeleteRange)
+			del.Header = s.header()
+			res.Responses = append(res.Responses, &pb.ResponseOp{Response: &pb.ResponseOp_ResponseDeleteRange{ResponseDeleteRange: del}})
+		case *pb.RequestOp_RequestTxn:
+			t := r.RequestTxn
+			succeeded := s.compare(t.Compare)
+			nested := t.Failure
+			if succeeded {
+				nested = t.Success
+			}
+			sub, err := s.txn(nested)
+			if err != nil {
+				return nil, err
+			}
+			sub.Succeeded = succeeded
+			sub.Header = s.header()
+			res.Responses = append(res.Responses, &pb.ResponseOp{Response: &pb.ResponseOp_ResponseTxn{ResponseTxn: sub}})
+		}
+	}
+	return res, nil
+}
+
+func (s *Server) notify(ev *mvccpb.Event) {
+	s.history = append(s.history, ev)
+	for w := range s.watchers {
+		if w.matches(ev) {
+			w.out <- &pb.WatchResponse{Header: s.header(), WatchId: w.id, Events: []*mvccpb.Event{w.event(ev)}}
+		}
+	}
+}
+
+func (w *watcher) matches(ev *mvccpb.Event) bool {
+	if ev.Type == mvccpb.PUT && w.noPut || ev.Type == mvccpb.DELETE && w.noDel {
+		return false
+	}
+	return inRange(ev.Kv.Key, w.key, w.end)
+}
+
+func (w *watcher) event(ev *mvccpb.Event) *mvccpb.Event {
+	if w.prevKv {
+		return ev
+	}
+	return &mvccpb.Event{Type: ev.Type, Kv: ev.Kv}
+}
+
+func (s *Server) Watch(stream pb.Watch_WatchServer) error {
+	out := make(chan *pb.WatchResponse, 4096)
+	owned := make(map[int64]*watcher)
+	defer func() {
+		s.mu.Lock()
+		for _, w := range owned {
+			delete(s.watchers, w)
+		}
+		s.mu.Unlock()
+	}()
+	errc := make(chan error, 1)
+	go func() {
+		for {
+			select {
+			case res := <-out:
+				if err := stream.Send(res); err != nil {
+					errc <- err
+					return
+				}
+			case <-stream.Context().Done():
+				return
+			}
+		}
+	}()
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		select {
+		case err = <-errc:
+			return err
+		default:
+		}
+		s.mu.Lock()
+		switch r := req.RequestUnion.(type) {
+		case *pb.WatchRequest_CreateRequest:
+			c := r.CreateRequest
+			w := &watcher{id: c.WatchId, key: c.Key, end: c.RangeEnd, prevKv: c.PrevKv, out: out}
+			if w.id <= 0 {
+				w.id = s.nextWatch
+				s.nextWatch++
+			}
+			for _, f := range c.Filters {
+				w.noPut = w.noPut || f == pb.WatchCreateRequest_NOPUT
+				w.noDel = w.noDel || f == pb.WatchCreateRequest_NODELETE
+			}
+			out <- &pb.WatchResponse{Header: s.header(), WatchId: w.id, Created: true}
+			if c.StartRevision > 0 {
+				var events []*mvccpb.Event
+				for _, ev := range s.history {
+					if ev.Kv.ModRevision >= c.StartRevision && w.matches(ev) {
+						events = append(events, w.event(ev))
+					}
+				}
+				if len(events) > 0 {
+					out <- &pb.WatchResponse{Header: s.header(), WatchId: w.id, Events: events}
+				}
+			}
+			owned[w.id] = w
+			s.watchers[w] = struct{}{}
+		case *pb.WatchRequest_CancelRequest:
+			if w, ok := owned[r.CancelRequest.WatchId]; ok {
+				delete(owned, w.id)
+				delete(s.watchers, w)
+				out <- &pb.WatchResponse{Header: s.header(), WatchId: w.id, Canceled: true}
+			}
+		case *pb.WatchRequest_ProgressRequest:
+			out <- &pb.WatchResponse{Header: s.header(), WatchId: -1}
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *Server) LeaseGrant(_ context.Context, r *pb.LeaseGrantRequest) (*pb.LeaseGrantResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := r.ID
+	if id == 0 {
+		s.nextLease++
+		id = s.nextLease
+	}
+	s.leases[id] = &lease{ttl: r.TTL, expires: time.Now().Add(time.Duration(r.TTL) * time.Second), keys: make(map[string]struct{})}
+	return &pb.LeaseGrantResponse{Header: s.header(), ID: id, TTL: r.TTL}, nil
+}
+
+func (s *Server) LeaseRevoke(_ context.Context, r *pb.LeaseRevokeRequest) (*pb.LeaseRevokeResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.leases[r.ID] == nil {
+		return nil, rpctypes.ErrGRPCLeaseNotFound
+	}
+	s.revoke(r.ID)
+	return &pb.LeaseRevokeResponse{Header: s.header()}, nil
+}
+
+// revoke deletes the lease and its keys in one revision.
+func (s *Server) revoke(id int64) {
+	l := s.leases[id]
+	delete(s.leases, id)
+	if len(l.keys) == 0 {
+		return
+	}
+	s.rev++
+	keys := make([]string, 0, len(l.keys))
+	for key := range l.keys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s.del(key)
+	}
+}
+
+func (s *Server) LeaseKeepAlive(stream pb.Lease_LeaseKeepAliveServer) error {
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		s.mu.Lock()
+		res := &pb.LeaseKeepAliveResponse{Header: s.header(), ID: req.ID}
+		if l := s.leases[req.ID]; l != nil {
+			l.expires = time.Now().Add(time.Duration(l.ttl) * time.Second)
+			res.TTL = l.ttl
+		}
+		s.mu.Unlock()
+		if err = stream.Send(res); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) LeaseTimeToLive(_ context.Context, r *pb.LeaseTimeToLiveRequest) (*pb.LeaseTimeToLiveResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := &pb.LeaseTimeToLiveResponse{Header: s.header(), ID: r.ID, TTL: -1}
+	if l := s.leases[r.ID]; l != nil {
+		res.TTL = int64(time.Until(l.expires).Seconds())
+		res.GrantedTTL = l.ttl
+		if r.Keys {
+			for key := range l.keys {
+				res.Keys = append(res.Keys, []byte(key))
+			}
+		}
+	}
+	return res, nil
+}
@@ -25,6 +25,7 @@ var startAutoClear = sync.OnceFunc(func() {
 				item := get()
 				if item.waiting == 0 && !item.locked && time.Since(item.lastTime) > cleanupTime {
 					etcdLockers.Delete(key)
+					redisLockers.Delete(key)
 					localLockers.Delete(key)
 				}
 				return true
@@ -1,111 +1,129 @@
 package keylocker
 
 import (
+	"github.com/tencent-go/pkg/errx"
 	"github.com/tencent-go/pkg/etcdx"
 	"github.com/tencent-go/pkg/shutdown"
 	"context"
+	"errors"
 	"github.com/sirupsen/logrus"
+	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/client/v3/concurrency"
 	"sync"
 	"time"
 )
 
-type Locker interface {
-	sync.Locker
-	TryLock() bool
-}
-
 func Etcd(key string) Locker {
-	val, _ := etcdLockers.LoadOrStore(key, sync.OnceValue(func() *etcdItem {
-		return &etcdItem{
-			local: Local(key).(*localItem),
-			etcd:  concurrency.NewMutex(getSession(), key),
+	val, _ := etcdLockers.LoadOrStore(key, sync.OnceValue(func() *layeredItem {
+		return &layeredItem{
+			local:   Local(key).(*localItem),
+			acquire: acquireEtcd,
 		}
 	}))
-	get := val.(func() *etcdItem)
+	get := val.(func() *layeredItem)
 	return get()
 }
 
 var etcdLockers sync.Map
 
-type etcdItem struct {
-	local *localItem
-	etcd  *concurrency.Mutex
-	count int
+// SetEtcdClient replaces etcdx.DefaultClient for the etcd lockers, the sessions of the previous client
+// are left to expire.
+func SetEtcdClient(client *clientv3.Client) {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	etcdClient = func() *clientv3.Client { return client }
+	sessions = make(map[int]*concurrency.Session)
 }
 
-func (e *etcdItem) Lock() {
-	// 先拿到本地鎖
-	e.local.L.Lock()
-	defer e.local.L.Unlock()
-	for e.local.locked {
-		e.local.waiting++
-		e.local.Wait()
-		e.local.waiting--
-	}
-	e.local.locked = true
-	if e.count == 0 {
-		ctx := context.Background()
-		err := e.etcd.Lock(ctx)
-		if err != nil {
-			if ctx.Err() != nil {
-				return
-			}
-			logrus.Fatalf("failed to lock etcd mutex %s: %v", e.local.key, err)
-		}
-	}
-	e.count++
-}
+var etcdClient = etcdx.DefaultClient
 
-func (e *etcdItem) TryLock() bool {
-	e.local.L.Lock()
-	defer e.local.L.Unlock()
-	if e.local.locked {
-		return false
-	}
-	if e.count == 0 {
-		ctx := context.Background()
-		err := e.etcd.TryLock(ctx)
-		if err != nil {
-			return false
-		}
+func acquireEtcd(ctx context.Context, key string, ttl time.Duration, try bool) (remoteLock, error) {
+	s, err := getSession(ttl)
+	if err != nil {
+		return nil, err
 	}
-	e.local.locked = true
-	e.count++
-	return true
+	return acquireEtcdSession(ctx, s, key, try)
 }
 
-func (e *etcdItem) Unlock() {
-	e.local.L.Lock()
-	defer e.local.L.Unlock()
-	if !e.local.locked {
-		logrus.Panicf("unlock unlocked lock %s", e.local.key)
-	}
-	e.local.locked = false
-	if e.local.waiting > 0 {
-		e.local.Signal()
+// acquireEtcdSession locks key with s, the locks of one session do not exclude each other.
+func acquireEtcdSession(ctx context.Context, s *concurrency.Session, key string, try bool) (remoteLock, error) {
+	m := concurrency.NewMutex(s, key)
+	var er error
+	if try {
+		if er = m.TryLock(ctx); errors.Is(er, concurrency.ErrLocked) {
+			return nil, errLocked
+		}
 	} else {
-		e.local.lastTime = time.Now()
+		er = m.Lock(ctx)
 	}
-	e.count--
-	if e.count < 0 {
-		logrus.Panicf("unlock unlocked lock %s", e.local.key)
+	if er != nil {
+		return nil, er
 	}
-	if e.count == 0 {
-		err := e.etcd.Unlock(context.Background())
-		if err != nil {
-			logrus.WithError(err).WithField("key", e.local.key).Error("failed to unlock etcd mutex")
-		}
+	return &etcdLock{key: key, mutex: m, session: s}, nil
+}
+
+type etcdLock struct {
+	key     string
+	mutex   *concurrency.Mutex
+	session *concurrency.Session
+}
+
+// token is the etcd revision at which the lock was acquired.
+func (l *etcdLock) token() int64 {
+	return l.mutex.Header().Revision
+}
+
+func (l *etcdLock) lost() <-chan struct{} {
+	return l.session.Done()
+}
+
+func (l *etcdLock) release() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := l.mutex.Unlock(ctx); err != nil {
+		// session過期後鎖已自動釋放
+		logrus.WithError(err).WithField("key", l.key).Error("failed to unlock etcd mutex")
 	}
 }
 
-var getSession = sync.OnceValue(func() *concurrency.Session {
-	s, err := concurrency.NewSession(etcdx.DefaultClient(), concurrency.WithTTL(10))
+var (
+	sessionsMu sync.Mutex
+	sessions   = make(map[int]*concurrency.Session) // keyed by ttl seconds
+)
+
+// getSession shares a session among the locks of the same TTL, an expired session is replaced.
+func getSession(ttl time.Duration) (*concurrency.Session, errx.Error) {
+	seconds := int((ttl + time.Second - 1) / time.Second)
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	if s, ok := sessions[seconds]; ok {
+		select {
+		case <-s.Done():
+			delete(sessions, seconds)
+		default:
+			return s, nil
+		}
+	}
+	s, err := concurrency.NewSession(etcdClient(), concurrency.WithTTL(seconds))
 	if err != nil {
-		logrus.WithError(err).Fatal("failed to create etcd session")
+		return nil, errx.Wrap(err).WithType(errx.TypeNetwork).AppendMsgf("failed to create etcd session").Err()
 	}
+	registerSessionShutdown()
+	sessions[seconds] = s
+	return s, nil
+}
+
+var registerSessionShutdown = sync.OnceFunc(func() {
 	shutdown.OnShutdown(func(ctx context.Context) error {
-		return s.Close()
+		sessionsMu.Lock()
+		defer sessionsMu.Unlock()
+		var errs []error
+		for seconds, s := range sessions {
+			if err := s.Close(); err != nil {
+				errs = append(errs, err)
+			}
+			delete(sessions, seconds)
+		}
+		return errors.Join(errs...)
 	}, true)
-	return s
 })
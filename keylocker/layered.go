@@ -0,0 +1,119 @@
+package keylocker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/tencent-go/pkg/errx"
+	"github.com/sirupsen/logrus"
+)
+
+// remoteLock is a lock held in a backend shared by the processes.
+type remoteLock interface {
+	token() int64
+	lost() <-chan struct{}
+	release()
+}
+
+// acquireFunc locks key in the backend, it returns errLocked when try is set and the key is held.
+type acquireFunc func(ctx context.Context, key string, ttl time.Duration, try bool) (remoteLock, error)
+
+var errLocked = errors.New("locked")
+
+// layeredItem takes the local lock before the remote one, so the goroutines of the process queue locally
+// and only one of them talks to the backend.
+type layeredItem struct {
+	local   *localItem
+	acquire acquireFunc
+	held    Lease // 由Lock或TryLock取得, 受local.L保護
+}
+
+// Lock retries until the lock is acquired, backend errors are logged instead of crashing the process.
+func (e *layeredItem) Lock() {
+	for attempt := 0; ; attempt++ {
+		lease, err := e.LockCtx(context.Background())
+		if err == nil {
+			e.local.L.Lock()
+			e.held = lease
+			e.local.L.Unlock()
+			return
+		}
+		logrus.WithError(err).WithField("key", e.local.key).Errorf("failed to lock, attempt %d", attempt+1)
+		time.Sleep(min(time.Duration(attempt+1)*time.Second, 10*time.Second))
+	}
+}
+
+func (e *layeredItem) TryLock() bool {
+	e.local.L.Lock()
+	if e.local.locked {
+		e.local.L.Unlock()
+		return false
+	}
+	e.local.locked = true
+	e.local.L.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTTL)
+	defer cancel()
+	remote, err := e.acquire(ctx, e.local.key, defaultTTL, true)
+	if err != nil {
+		if !errors.Is(err, errLocked) {
+			logrus.WithError(err).WithField("key", e.local.key).Error("failed to try lock")
+		}
+		e.local.Unlock()
+		return false
+	}
+	e.local.L.Lock()
+	e.held = &layeredLease{item: e, remote: remote}
+	e.local.L.Unlock()
+	return true
+}
+
+func (e *layeredItem) LockCtx(ctx context.Context, opts ...LockOption) (Lease, errx.Error) {
+	o := getLockOptions(opts...)
+	e.local.L.Lock()
+	if err := e.local.wait(ctx); err != nil {
+		e.local.L.Unlock()
+		return nil, err
+	}
+	e.local.locked = true
+	e.local.L.Unlock()
+	remote, err := e.acquire(ctx, e.local.key, o.ttl, false)
+	if err != nil {
+		e.local.Unlock()
+		return nil, lockError(ctx, e.local.key, err)
+	}
+	return &layeredLease{item: e, remote: remote}, nil
+}
+
+func (e *layeredItem) Unlock() {
+	e.local.L.Lock()
+	lease := e.held
+	e.held = nil
+	e.local.L.Unlock()
+	if lease == nil {
+		logrus.Panicf("unlock unlocked lock %s", e.local.key)
+	}
+	lease.Unlock()
+}
+
+type layeredLease struct {
+	item   *layeredItem
+	remote remoteLock
+	once   sync.Once
+}
+
+func (l *layeredLease) Token() int64 {
+	return l.remote.token()
+}
+
+func (l *layeredLease) Lost() <-chan struct{} {
+	return l.remote.lost()
+}
+
+func (l *layeredLease) Unlock() {
+	l.once.Do(func() {
+		l.remote.release()
+		l.item.local.Unlock()
+	})
+}
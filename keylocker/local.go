@@ -1,18 +1,18 @@
 package keylocker
 
 import (
+	"context"
 	"github.com/sirupsen/logrus"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/tencent-go/pkg/errx"
 )
 
 func Local(key string) Locker {
 	val, _ := localLockers.LoadOrStore(key, sync.OnceValue(func() *localItem {
-		return &localItem{
-			Cond:     sync.NewCond(&sync.Mutex{}),
-			key:      key,
-			lastTime: time.Now(),
-		}
+		return newLocalItem(key)
 	}))
 	get := val.(func() *localItem)
 	startAutoClear()
@@ -21,10 +21,23 @@ func Local(key string) Locker {
 
 var localLockers sync.Map
 
+// localTokens numbers the acquisitions of all the local locks, a key keeps increasing tokens after its item
+// is evicted by the cleanup.
+var localTokens atomic.Int64
+
+func newLocalItem(key string) *localItem {
+	return &localItem{
+		Cond:     sync.NewCond(&sync.Mutex{}),
+		key:      key,
+		lastTime: time.Now(),
+	}
+}
+
 type localItem struct {
 	waiting  int
 	locked   bool
 	lastTime time.Time
+	token    int64
 	*sync.Cond
 	key string
 }
@@ -38,6 +51,7 @@ func (l *localItem) Lock() {
 		l.waiting--
 	}
 	l.locked = true
+	l.token = localTokens.Add(1)
 }
 
 func (l *localItem) TryLock() bool {
@@ -48,9 +62,51 @@ func (l *localItem) TryLock() bool {
 	}
 
 	l.locked = true
+	l.token = localTokens.Add(1)
 	return true
 }
 
+// LockCtx ignores the TTL, a local lock is only lost when the process exits.
+func (l *localItem) LockCtx(ctx context.Context, opts ...LockOption) (Lease, errx.Error) {
+	l.L.Lock()
+	defer l.L.Unlock()
+	if err := l.wait(ctx); err != nil {
+		return nil, err
+	}
+	l.locked = true
+	l.token = localTokens.Add(1)
+	return &localLease{item: l, token: l.token}, nil
+}
+
+// wait blocks until the lock is free or ctx is done, l.L must be held.
+func (l *localItem) wait(ctx context.Context) errx.Error {
+	if !l.locked {
+		return nil
+	}
+	stop := context.AfterFunc(ctx, func() {
+		l.L.Lock()
+		defer l.L.Unlock()
+		l.Broadcast()
+	})
+	defer stop()
+	for l.locked {
+		if ctx.Err() != nil {
+			return lockError(ctx, l.key, ctx.Err())
+		}
+		l.waiting++
+		l.Wait()
+		l.waiting--
+	}
+	if ctx.Err() != nil {
+		// 被喚醒的是已取消的等待者, 轉交給下一個
+		if l.waiting > 0 {
+			l.Signal()
+		}
+		return lockError(ctx, l.key, ctx.Err())
+	}
+	return nil
+}
+
 func (l *localItem) Unlock() {
 	l.L.Lock()
 	defer l.L.Unlock()
@@ -64,3 +120,23 @@ func (l *localItem) Unlock() {
 		l.lastTime = time.Now()
 	}
 }
+
+type localLease struct {
+	item  *localItem
+	token int64
+	once  sync.Once
+}
+
+func (l *localLease) Token() int64 {
+	return l.token
+}
+
+func (l *localLease) Lost() <-chan struct{} {
+	return neverLost
+}
+
+func (l *localLease) Unlock() {
+	l.once.Do(l.item.Unlock)
+}
+
+var neverLost = make(chan struct{})
@@ -0,0 +1,64 @@
+package keylocker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/tencent-go/pkg/errx"
+)
+
+type Locker interface {
+	sync.Locker
+	TryLock() bool
+	// LockCtx waits for the lock until ctx is done, the lock is released by Lease.Unlock instead of Unlock.
+	LockCtx(ctx context.Context, opts ...LockOption) (Lease, errx.Error)
+}
+
+// Lease is a lock acquired by LockCtx.
+type Lease interface {
+	// Token increases with every acquisition of the key, storage can reject writes carrying a token lower
+	// than the last one seen to fence off a holder that lost the lock without knowing.
+	Token() int64
+	// Lost is closed when the lock may be held by another process, e.g. the etcd session expired.
+	Lost() <-chan struct{}
+	Unlock()
+}
+
+const (
+	defaultTTL = 10 * time.Second
+	minTTL     = 100 * time.Millisecond // 過短的TTL來不及續期
+)
+
+type lockOptions struct {
+	ttl time.Duration
+}
+
+type LockOption func(*lockOptions)
+
+// WithTTL sets how long the lock survives the holder losing contact with the backend, it is rounded up to
+// seconds for etcd. The default is 10s, a TTL below 100ms is raised to 100ms.
+func WithTTL(ttl time.Duration) LockOption {
+	return func(o *lockOptions) {
+		o.ttl = ttl
+	}
+}
+
+func getLockOptions(opts ...LockOption) lockOptions {
+	o := lockOptions{ttl: defaultTTL}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.ttl <= 0 {
+		o.ttl = defaultTTL
+	}
+	o.ttl = max(o.ttl, minTTL)
+	return o
+}
+
+func lockError(ctx context.Context, key string, e error) errx.Error {
+	if ctx.Err() != nil {
+		return errx.Wrap(ctx.Err()).WithType(errx.TypeTimeout).AppendMsgf("lock %s canceled", key).Err()
+	}
+	return errx.Wrap(e).WithType(errx.TypeNetwork).AppendMsgf("lock %s failed", key).Err()
+}
@@ -0,0 +1,195 @@
+package keylocker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tencent-go/pkg/errx"
+	"github.com/tencent-go/pkg/internal/etcdtest"
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+// testLocker is the conformance suite every backend passes, acquire and other lock the backend as two
+// processes would, they are nil for the local locker.
+func testLocker(t *testing.T, name string, locker func(key string) Locker, acquire, other acquireFunc) {
+	key := func(t *testing.T) string {
+		return fmt.Sprintf("keylocker-test/%s/%s", name, t.Name())
+	}
+
+	t.Run("mutual exclusion", func(t *testing.T) {
+		l := locker(key(t))
+		var (
+			wg      sync.WaitGroup
+			holders atomic.Int32
+			count   int
+		)
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				l.Lock()
+				defer l.Unlock()
+				assert.Equal(t, int32(1), holders.Add(1))
+				count++
+				time.Sleep(time.Millisecond)
+				holders.Add(-1)
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, 8, count)
+	})
+
+	t.Run("try lock", func(t *testing.T) {
+		l := locker(key(t))
+		assert.True(t, l.TryLock())
+		assert.False(t, locker(key(t)).TryLock())
+		l.Unlock()
+		assert.True(t, l.TryLock())
+		l.Unlock()
+	})
+
+	t.Run("lock with context", func(t *testing.T) {
+		l := locker(key(t))
+		lease, err := l.LockCtx(context.Background(), WithTTL(2*time.Second))
+		if !assert.NoError(t, err) {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		_, err = l.LockCtx(ctx)
+		if assert.Error(t, err) {
+			assert.Equal(t, errx.TypeTimeout, err.Type())
+		}
+		select {
+		case <-lease.Lost():
+			t.Fatal("lease lost while held")
+		default:
+		}
+
+		next := make(chan Lease)
+		go func() {
+			lease, err := l.LockCtx(context.Background())
+			assert.NoError(t, err)
+			next <- lease
+		}()
+		time.Sleep(50 * time.Millisecond)
+		lease.Unlock()
+		lease.Unlock()
+		select {
+		case following := <-next:
+			assert.Greater(t, following.Token(), lease.Token())
+			following.Unlock()
+		case <-time.After(5 * time.Second):
+			t.Fatal("waiter not woken")
+		}
+	})
+
+	if acquire == nil {
+		return
+	}
+
+	t.Run("backend mutual exclusion", func(t *testing.T) {
+		// 各自的本地鎖, 僅後端互斥
+		first := &layeredItem{local: newLocalItem(key(t)), acquire: acquire}
+		second := &layeredItem{local: newLocalItem(key(t)), acquire: other}
+		lease, err := first.LockCtx(context.Background(), WithTTL(2*time.Second))
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.False(t, second.TryLock())
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		_, err = second.LockCtx(ctx)
+		if assert.Error(t, err) {
+			assert.Equal(t, errx.TypeTimeout, err.Type())
+		}
+
+		next := make(chan Lease)
+		go func() {
+			lease, err := second.LockCtx(context.Background(), WithTTL(2*time.Second))
+			assert.NoError(t, err)
+			next <- lease
+		}()
+		select {
+		case <-next:
+			t.Fatal("acquired while held by another process")
+		case <-time.After(300 * time.Millisecond):
+		}
+		lease.Unlock()
+		select {
+		case following := <-next:
+			assert.Greater(t, following.Token(), lease.Token())
+			following.Unlock()
+		case <-time.After(5 * time.Second):
+			t.Fatal("waiter not woken")
+		}
+	})
+}
+
+func TestLockOptions(t *testing.T) {
+	assert.Equal(t, defaultTTL, getLockOptions().ttl)
+	assert.Equal(t, defaultTTL, getLockOptions(WithTTL(-time.Second)).ttl)
+	assert.Equal(t, minTTL, getLockOptions(WithTTL(time.Nanosecond)).ttl)
+	assert.Equal(t, time.Second, getLockOptions(WithTTL(time.Second)).ttl)
+}
+
+func TestLocal(t *testing.T) {
+	testLocker(t, "local", Local, nil, nil)
+
+	t.Run("清除後token仍遞增", func(t *testing.T) {
+		key := "local-evicted"
+		lease, err := Local(key).LockCtx(context.Background())
+		if !assert.NoError(t, err) {
+			return
+		}
+		lease.Unlock()
+		// 如同清除閒置的鎖
+		localLockers.Delete(key)
+		following, err := Local(key).LockCtx(context.Background())
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Greater(t, following.Token(), lease.Token())
+		following.Unlock()
+	})
+}
+
+func TestEtcd(t *testing.T) {
+	srv := etcdtest.NewServer()
+	defer srv.Close()
+	client, err := srv.Client()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { _ = client.Close() }()
+	SetEtcdClient(client)
+	// 同一session的鎖互不排斥, 另一session如同另一個進程
+	s, e := concurrency.NewSession(client, concurrency.WithTTL(2))
+	if !assert.NoError(t, e) {
+		return
+	}
+	defer func() { _ = s.Close() }()
+	other := func(ctx context.Context, key string, ttl time.Duration, try bool) (remoteLock, error) {
+		return acquireEtcdSession(ctx, s, key, try)
+	}
+	testLocker(t, "etcd", Etcd, acquireEtcd, other)
+
+	t.Run("lost", func(t *testing.T) {
+		lease, err := Etcd("keylocker-test/etcd/lost").LockCtx(context.Background(), WithTTL(time.Second))
+		if !assert.NoError(t, err) {
+			return
+		}
+		srv.ExpireLeases()
+		select {
+		case <-lease.Lost():
+		case <-time.After(5 * time.Second):
+			t.Fatal("lease not lost")
+		}
+		lease.Unlock()
+	})
+}
@@ -0,0 +1,159 @@
+package keylocker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/tencent-go/pkg/redisx"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// Redis locks key with SET NX PX, the lock is extended while held. The keys are hash tagged, so the lock
+// and its fencing counter stay in one slot of Redis Cluster.
+func Redis(key string) Locker {
+	val, _ := redisLockers.LoadOrStore(key, sync.OnceValue(func() *layeredItem {
+		return &layeredItem{
+			local:   Local(key).(*localItem),
+			acquire: acquireRedis,
+		}
+	}))
+	get := val.(func() *layeredItem)
+	return get()
+}
+
+// SetRedisClient replaces redisx.GetDefaultClient for the Redis lockers.
+func SetRedisClient(client redis.UniversalClient) {
+	redisClient = func() redis.UniversalClient { return client }
+}
+
+var redisClient = func() redis.UniversalClient {
+	return redisx.GetDefaultClient()
+}
+
+var redisLockers sync.Map
+
+const redisRetryInterval = 100 * time.Millisecond
+
+var (
+	// 取得鎖時遞增fencing計數器
+	redisAcquireScript = redis.NewScript(`
+if redis.call('set', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	return redis.call('incr', KEYS[2])
+end
+return 0`)
+	redisReleaseScript = redis.NewScript(`
+if redis.call('get', KEYS[1]) == ARGV[1] then
+	return redis.call('del', KEYS[1])
+end
+return 0`)
+	redisExtendScript = redis.NewScript(`
+if redis.call('get', KEYS[1]) == ARGV[1] then
+	return redis.call('pexpire', KEYS[1], ARGV[2])
+end
+return 0`)
+)
+
+func acquireRedis(ctx context.Context, key string, ttl time.Duration, try bool) (remoteLock, error) {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	l := &redisLock{
+		client: redisClient(),
+		keys:   []string{"{" + key + "}", "{" + key + "}:fence"},
+		value:  hex.EncodeToString(b),
+		ttl:    ttl,
+		done:   make(chan struct{}),
+		lostCh: make(chan struct{}),
+	}
+	for {
+		// 過期時間自送出指令起算, 不受往返延遲影響
+		sent := time.Now()
+		token, err := redisAcquireScript.Run(ctx, l.client, l.keys, l.value, ttl.Milliseconds()).Int64()
+		if err != nil {
+			return nil, err
+		}
+		if token > 0 {
+			l.fence = token
+			go l.extend(sent)
+			return l, nil
+		}
+		if try {
+			return nil, errLocked
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(redisRetryInterval):
+		}
+	}
+}
+
+type redisLock struct {
+	client redis.UniversalClient
+	keys   []string // lock and fencing counter
+	value  string   // identifies the holder
+	ttl    time.Duration
+	fence  int64
+	done   chan struct{}
+	lostCh chan struct{}
+}
+
+// token is the value of the fencing counter of the key after the acquisition.
+func (l *redisLock) token() int64 {
+	return l.fence
+}
+
+func (l *redisLock) lost() <-chan struct{} {
+	return l.lostCh
+}
+
+// extend renews the expiration every third of the TTL from the time the renewal is sent, the lock is lost
+// once the key belongs to someone else or shortly before the last expiration renewed, the margin covering
+// the clock drift between the process and Redis.
+func (l *redisLock) extend(renewed time.Time) {
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	margin := l.ttl / 10
+	expiry := time.NewTimer(time.Until(renewed.Add(l.ttl - margin)))
+	defer expiry.Stop()
+	for {
+		select {
+		case <-l.done:
+			return
+		case <-expiry.C:
+			logrus.WithField("key", l.keys[0]).Error("redis lock expired")
+			close(l.lostCh)
+			return
+		case <-ticker.C:
+		}
+		sent := time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), l.ttl/3)
+		res, err := redisExtendScript.Run(ctx, l.client, l.keys[:1], l.value, l.ttl.Milliseconds()).Int64()
+		cancel()
+		switch {
+		case err == nil && res == 1:
+			expiry.Reset(time.Until(sent.Add(l.ttl - margin)))
+			continue
+		case err == nil:
+			logrus.WithField("key", l.keys[0]).Error("redis lock taken over")
+		default:
+			logrus.WithError(err).WithField("key", l.keys[0]).Warn("failed to extend redis lock")
+			continue
+		}
+		close(l.lostCh)
+		return
+	}
+}
+
+func (l *redisLock) release() {
+	close(l.done)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := redisReleaseScript.Run(ctx, l.client, l.keys[:1], l.value).Err(); err != nil && !errors.Is(err, redis.Nil) {
+		logrus.WithError(err).WithField("key", l.keys[0]).Error("failed to unlock redis lock")
+	}
+}
@@ -0,0 +1,274 @@
+package keylocker
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRedis speaks enough RESP2 for the lock scripts, which it runs natively instead of interpreting Lua.
+type fakeRedis struct {
+	listener net.Listener
+	mu       sync.Mutex
+	values   map[string]string
+	expires  map[string]time.Time
+	down     bool // 所有指令回覆錯誤, 如同連線中斷
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeRedis{listener: l, values: make(map[string]string), expires: make(map[string]time.Time)}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		if _, err = io.WriteString(conn, f.exec(args)); err != nil {
+			return
+		}
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err = io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func (f *fakeRedis) exec(args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down {
+		return "-ERR unavailable\r\n"
+	}
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "EVALSHA":
+		return "-NOSCRIPT No matching script. Please use EVAL.\r\n"
+	case "EVAL":
+		n, _ := strconv.Atoi(args[2])
+		keys, argv := args[3:3+n], args[3+n:]
+		switch fmt.Sprintf("%x", sha1.Sum([]byte(args[1]))) {
+		case redisAcquireScript.Hash():
+			if _, ok := f.get(keys[0]); ok {
+				return ":0\r\n"
+			}
+			ms, _ := strconv.Atoi(argv[1])
+			f.set(keys[0], argv[0], time.Duration(ms)*time.Millisecond)
+			fence, _ := strconv.Atoi(f.values[keys[1]])
+			f.values[keys[1]] = strconv.Itoa(fence + 1)
+			return fmt.Sprintf(":%d\r\n", fence+1)
+		case redisReleaseScript.Hash():
+			if v, ok := f.get(keys[0]); ok && v == argv[0] {
+				delete(f.values, keys[0])
+				return ":1\r\n"
+			}
+			return ":0\r\n"
+		case redisExtendScript.Hash():
+			if v, ok := f.get(keys[0]); ok && v == argv[0] {
+				ms, _ := strconv.Atoi(argv[1])
+				f.set(keys[0], v, time.Duration(ms)*time.Millisecond)
+				return ":1\r\n"
+			}
+			return ":0\r\n"
+		}
+	}
+	return "-ERR unknown command\r\n"
+}
+
+func (f *fakeRedis) get(key string) (string, bool) {
+	if e, ok := f.expires[key]; ok && time.Now().After(e) {
+		delete(f.values, key)
+		delete(f.expires, key)
+	}
+	v, ok := f.values[key]
+	return v, ok
+}
+
+func (f *fakeRedis) set(key, value string, ttl time.Duration) {
+	f.values[key] = value
+	f.expires[key] = time.Now().Add(ttl)
+}
+
+// takeOver replaces the holder of key as if the lock expired and another process acquired it.
+func (f *fakeRedis) takeOver(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.values["{"+key+"}"] = "other"
+}
+
+func (f *fakeRedis) setDown(down bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.down = down
+}
+
+func TestRedis(t *testing.T) {
+	srv := newFakeRedis(t)
+	defer func() { _ = srv.listener.Close() }()
+	client := redis.NewClient(&redis.Options{Addr: srv.listener.Addr().String()})
+	defer func() { _ = client.Close() }()
+	SetRedisClient(client)
+	testLocker(t, "redis", Redis, acquireRedis, acquireRedis)
+
+	t.Run("other process", func(t *testing.T) {
+		key := "keylocker-test/redis/other"
+		lease, err := Redis(key).LockCtx(context.Background())
+		if !assert.NoError(t, err) {
+			return
+		}
+		_, e := acquireRedis(context.Background(), key, defaultTTL, true)
+		assert.ErrorIs(t, e, errLocked)
+		lease.Unlock()
+		remote, e := acquireRedis(context.Background(), key, defaultTTL, true)
+		if assert.NoError(t, e) {
+			assert.Greater(t, remote.token(), lease.Token())
+			remote.release()
+		}
+	})
+
+	t.Run("extended while held", func(t *testing.T) {
+		lease, err := Redis("keylocker-test/redis/extended").LockCtx(context.Background(), WithTTL(300*time.Millisecond))
+		if !assert.NoError(t, err) {
+			return
+		}
+		time.Sleep(time.Second)
+		select {
+		case <-lease.Lost():
+			t.Fatal("lease lost while held")
+		default:
+		}
+		lease.Unlock()
+	})
+
+	t.Run("lost", func(t *testing.T) {
+		key := "keylocker-test/redis/lost"
+		lease, err := Redis(key).LockCtx(context.Background(), WithTTL(300*time.Millisecond))
+		if !assert.NoError(t, err) {
+			return
+		}
+		srv.takeOver(key)
+		select {
+		case <-lease.Lost():
+		case <-time.After(3 * time.Second):
+			t.Fatal("lease not lost")
+		}
+		lease.Unlock()
+	})
+
+	t.Run("lost before expiring without renewal", func(t *testing.T) {
+		start := time.Now()
+		lease, err := Redis("keylocker-test/redis/unrenewed").LockCtx(context.Background(), WithTTL(300*time.Millisecond))
+		if !assert.NoError(t, err) {
+			return
+		}
+		srv.setDown(true)
+		defer srv.setDown(false)
+		select {
+		case <-lease.Lost():
+			// 以送出指令的時間起算, 在key過期之前通知
+			assert.Less(t, time.Since(start), 300*time.Millisecond)
+		case <-time.After(3 * time.Second):
+			t.Fatal("lease not lost")
+		}
+		lease.Unlock()
+	})
+
+	t.Run("tiny ttl", func(t *testing.T) {
+		lease, err := Redis("keylocker-test/redis/tiny").LockCtx(context.Background(), WithTTL(time.Nanosecond))
+		if !assert.NoError(t, err) {
+			return
+		}
+		time.Sleep(200 * time.Millisecond)
+		select {
+		case <-lease.Lost():
+			t.Fatal("lease lost while held")
+		default:
+		}
+		lease.Unlock()
+	})
+}
+
+// TestRedisServer runs the suite against a real Redis, so the Lua scripts are exercised as well. It is
+// skipped unless REDIS_ADDRESS is set.
+func TestRedisServer(t *testing.T) {
+	address := os.Getenv("REDIS_ADDRESS")
+	if address == "" {
+		t.Skip("REDIS_ADDRESS not set")
+	}
+	client := redis.NewUniversalClient(&redis.UniversalOptions{
+		Addrs:    []string{address},
+		Password: os.Getenv("REDIS_PASSWORD"),
+	})
+	defer func() { _ = client.Close() }()
+	SetRedisClient(client)
+	testLocker(t, "redis-server", Redis, acquireRedis, acquireRedis)
+
+	t.Run("lost", func(t *testing.T) {
+		key := "keylocker-test/redis-server/lost"
+		lease, err := Redis(key).LockCtx(context.Background(), WithTTL(300*time.Millisecond))
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.NoError(t, client.Set(context.Background(), "{"+key+"}", "other", time.Second).Err())
+		select {
+		case <-lease.Lost():
+		case <-time.After(3 * time.Second):
+			t.Fatal("lease not lost")
+		}
+		lease.Unlock()
+		assert.Equal(t, "other", client.Get(context.Background(), "{"+key+"}").Val(), "release checks the holder")
+	})
+}
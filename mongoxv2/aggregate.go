@@ -0,0 +1,247 @@
+package mongox
+
+import (
+	"context"
+	"iter"
+	"reflect"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tencent-go/pkg/errx"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Aggregate runs pipeline on coll and decodes every result into R with the client registry.
+func Aggregate[R any, T any](ctx context.Context, coll Collection[T], pipeline mongo.Pipeline, opts ...*AggregateOptions) ([]R, errx.Error) {
+	cur, err := aggregate(ctx, coll.Raw(), pipeline, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = cur.Close(ctx) }()
+	list := make([]R, 0)
+	if e := cur.All(ctx, &list); e != nil {
+		return nil, errx.Wrap(e).AppendMsg("decode failed").Err()
+	}
+	return list, nil
+}
+
+// AggregateIter is the streaming variant of Aggregate, the cursor is closed when the loop ends.
+//
+//	for item, err := range AggregateIter[Report](ctx, coll, pipeline) {
+//		if err != nil {
+//			return err
+//		}
+//	}
+func AggregateIter[R any, T any](ctx context.Context, coll Collection[T], pipeline mongo.Pipeline, opts ...*AggregateOptions) iter.Seq2[*R, errx.Error] {
+	return func(yield func(*R, errx.Error) bool) {
+		cur, err := aggregate(ctx, coll.Raw(), pipeline, opts)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer func() { _ = cur.Close(ctx) }()
+		for cur.Next(ctx) {
+			var r R
+			if e := cur.Decode(&r); e != nil {
+				yield(nil, errx.Wrap(e).AppendMsg("decode failed").Err())
+				return
+			}
+			if !yield(&r, nil) {
+				return
+			}
+		}
+		if e := cur.Err(); e != nil {
+			yield(nil, errx.Wrap(e).AppendMsg("aggregate failed").Err())
+		}
+	}
+}
+
+func aggregate(ctx context.Context, coll *mongo.Collection, pipeline mongo.Pipeline, opts []*AggregateOptions) (*mongo.Cursor, errx.Error) {
+	if pipeline == nil {
+		pipeline = mongo.Pipeline{}
+	}
+	opt := Aggregation()
+	if len(opts) > 0 && opts[0] != nil {
+		opt = opts[0]
+	}
+	if opt.ExplainVerbosity != nil && opt.ExplainHandler != nil {
+		explain(ctx, coll, pipeline, *opt.ExplainVerbosity, opt.ExplainHandler)
+	}
+	cur, err := coll.Aggregate(ctx, pipeline, opt.AggregateOptions)
+	if err != nil {
+		return nil, errx.Wrap(err).AppendMsg("aggregate failed").Err()
+	}
+	return cur, nil
+}
+
+func explain(ctx context.Context, coll *mongo.Collection, pipeline mongo.Pipeline, verbosity string, handler func(bson.M)) {
+	cmd := bson.D{
+		{Key: "explain", Value: bson.D{
+			{Key: "aggregate", Value: coll.Name()},
+			{Key: "pipeline", Value: pipeline},
+			{Key: "cursor", Value: bson.D{}},
+		}},
+		{Key: "verbosity", Value: verbosity},
+	}
+	var res bson.M
+	if err := coll.Database().RunCommand(ctx, cmd).Decode(&res); err != nil {
+		logrus.WithError(err).WithField("collection", coll.Name()).Warn("explain aggregate failed")
+		return
+	}
+	handler(res)
+}
+
+// MatchStage filters the documents of T.
+func MatchStage[T any](filter Filter[T]) bson.D {
+	return bson.D{{Key: "$match", Value: filter.nonNil()}}
+}
+
+// Accumulator is an output field of GroupStage, it is created by the Acc functions named after the
+// operators, e.g. AccSum for $sum.
+type Accumulator struct {
+	name string
+	expr bson.D
+}
+
+func AccSum(name string, expr any) Accumulator {
+	return Accumulator{name: name, expr: bson.D{{Key: "$sum", Value: expr}}}
+}
+
+func AccCount(name string) Accumulator {
+	return AccSum(name, 1)
+}
+
+func AccAvg(name string, expr any) Accumulator {
+	return Accumulator{name: name, expr: bson.D{{Key: "$avg", Value: expr}}}
+}
+
+func AccMin(name string, expr any) Accumulator {
+	return Accumulator{name: name, expr: bson.D{{Key: "$min", Value: expr}}}
+}
+
+func AccMax(name string, expr any) Accumulator {
+	return Accumulator{name: name, expr: bson.D{{Key: "$max", Value: expr}}}
+}
+
+func AccFirst(name string, expr any) Accumulator {
+	return Accumulator{name: name, expr: bson.D{{Key: "$first", Value: expr}}}
+}
+
+func AccLast(name string, expr any) Accumulator {
+	return Accumulator{name: name, expr: bson.D{{Key: "$last", Value: expr}}}
+}
+
+func AccPush(name string, expr any) Accumulator {
+	return Accumulator{name: name, expr: bson.D{{Key: "$push", Value: expr}}}
+}
+
+func AccAddToSet(name string, expr any) Accumulator {
+	return Accumulator{name: name, expr: bson.D{{Key: "$addToSet", Value: expr}}}
+}
+
+// GroupStage groups by id, every accumulator name must be a bson field of the result type R.
+func GroupStage[R any](id any, accumulators ...Accumulator) bson.D {
+	group := bson.D{{Key: "_id", Value: id}}
+	for _, a := range accumulators {
+		if !HasField[R](a.name) {
+			logrus.Panicf("group field %s not found in %s", a.name, entityTypeName[R]())
+		}
+		group = append(group, bson.E{Key: a.name, Value: a.expr})
+	}
+	return bson.D{{Key: "$group", Value: group}}
+}
+
+// LookupStage joins the documents of from whose foreignField equals localField into the array as of
+// the result type R, the fields are checked like NewField.
+func LookupStage[T, F, R, V, E any](from string, localField Field[T, V], foreignField Field[F, V], as ArrayField[R, E]) bson.D {
+	return bson.D{{Key: "$lookup", Value: bson.D{
+		{Key: "from", Value: from},
+		{Key: "localField", Value: localField.name},
+		{Key: "foreignField", Value: foreignField.name},
+		{Key: "as", Value: as.name},
+	}}}
+}
+
+// LookupPipelineStage runs pipeline on from for every document, as must be a bson field of the result type R.
+func LookupPipelineStage[R any](from string, let bson.D, pipeline mongo.Pipeline, as string) bson.D {
+	if !HasField[R](as) {
+		logrus.Panicf("lookup field %s not found in %s", as, entityTypeName[R]())
+	}
+	if pipeline == nil {
+		pipeline = mongo.Pipeline{}
+	}
+	lookup := bson.D{{Key: "from", Value: from}}
+	if len(let) > 0 {
+		lookup = append(lookup, bson.E{Key: "let", Value: let})
+	}
+	lookup = append(lookup, bson.E{Key: "pipeline", Value: pipeline}, bson.E{Key: "as", Value: as})
+	return bson.D{{Key: "$lookup", Value: lookup}}
+}
+
+type FacetPipeline struct {
+	Name     string
+	Pipeline mongo.Pipeline
+}
+
+// FacetStage runs the pipelines on the same input, every name must be a bson field of the result type R.
+func FacetStage[R any](facets ...FacetPipeline) bson.D {
+	facet := bson.D{}
+	for _, f := range facets {
+		if !HasField[R](f.Name) {
+			logrus.Panicf("facet field %s not found in %s", f.Name, entityTypeName[R]())
+		}
+		p := f.Pipeline
+		if p == nil {
+			p = mongo.Pipeline{}
+		}
+		facet = append(facet, bson.E{Key: f.Name, Value: p})
+	}
+	return bson.D{{Key: "$facet", Value: facet}}
+}
+
+// UnwindStage outputs a document for every element of the array field.
+func UnwindStage[T, E any](field ArrayField[T, E], preserveNullAndEmptyArrays bool) bson.D {
+	return bson.D{{Key: "$unwind", Value: bson.D{
+		{Key: "path", Value: "$" + field.name},
+		{Key: "preserveNullAndEmptyArrays", Value: preserveNullAndEmptyArrays},
+	}}}
+}
+
+// ProjectStage keeps the top level fields of R, computed lists extra expressions that
+// override or add fields, e.g. bson.E{Key: "total", Value: bson.M{"$size": "$items"}}.
+func ProjectStage[R any](computed ...bson.E) bson.D {
+	project := bson.D{}
+	index := make(map[string]int)
+	typ := reflect.TypeOf((*R)(nil)).Elem()
+	for _, f := range getValueFields(typ) {
+		if _, ok := fieldTypeByPath(typ, f.path); !ok {
+			continue
+		}
+		index[f.bsonKey] = len(project)
+		project = append(project, bson.E{Key: f.bsonKey, Value: 1})
+	}
+	if _, ok := index["_id"]; !ok {
+		index["_id"] = len(project)
+		project = append(project, bson.E{Key: "_id", Value: 0})
+	}
+	for _, e := range computed {
+		if i, ok := index[e.Key]; ok {
+			project[i].Value = e.Value
+			continue
+		}
+		project = append(project, e)
+	}
+	return bson.D{{Key: "$project", Value: project}}
+}
+
+func SortStage(sort bson.D) bson.D {
+	return bson.D{{Key: "$sort", Value: sort}}
+}
+
+func SkipStage(n int64) bson.D {
+	return bson.D{{Key: "$skip", Value: n}}
+}
+
+func LimitStage(n int64) bson.D {
+	return bson.D{{Key: "$limit", Value: n}}
+}
@@ -0,0 +1,82 @@
+package mongox
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tencent-go/pkg/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type aggregateBuyer struct {
+	types.Entity `bson:",inline"`
+	Name         string `bson:"name"`
+}
+
+type aggregateOrder struct {
+	types.Entity `bson:",inline"`
+	BuyerID      types.ID    `bson:"buyerId"`
+	Status       string      `bson:"status"`
+	Items        []queryItem `bson:"items"`
+}
+
+type aggregateReport struct {
+	Status string           `bson:"_id"`
+	Count  int64            `bson:"count"`
+	Total  int64            `bson:"total"`
+	Buyers []aggregateBuyer `bson:"buyers"`
+	Recent []aggregateOrder `bson:"recent"`
+}
+
+func TestStageBuilders(t *testing.T) {
+	status := NewField[aggregateOrder, string]("status")
+
+	t.Run("篩選及分組", func(t *testing.T) {
+		assert.Equal(t, bson.D{{Key: "$match", Value: status.Eq("paid")}}, MatchStage(status.Eq("paid")))
+		assert.Equal(t, bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$status"},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "total", Value: bson.D{{Key: "$sum", Value: "$items.count"}}},
+		}}}, GroupStage[aggregateReport]("$status", AccCount("count"), AccSum("total", "$items.count")))
+		assert.Panics(t, func() { GroupStage[aggregateReport]("$status", AccCount("missing")) })
+	})
+
+	t.Run("關聯", func(t *testing.T) {
+		buyerID := NewField[aggregateOrder, types.ID]("buyerId")
+		id := NewField[aggregateBuyer, types.ID]("_id")
+		buyers := NewArrayField[aggregateReport, aggregateBuyer]("buyers")
+		assert.Equal(t, bson.D{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "buyers"},
+			{Key: "localField", Value: "buyerId"},
+			{Key: "foreignField", Value: "_id"},
+			{Key: "as", Value: "buyers"},
+		}}}, LookupStage("buyers", buyerID, id, buyers))
+		assert.Equal(t, bson.D{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "buyers"},
+			{Key: "pipeline", Value: mongo.Pipeline{}},
+			{Key: "as", Value: "buyers"},
+		}}}, LookupPipelineStage[aggregateReport]("buyers", nil, nil, "buyers"))
+		assert.Panics(t, func() { LookupPipelineStage[aggregateReport]("buyers", nil, nil, "missing") })
+	})
+
+	t.Run("展開及分面", func(t *testing.T) {
+		items := NewArrayField[aggregateOrder, queryItem]("items")
+		assert.Equal(t, bson.D{{Key: "$unwind", Value: bson.D{
+			{Key: "path", Value: "$items"},
+			{Key: "preserveNullAndEmptyArrays", Value: true},
+		}}}, UnwindStage(items, true))
+		assert.Equal(t, bson.D{{Key: "$facet", Value: bson.D{
+			{Key: "recent", Value: mongo.Pipeline{LimitStage(5)}},
+		}}}, FacetStage[aggregateReport](FacetPipeline{Name: "recent", Pipeline: mongo.Pipeline{LimitStage(5)}}))
+		assert.Panics(t, func() { FacetStage[aggregateReport](FacetPipeline{Name: "missing"}) })
+	})
+
+	t.Run("投影", func(t *testing.T) {
+		stage := ProjectStage[aggregateReport](bson.E{Key: "count", Value: bson.M{"$size": "$items"}})
+		project := stage[0].Value.(bson.D)
+		assert.Contains(t, project, bson.E{Key: "_id", Value: 1})
+		assert.Contains(t, project, bson.E{Key: "count", Value: bson.M{"$size": "$items"}})
+		assert.Contains(t, project, bson.E{Key: "buyers", Value: 1})
+	})
+}
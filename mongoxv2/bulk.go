@@ -0,0 +1,330 @@
+package mongox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tencent-go/pkg/errx"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const defaultBatchSize = 1000
+
+var (
+	errBulkSkipped = errx.Define().WithMsg("bulk write skipped after a previous failure")
+	// 無法判斷更新是否生效, 呼叫方應先讀回再決定是否重試
+	errBulkUnverified = errx.Define().WithType(errx.TypeConcurrency).WithMsg("UpdateManyByID failed: the documents changed during the write, the outcome of the update is unknown.")
+)
+
+// BulkResult is the outcome of one document of a bulk call, Index is its position in the input list.
+type BulkResult struct {
+	Index   int
+	ID      any
+	Created bool
+	Err     errx.Error
+}
+
+type bulkItem struct {
+	index        int
+	id           any
+	model        mongo.WriteModel
+	insert       bool // created once written
+	requireMatch bool
+	version      *int64 // version expected after an optimistic update
+	done         func(created bool)
+}
+
+// CreateMany inserts list with the same defaults as Create.
+func (c *collectionImpl[T]) CreateMany(ctx context.Context, list []*T, opts ...*BulkOptions) ([]BulkResult, errx.Error) {
+	opt := getBulkOptions(opts)
+	return c.bulkWrite(ctx, len(list), opt, func(i int) (*bulkItem, errx.Error) {
+		entity := list[i]
+		if entity == nil {
+			return nil, errx.New("CreateMany: document is nil")
+		}
+		b, needSetID, err := c.createDocument(entity)
+		if err != nil {
+			return nil, err
+		}
+		return &bulkItem{
+			id:     b["_id"],
+			model:  mongo.NewInsertOneModel().SetDocument(b),
+			insert: true,
+			done: func(bool) {
+				c.afterCreate(entity, b, needSetID, b["_id"])
+			},
+		}, nil
+	})
+}
+
+// UpdateManyByID updates list with the same rules as UpdateByID. Documents that did not match,
+// because of a missing id or an optimistic lock conflict, are reported in their BulkResult and do not
+// stop the write, even in ordered mode. An error of TypeConcurrency means the outcome of the update is
+// unknown, the document should be read again before retrying.
+func (c *collectionImpl[T]) UpdateManyByID(ctx context.Context, list []*T, opts ...*BulkOptions) ([]BulkResult, errx.Error) {
+	opt := getBulkOptions(opts)
+	ignoreZeroValue := opt.IgnoreZeroValue == nil || *opt.IgnoreZeroValue
+	optimisticLock := opt.OptimisticLock == nil || *opt.OptimisticLock
+	return c.bulkWrite(ctx, len(list), opt, func(i int) (*bulkItem, errx.Error) {
+		data := list[i]
+		if data == nil {
+			return nil, errx.New("UpdateManyByID: document is nil")
+		}
+		filter, set, err := c.updateDocument("UpdateManyByID", data, ignoreZeroValue, optimisticLock)
+		if err != nil {
+			return nil, err
+		}
+		item := &bulkItem{
+			id:           filter["_id"],
+			model:        mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(bson.M{"$set": set}),
+			requireMatch: true,
+			done: func(bool) {
+				c.afterUpdate(data, set)
+			},
+		}
+		if _, ok := filter[c.VersionBsonField]; ok && c.VersionBsonField != "" {
+			v := set[c.VersionBsonField].(int64)
+			item.version = &v
+		}
+		return item, nil
+	})
+}
+
+// BulkUpsert creates or updates list with the same rules as CreateOrUpdateByID.
+func (c *collectionImpl[T]) BulkUpsert(ctx context.Context, list []*T, opts ...*BulkOptions) ([]BulkResult, errx.Error) {
+	opt := getBulkOptions(opts)
+	ignoreZeroValue := opt.IgnoreZeroValue == nil || *opt.IgnoreZeroValue
+	return c.bulkWrite(ctx, len(list), opt, func(i int) (*bulkItem, errx.Error) {
+		data := list[i]
+		if data == nil {
+			return nil, errx.New("BulkUpsert: document is nil")
+		}
+		filter, update, err := c.upsertDocument("BulkUpsert", data, ignoreZeroValue)
+		if err != nil {
+			return nil, err
+		}
+		return &bulkItem{
+			id:    filter["_id"],
+			model: mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(true),
+			done: func(created bool) {
+				c.afterUpsert(data, update, created)
+			},
+		}, nil
+	})
+}
+
+func getBulkOptions(opts []*BulkOptions) *BulkOptions {
+	if len(opts) > 0 && opts[0] != nil {
+		return opts[0]
+	}
+	return Bulk()
+}
+
+// bulkWrite prepares the n documents in order and writes them in batches. In ordered mode everything
+// after the first document that fails to prepare or to write is skipped, unmatched updates do not count
+// as failures.
+func (c *collectionImpl[T]) bulkWrite(ctx context.Context, n int, opt *BulkOptions, prepare func(i int) (*bulkItem, errx.Error)) ([]BulkResult, errx.Error) {
+	// 複製一份, 不修改呼叫方的options
+	bo := options.BulkWrite()
+	if opt.BulkWriteOptions != nil {
+		copied := *opt.BulkWriteOptions
+		bo = &copied
+	}
+	if bo.Ordered == nil {
+		bo.SetOrdered(true)
+	}
+	ordered := *bo.Ordered
+	batchSize := defaultBatchSize
+	if opt.BatchSize != nil && *opt.BatchSize > 0 {
+		batchSize = *opt.BatchSize
+	}
+	results := make([]BulkResult, n)
+	var (
+		chunk   []*bulkItem
+		stopped bool
+		lastErr errx.Error
+	)
+	flush := func() {
+		if len(chunk) == 0 {
+			return
+		}
+		failed, err := c.writeChunk(ctx, chunk, bo, results)
+		chunk = nil
+		if err != nil {
+			lastErr = err
+			stopped = true
+		} else if failed && ordered {
+			stopped = true
+		}
+	}
+	for i := 0; i < n; i++ {
+		results[i].Index = i
+		if stopped {
+			results[i].Err = errBulkSkipped.Err()
+			continue
+		}
+		item, err := prepare(i)
+		if err != nil {
+			results[i].Err = err
+			if ordered {
+				flush()
+				stopped = true
+			}
+			continue
+		}
+		item.index = i
+		results[i].ID = item.id
+		chunk = append(chunk, item)
+		if len(chunk) >= batchSize {
+			flush()
+		}
+	}
+	flush()
+	return results, lastErr
+}
+
+// writeChunk sends one batch and fills the results of its items, failed reports whether any write failed.
+func (c *collectionImpl[T]) writeChunk(ctx context.Context, chunk []*bulkItem, bo *options.BulkWriteOptions, results []BulkResult) (bool, errx.Error) {
+	models := make([]mongo.WriteModel, len(chunk))
+	for i, item := range chunk {
+		models[i] = item.model
+	}
+	ordered := *bo.Ordered
+	res, e := c.BulkWrite(ctx, models, bo)
+	writeErrors := make(map[int]errx.Error)
+	executed := len(chunk)
+	if e != nil {
+		var bwe mongo.BulkWriteException
+		if !errors.As(e, &bwe) || bwe.WriteConcernError != nil || len(bwe.WriteErrors) == 0 {
+			err := errx.Wrap(e).AppendMsg("bulk write failed").Err()
+			for _, item := range chunk {
+				results[item.index].Err = err
+			}
+			return true, err
+		}
+		for _, we := range bwe.WriteErrors {
+			b := errx.Wrap(we.WriteError)
+			if mongo.IsDuplicateKeyError(we.WriteError) {
+				b = b.WithType(errx.TypeConflict)
+			}
+			writeErrors[we.Index] = b.AppendMsg("bulk write failed").Err()
+			if ordered && we.Index+1 < executed {
+				executed = we.Index + 1
+			}
+		}
+	}
+
+	var checks []*bulkItem
+	for i, item := range chunk {
+		if i >= executed {
+			results[item.index].Err = errBulkSkipped.Err()
+		} else if err, ok := writeErrors[i]; ok {
+			results[item.index].Err = err
+		} else if item.requireMatch {
+			checks = append(checks, item)
+		}
+	}
+	failed := len(writeErrors) > 0
+	if len(checks) > 0 && res != nil && res.MatchedCount < int64(len(checks)) {
+		if err := c.checkUnmatched(ctx, checks, res.MatchedCount, results); err != nil {
+			return true, err
+		}
+	}
+
+	for i, item := range chunk {
+		if i >= executed || results[item.index].Err != nil {
+			continue
+		}
+		if item.insert {
+			results[item.index].Created = true
+		} else if res != nil {
+			_, results[item.index].Created = res.UpsertedIDs[int64(i)]
+		}
+		if item.done != nil {
+			item.done(results[item.index].Created)
+		}
+	}
+	return failed, nil
+}
+
+// checkUnmatched finds the updates that matched nothing, BulkWrite only reports the total matched count.
+// The documents are read back to tell a missing id from an optimistic lock conflict, the read is only
+// trusted when it accounts for exactly matched updates; otherwise a concurrent write changed the documents
+// in between, none of the updates can be attributed and they are all reported with an unknown outcome.
+func (c *collectionImpl[T]) checkUnmatched(ctx context.Context, checks []*bulkItem, matched int64, results []BulkResult) errx.Error {
+	ids := make(bson.A, 0, len(checks))
+	for _, item := range checks {
+		ids = append(ids, item.id)
+	}
+	projection := bson.M{"_id": 1}
+	if c.VersionBsonField != "" {
+		projection[c.VersionBsonField] = 1
+	}
+	cur, e := c.Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, options.Find().SetProjection(projection))
+	if e != nil {
+		err := errx.Wrap(e).AppendMsg("bulk write check failed").Err()
+		for _, item := range checks {
+			results[item.index].Err = err
+		}
+		return err
+	}
+	var docs []bson.M
+	if e = cur.All(ctx, &docs); e != nil {
+		err := errx.Wrap(e).AppendMsg("bulk write check failed").Err()
+		for _, item := range checks {
+			results[item.index].Err = err
+		}
+		return err
+	}
+	unmatched := attributeUnmatched(checks, docs, c.VersionBsonField)
+	if int64(len(checks)-len(unmatched)) != matched {
+		for _, item := range checks {
+			results[item.index].Err = errBulkUnverified.Err()
+		}
+		return nil
+	}
+	for i, err := range unmatched {
+		results[checks[i].index].Err = err
+	}
+	return nil
+}
+
+// attributeUnmatched returns the error of every update of checks whose document is missing from docs
+// or holds another version than the update wrote, keyed by the position in checks.
+func attributeUnmatched(checks []*bulkItem, docs []bson.M, versionField string) map[int]errx.Error {
+	// 解碼後的id型別可能不同, 例如types.ID讀回為int64
+	found := make(map[string]bson.M, len(docs))
+	for _, d := range docs {
+		found[fmt.Sprint(d["_id"])] = d
+	}
+	unmatched := make(map[int]errx.Error)
+	for i, item := range checks {
+		d, ok := found[fmt.Sprint(item.id)]
+		if !ok {
+			unmatched[i] = errx.NotFound.WithMsg("UpdateManyByID failed: data not found").Err()
+			continue
+		}
+		if item.version == nil {
+			continue
+		}
+		if v, ok := toInt64(d[versionField]); !ok || v != *item.version {
+			unmatched[i] = errx.Conflict.WithMsg("UpdateManyByID failed due to optimistic lock conflict.").Err()
+		}
+	}
+	return unmatched
+}
+
+func toInt64(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case int32:
+		return int64(n), true
+	case float64:
+		return int64(n), n == float64(int64(n))
+	}
+	return 0, false
+}
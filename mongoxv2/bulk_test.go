@@ -0,0 +1,138 @@
+package mongox
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tencent-go/pkg/errx"
+	"github.com/tencent-go/pkg/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+type bulkEntity struct {
+	types.Entity `bson:",inline"`
+	Name         string `bson:"name"`
+}
+
+func bulkCollection(mt *mtest.T) Collection[bulkEntity] {
+	return Repo[bulkEntity]().
+		WithClient(func() *mongo.Client { return mt.Client }).
+		WithDatabase("test").
+		WithCollectionName("bulk").
+		Collection()
+}
+
+func bulkEntities(versions ...int64) []*bulkEntity {
+	list := make([]*bulkEntity, len(versions))
+	for i, v := range versions {
+		list[i] = &bulkEntity{Entity: types.Entity{ID: types.ID(i + 1), Version: v}, Name: "n"}
+	}
+	return list
+}
+
+func TestBulkWrite(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("不修改呼叫方的options", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 2}))
+		opt := Bulk().SetBatchSize(10)
+		res, err := bulkCollection(mt).CreateMany(context.Background(), bulkEntities(0, 0), opt)
+		assert.NoError(mt, err)
+		if assert.Len(mt, res, 2) {
+			assert.True(mt, res[0].Created)
+			assert.True(mt, res[1].Created)
+		}
+		assert.True(mt, *opt.Ordered)
+		assert.Nil(mt, opt.IgnoreZeroValue)
+
+		opt = Bulk().SetOrdered(false)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(
+			bson.E{Key: "n", Value: 2}, bson.E{Key: "nModified", Value: 2},
+		))
+		_, err = bulkCollection(mt).UpdateManyByID(context.Background(), bulkEntities(1, 1), opt)
+		assert.NoError(mt, err)
+		assert.False(mt, *opt.Ordered)
+		assert.Nil(mt, opt.IgnoreZeroValue)
+		assert.Nil(mt, opt.OptimisticLock)
+		started := mt.GetStartedEvent()
+		for started != nil && started.CommandName != "update" {
+			started = mt.GetStartedEvent()
+		}
+		if assert.NotNil(mt, started) {
+			assert.False(mt, started.Command.Lookup("ordered").Boolean())
+		}
+	})
+
+	mt.Run("順序寫入失敗後略過", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 1, Code: 11000, Message: "duplicate key"}))
+		res, err := bulkCollection(mt).CreateMany(context.Background(), bulkEntities(0, 0, 0, 0), Bulk().SetBatchSize(3))
+		assert.NoError(mt, err)
+		assert.NoError(mt, res[0].Err)
+		assert.True(mt, res[0].Created)
+		if assert.Error(mt, res[1].Err) {
+			assert.Equal(mt, errx.TypeConflict, res[1].Err.Type())
+		}
+		assert.False(mt, res[1].Created)
+		assert.Equal(mt, errBulkSkipped.Err().Error(), res[2].Err.Error())
+		assert.Equal(mt, errBulkSkipped.Err().Error(), res[3].Err.Error())
+	})
+
+	mt.Run("未匹配不中斷順序寫入", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateCursorResponse(0, "test.bulk", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: int64(1)}, {Key: "version", Value: int64(2)}},
+				bson.D{{Key: "_id", Value: int64(2)}, {Key: "version", Value: int64(5)}},
+			),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+		list := bulkEntities(1, 1, 1)
+		res, err := bulkCollection(mt).UpdateManyByID(context.Background(), list, Bulk().SetBatchSize(2))
+		assert.NoError(mt, err)
+		assert.NoError(mt, res[0].Err)
+		if assert.Error(mt, res[1].Err) {
+			assert.Equal(mt, errx.TypeConflict, res[1].Err.Type())
+		}
+		assert.NoError(mt, res[2].Err)
+		assert.Equal(mt, int64(2), list[0].Version)
+		assert.Equal(mt, int64(1), list[1].Version)
+		assert.Equal(mt, int64(2), list[2].Version)
+	})
+
+	mt.Run("文件不存在", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateCursorResponse(0, "test.bulk", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: int64(2)}, {Key: "version", Value: int64(2)}},
+			),
+		)
+		res, err := bulkCollection(mt).UpdateManyByID(context.Background(), bulkEntities(1, 1))
+		assert.NoError(mt, err)
+		if assert.Error(mt, res[0].Err) {
+			assert.Equal(mt, errx.TypeNotFound, res[0].Err.Type())
+		}
+		assert.NoError(mt, res[1].Err)
+	})
+
+	mt.Run("讀回結果與匹配數不符", func(mt *mtest.T) {
+		// 另一個寫入者在寫入與讀回之間也把版本更新為2
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateCursorResponse(0, "test.bulk", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: int64(1)}, {Key: "version", Value: int64(2)}},
+				bson.D{{Key: "_id", Value: int64(2)}, {Key: "version", Value: int64(2)}},
+			),
+		)
+		res, err := bulkCollection(mt).UpdateManyByID(context.Background(), bulkEntities(1, 1))
+		assert.NoError(mt, err)
+		// 無法歸屬, 結果未知而非衝突, 以免呼叫方重試已生效的更新
+		for _, r := range res {
+			if assert.Error(mt, r.Err) {
+				assert.Equal(mt, errx.TypeConcurrency, r.Err.Type())
+			}
+		}
+	})
+}
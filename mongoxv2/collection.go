@@ -6,6 +6,7 @@ import (
 
 	"github.com/tencent-go/pkg/ctxx"
 	"github.com/tencent-go/pkg/errx"
+	"github.com/tencent-go/pkg/types"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -23,6 +24,10 @@ type Collection[T any] interface {
 	GetAndCreateOrUpdateByID(ctx context.Context, data *T, opts ...*FindOneAndUpdateOptions) errx.Error
 	DeleteByID(ctx context.Context, id any, opts ...*options.DeleteOptions) errx.Error
 	CountDocuments(ctx context.Context, filter any, opts ...*options.CountOptions) (*int64, errx.Error)
+	CreateMany(ctx context.Context, list []*T, opts ...*BulkOptions) ([]BulkResult, errx.Error)
+	UpdateManyByID(ctx context.Context, list []*T, opts ...*BulkOptions) ([]BulkResult, errx.Error)
+	BulkUpsert(ctx context.Context, list []*T, opts ...*BulkOptions) ([]BulkResult, errx.Error)
+	GetCursorPage(ctx context.Context, filter any, query types.CursorQuery[string], sorts ...types.Sortable) (*CursorPage[T], errx.Error)
 	Watch(ctx context.Context, pipeline interface{}, cb func(ctx ctxx.Context, ev ChangeEventWithDoc[T]) errx.Error, opts ...*ChangeStreamOptions) (func(), errx.Error)
 }
 
@@ -83,9 +88,113 @@ func (c *collectionImpl[T]) GetList(ctx context.Context, filter any, opts ...*op
 }
 
 func (c *collectionImpl[T]) Create(ctx context.Context, entity *T, opts ...*options.InsertOneOptions) errx.Error {
+	b, needSetID, err := c.createDocument(entity)
+	if err != nil {
+		return err
+	}
+	res, e := c.InsertOne(ctx, b, opts...)
+	if e != nil {
+		return errx.Wrap(e).AppendMsg("create failed").Err()
+	}
+	c.afterCreate(entity, b, needSetID, res.InsertedID)
+	return nil
+}
+
+func (c *collectionImpl[T]) UpdateByID(ctx context.Context, data *T, expandedOpts ...*UpdateOptions) errx.Error {
+	var opt *UpdateOptions
+	if len(expandedOpts) > 0 {
+		opt = expandedOpts[0]
+	} else {
+		opt = Update()
+	}
+	if opt.IgnoreZeroValue == nil {
+		opt.SetIgnoreZeroValue(true)
+	}
+	ignoreZeroValue := *opt.IgnoreZeroValue
+	if opt.OptimisticLock == nil {
+		opt.SetOptimisticLock(true)
+	}
+	optimisticLock := *opt.OptimisticLock
+	if opt.Upsert != nil && *opt.Upsert {
+		return errx.New("UpdateByID: upsert is not supported")
+	}
+	filter, set, err := c.updateDocument("UpdateByID", data, ignoreZeroValue, optimisticLock)
+	if err != nil {
+		return err
+	}
+
+	res, e := c.UpdateOne(ctx, filter, bson.M{"$set": set}, opt.UpdateOptions)
+	if e != nil {
+		return errx.Wrap(e).AppendMsg("UpdateByID failed").Err()
+	}
+	if res.MatchedCount == 0 {
+		return errx.Conflict.WithMsg("UpdateByID failed due to optimistic lock conflict.").Err()
+	}
+	c.afterUpdate(data, set)
+	return nil
+}
+
+func (c *collectionImpl[T]) CreateOrUpdateByID(ctx context.Context, data *T, expandedOpts ...*UpdateOptions) (bool, errx.Error) {
+	var opt *UpdateOptions
+	if len(expandedOpts) > 0 {
+		opt = expandedOpts[0]
+	} else {
+		opt = Update()
+	}
+	if opt.IgnoreZeroValue == nil {
+		opt.SetIgnoreZeroValue(true)
+	}
+	ignoreZeroValue := *opt.IgnoreZeroValue
+	opt.SetUpsert(true)
+	filter, update, err := c.upsertDocument("CreateOrUpdateByID", data, ignoreZeroValue)
+	if err != nil {
+		return false, err
+	}
+
+	res, e := c.UpdateOne(ctx, filter, update, opt.UpdateOptions)
+	if e != nil {
+		return false, errx.Wrap(e).AppendMsg("CreateOrUpdateByID failed").Err()
+	}
+
+	if res.ModifiedCount == 0 && res.UpsertedCount == 0 {
+		return false, errx.New("CreateOrUpdateByID failed: no data is modified or created")
+	}
+
+	isNew := res.UpsertedCount > 0
+	c.afterUpsert(data, update, isNew)
+	return isNew, nil
+}
+
+func (c *collectionImpl[T]) GetAndCreateOrUpdateByID(ctx context.Context, data *T, expandedOpts ...*FindOneAndUpdateOptions) errx.Error {
+	var otp *FindOneAndUpdateOptions
+	if len(expandedOpts) > 0 {
+		otp = expandedOpts[0]
+	} else {
+		otp = FindOneAndUpdate()
+	}
+	if otp.IgnoreZeroValue == nil {
+		otp.SetIgnoreZeroValue(true)
+	}
+	ignoreZeroValue := *otp.IgnoreZeroValue
+	otp.SetUpsert(true)
+
+	filter, update, err := c.upsertDocument("GetAndCreateOrUpdateByID", data, ignoreZeroValue)
+	if err != nil {
+		return err
+	}
+
+	e := c.Collection.FindOneAndUpdate(ctx, filter, update, otp.FindOneAndUpdateOptions).Decode(data)
+	if e != nil {
+		return errx.Wrap(e).AppendMsg("GetAndCreateOrUpdateByID failed").Err()
+	}
+	return nil
+}
+
+// createDocument converts entity into the inserted document, filling the id, createdAt, updatedAt and version.
+func (c *collectionImpl[T]) createDocument(entity *T) (bson.M, bool, errx.Error) {
 	b := bson.M{}
 	if err := c.BsonParser(entity, &b, false); err != nil {
-		return err
+		return nil, false, err
 	}
 	var needSetID bool
 	{
@@ -108,12 +217,12 @@ func (c *collectionImpl[T]) Create(ctx context.Context, entity *T, opts ...*opti
 	if c.VersionBsonField != "" {
 		b[c.VersionBsonField] = int64(1)
 	}
-	res, err := c.InsertOne(ctx, b, opts...)
-	if err != nil {
-		return errx.Wrap(err).AppendMsg("create failed").Err()
-	}
+	return b, needSetID, nil
+}
+
+func (c *collectionImpl[T]) afterCreate(entity *T, b bson.M, needSetID bool, insertedID any) {
 	if needSetID && c.IdSetter != nil {
-		c.IdSetter(entity, res.InsertedID)
+		c.IdSetter(entity, insertedID)
 	}
 	if c.CreatedAtBsonField != "" && c.CreatedAtSetter != nil {
 		c.CreatedAtSetter(entity, b[c.CreatedAtBsonField])
@@ -124,37 +233,21 @@ func (c *collectionImpl[T]) Create(ctx context.Context, entity *T, opts ...*opti
 	if c.VersionBsonField != "" && c.VersionSetter != nil {
 		c.VersionSetter(entity, 1)
 	}
-	return nil
 }
 
-func (c *collectionImpl[T]) UpdateByID(ctx context.Context, data *T, expandedOpts ...*UpdateOptions) errx.Error {
-	var opt *UpdateOptions
-	if len(expandedOpts) > 0 {
-		opt = expandedOpts[0]
-	} else {
-		opt = Update()
-	}
-	if opt.IgnoreZeroValue == nil {
-		opt.SetIgnoreZeroValue(true)
-	}
-	ignoreZeroValue := *opt.IgnoreZeroValue
-	if opt.OptimisticLock == nil {
-		opt.SetOptimisticLock(true)
-	}
-	optimisticLock := *opt.OptimisticLock
-	if opt.Upsert != nil && *opt.Upsert {
-		return errx.New("UpdateByID: upsert is not supported")
-	}
+// updateDocument builds the filter and $set document of UpdateByID, the filter contains the current
+// version when optimisticLock is on and the version in set is increased.
+func (c *collectionImpl[T]) updateDocument(op string, data *T, ignoreZeroValue bool, optimisticLock bool) (bson.M, bson.M, errx.Error) {
 	var filter, set = bson.M{}, bson.M{}
 	if err := c.BsonParser(data, &set, ignoreZeroValue); err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	// id
 	{
 		id, ok := set["_id"]
 		if !ok || isZeroID(id, c.IDType) {
-			return errx.New("UpdateByID: ID is empty")
+			return nil, nil, errx.Newf("%s: ID is empty", op)
 		}
 		delete(set, "_id")
 		filter["_id"] = id
@@ -165,12 +258,12 @@ func (c *collectionImpl[T]) UpdateByID(ctx context.Context, data *T, expandedOpt
 		version, exists := set[c.VersionBsonField]
 		if !exists {
 			if optimisticLock {
-				return errx.New("UpdateByID with optimistic lock: the version is missing.")
+				return nil, nil, errx.Newf("%s with optimistic lock: the version is missing.", op)
 			}
 		} else {
 			v, ok := version.(int64)
 			if !ok {
-				return errx.New("UpdateByID: the version must be int64.")
+				return nil, nil, errx.Newf("%s: the version must be int64.", op)
 			}
 			if optimisticLock {
 				filter[c.VersionBsonField] = v
@@ -184,45 +277,32 @@ func (c *collectionImpl[T]) UpdateByID(ctx context.Context, data *T, expandedOpt
 	if c.UpdatedAtBsonField != "" {
 		set[c.UpdatedAtBsonField] = createCurrentTime(c.TimeType)
 	}
+	return filter, set, nil
+}
 
-	res, err := c.UpdateOne(ctx, filter, bson.M{"$set": set}, opt.UpdateOptions)
-	if err != nil {
-		return errx.Wrap(err).AppendMsg("UpdateByID failed").Err()
-	}
-	if res.MatchedCount == 0 {
-		return errx.Conflict.WithMsg("UpdateByID failed due to optimistic lock conflict.").Err()
-	}
+func (c *collectionImpl[T]) afterUpdate(data *T, set bson.M) {
 	if c.VersionBsonField != "" && c.VersionSetter != nil {
-		c.VersionSetter(data, set[c.VersionBsonField].(int64))
+		if v, ok := set[c.VersionBsonField].(int64); ok {
+			c.VersionSetter(data, v)
+		}
 	}
 	if c.UpdatedAtBsonField != "" && c.UpdatedAtSetter != nil {
 		c.UpdatedAtSetter(data, set[c.UpdatedAtBsonField])
 	}
-	return nil
 }
 
-func (c *collectionImpl[T]) CreateOrUpdateByID(ctx context.Context, data *T, expandedOpts ...*UpdateOptions) (bool, errx.Error) {
-	var opt *UpdateOptions
-	if len(expandedOpts) > 0 {
-		opt = expandedOpts[0]
-	} else {
-		opt = Update()
-	}
-	if opt.IgnoreZeroValue == nil {
-		opt.SetIgnoreZeroValue(true)
-	}
-	ignoreZeroValue := *opt.IgnoreZeroValue
-	opt.SetUpsert(true)
+// upsertDocument builds the filter and update document of CreateOrUpdateByID.
+func (c *collectionImpl[T]) upsertDocument(op string, data *T, ignoreZeroValue bool) (bson.M, bson.M, errx.Error) {
 	var filter, set, setOnInsert = bson.M{}, bson.M{}, bson.M{}
 	if err := c.BsonParser(data, &set, ignoreZeroValue); err != nil {
-		return false, err
+		return nil, nil, err
 	}
 
 	// id
 	{
 		id, ok := set["_id"]
 		if !ok || isZeroID(id, c.IDType) {
-			return false, errx.New("CreateOrUpdateByID: ID is empty")
+			return nil, nil, errx.Newf("%s: ID is empty", op)
 		}
 		delete(set, "_id")
 		filter["_id"] = id
@@ -249,17 +329,11 @@ func (c *collectionImpl[T]) CreateOrUpdateByID(ctx context.Context, data *T, exp
 		delete(set, c.VersionBsonField)
 		update["$inc"] = bson.M{c.VersionBsonField: int64(1)}
 	}
+	return filter, update, nil
+}
 
-	res, err := c.UpdateOne(ctx, filter, update, opt.UpdateOptions)
-	if err != nil {
-		return false, errx.Wrap(err).AppendMsg("CreateOrUpdateByID failed").Err()
-	}
-
-	if res.ModifiedCount == 0 && res.UpsertedCount == 0 {
-		return false, errx.New("CreateOrUpdateByID failed: no data is modified or created")
-	}
-
-	isNew := res.UpsertedCount > 0
+func (c *collectionImpl[T]) afterUpsert(data *T, update bson.M, isNew bool) {
+	set, setOnInsert := update["$set"].(bson.M), update["$setOnInsert"].(bson.M)
 	if isNew {
 		if c.CreatedAtBsonField != "" && c.CreatedAtSetter != nil {
 			c.CreatedAtSetter(data, setOnInsert[c.CreatedAtBsonField])
@@ -267,72 +341,10 @@ func (c *collectionImpl[T]) CreateOrUpdateByID(ctx context.Context, data *T, exp
 		if c.VersionBsonField != "" && c.VersionSetter != nil {
 			c.VersionSetter(data, int64(1))
 		}
-		if c.UpdatedAtBsonField != "" && c.UpdatedAtSetter != nil {
-			c.UpdatedAtSetter(data, set[c.UpdatedAtBsonField])
-		}
-	} else {
-		if c.UpdatedAtBsonField != "" && c.UpdatedAtSetter != nil {
-			c.UpdatedAtSetter(data, set[c.UpdatedAtBsonField])
-		}
-	}
-	return isNew, nil
-}
-
-func (c *collectionImpl[T]) GetAndCreateOrUpdateByID(ctx context.Context, data *T, expandedOpts ...*FindOneAndUpdateOptions) errx.Error {
-	var otp *FindOneAndUpdateOptions
-	if len(expandedOpts) > 0 {
-		otp = expandedOpts[0]
-	} else {
-		otp = FindOneAndUpdate()
-	}
-	if otp.IgnoreZeroValue == nil {
-		otp.SetIgnoreZeroValue(true)
-	}
-	ignoreZeroValue := *otp.IgnoreZeroValue
-	otp.SetUpsert(true)
-
-	var filter, set, setOnInsert = bson.M{}, bson.M{}, bson.M{}
-	if err := c.BsonParser(data, &set, ignoreZeroValue); err != nil {
-		return err
-	}
-
-	// id
-	{
-		id, ok := set["_id"]
-		if !ok || isZeroID(id, c.IDType) {
-			return errx.New("GetAndCreateOrUpdateByID: ID is empty")
-		}
-		delete(set, "_id")
-		filter["_id"] = id
-		setOnInsert["_id"] = id
 	}
-
-	// updatedAt
-	if c.UpdatedAtBsonField != "" {
-		set[c.UpdatedAtBsonField] = createCurrentTime(c.TimeType)
-	}
-
-	// createdAt
-	if c.CreatedAtBsonField != "" {
-		setOnInsert[c.CreatedAtBsonField] = createCurrentTime(c.TimeType)
-		delete(set, c.CreatedAtBsonField)
-	}
-
-	update := bson.M{
-		"$set":         set,
-		"$setOnInsert": setOnInsert,
-	}
-
-	if c.VersionBsonField != "" {
-		update["$inc"] = bson.M{c.VersionBsonField: int64(1)}
-		delete(set, c.VersionBsonField)
-	}
-
-	err := c.Collection.FindOneAndUpdate(ctx, filter, update, otp.FindOneAndUpdateOptions).Decode(data)
-	if err != nil {
-		return errx.Wrap(err).AppendMsg("GetAndCreateOrUpdateByID failed").Err()
+	if c.UpdatedAtBsonField != "" && c.UpdatedAtSetter != nil {
+		c.UpdatedAtSetter(data, set[c.UpdatedAtBsonField])
 	}
-	return nil
 }
 
 func (c *collectionImpl[T]) DeleteByID(ctx context.Context, id any, opts ...*options.DeleteOptions) errx.Error {
@@ -0,0 +1,225 @@
+package mongox
+
+import (
+	"context"
+	"encoding/base64"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/tencent-go/pkg/errx"
+	"github.com/tencent-go/pkg/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const defaultCursorLimit int64 = 10
+
+type CursorPage[T any] = types.CursorQueryResult[types.CursorQuery[string], T]
+
+// GetCursorPage pages through the documents matching filter ordered by sorts, with "_id" appended
+// as the tie-breaker. The returned Cursor continues forwards after the last item and PrevCursor
+// goes backwards before the first item, both are opaque strings bound to the sort keys.
+func (c *collectionImpl[T]) GetCursorPage(ctx context.Context, filter any, query types.CursorQuery[string], sorts ...types.Sortable) (*CursorPage[T], errx.Error) {
+	keys, err := getCursorSortKeys[T](sorts)
+	if err != nil {
+		return nil, err
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultCursorLimit
+	}
+	if limit > defaultLimit {
+		limit = defaultLimit
+	}
+
+	var token *cursorToken
+	if query.Cursor != "" {
+		if token, err = decodeCursor(query.Cursor, keys); err != nil {
+			return nil, err
+		}
+	}
+	backward := token != nil && token.Backward
+
+	var conditions bson.A
+	if filter != nil {
+		conditions = append(conditions, filter)
+	}
+	if token != nil {
+		conditions = append(conditions, cursorFilter(keys, token.Values, backward))
+	}
+	var f any = bson.M{}
+	if len(conditions) == 1 {
+		f = conditions[0]
+	} else if len(conditions) > 1 {
+		f = bson.M{"$and": conditions}
+	}
+	sort := bson.D{}
+	for _, k := range keys {
+		order := k.order
+		if backward {
+			order = -order
+		}
+		sort = append(sort, bson.E{Key: k.name, Value: order})
+	}
+
+	cur, e := c.Find(ctx, f, options.Find().SetSort(sort).SetLimit(limit+1))
+	if e != nil {
+		return nil, errx.Wrap(e).AppendMsg("find failed").Err()
+	}
+	defer func() { _ = cur.Close(ctx) }()
+	var list []T
+	var raws []bson.Raw
+	for cur.Next(ctx) {
+		var t T
+		if e = cur.Decode(&t); e != nil {
+			return nil, errx.Wrap(e).AppendMsg("decode failed").Err()
+		}
+		list = append(list, t)
+		raws = append(raws, slices.Clone(cur.Current))
+	}
+	if e = cur.Err(); e != nil {
+		return nil, errx.Wrap(e).AppendMsg("find failed").Err()
+	}
+
+	hasMore := int64(len(list)) > limit
+	if hasMore {
+		list, raws = list[:limit], raws[:limit]
+	}
+	if backward {
+		slices.Reverse(list)
+		slices.Reverse(raws)
+	}
+	if list == nil {
+		list = []T{}
+	}
+	res := &CursorPage[T]{List: list, Query: query, HasMore: hasMore}
+	if len(raws) == 0 {
+		return res, nil
+	}
+	if !backward && hasMore || backward {
+		if res.Cursor, err = encodeCursor(keys, raws[len(raws)-1], false); err != nil {
+			return nil, err
+		}
+	}
+	if backward && hasMore || !backward && token != nil {
+		if res.PrevCursor, err = encodeCursor(keys, raws[0], true); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+type cursorSortKey struct {
+	name  string
+	order int
+}
+
+func (k cursorSortKey) String() string {
+	if k.order < 0 {
+		return "-" + k.name
+	}
+	return k.name
+}
+
+func getCursorSortKeys[T any](sorts []types.Sortable) ([]cursorSortKey, errx.Error) {
+	fields := getFieldTypes(reflect.TypeOf((*T)(nil)).Elem())
+	var keys []cursorSortKey
+	order := -1
+	for _, s := range sorts {
+		if s == nil || s.GetSort() == "" {
+			continue
+		}
+		if _, ok := fields[s.GetSort()]; !ok {
+			return nil, errx.Validation.WithMsgf("invalid sort field %s", s.GetSort()).Err()
+		}
+		order = 1
+		if s.GetDirection() == types.DirectionDesc {
+			order = -1
+		}
+		if s.GetSort() == "_id" {
+			break
+		}
+		keys = append(keys, cursorSortKey{name: s.GetSort(), order: order})
+	}
+	return append(keys, cursorSortKey{name: "_id", order: order}), nil
+}
+
+// cursorFilter matches the documents after values in the given order, e.g. for (a asc, _id asc):
+// {$or: [{a: {$gt: va}}, {a: va, _id: {$gt: vid}}]}
+// Null and missing values sort before any other value, a null va becomes {a: {$ne: null}} after it and
+// matches nothing before it, while {a: {$lt: va}} is widened with {a: null} except for _id, since comparison operators
+// never match null across types.
+func cursorFilter(keys []cursorSortKey, values bson.A, backward bool) bson.D {
+	or := bson.A{}
+	for i, k := range keys {
+		cond := bson.D{}
+		for j := 0; j < i; j++ {
+			cond = append(cond, bson.E{Key: keys[j].name, Value: values[j]})
+		}
+		after := (k.order < 0) == backward
+		switch {
+		case values[i] == nil && after:
+			cond = append(cond, bson.E{Key: k.name, Value: bson.D{{Key: "$ne", Value: nil}}})
+		case values[i] == nil:
+			continue
+		case after:
+			cond = append(cond, bson.E{Key: k.name, Value: bson.D{{Key: "$gt", Value: values[i]}}})
+		case k.name == "_id":
+			cond = append(cond, bson.E{Key: k.name, Value: bson.D{{Key: "$lt", Value: values[i]}}})
+		default:
+			cond = append(cond, bson.E{Key: "$or", Value: bson.A{
+				bson.D{{Key: k.name, Value: bson.D{{Key: "$lt", Value: values[i]}}}},
+				bson.D{{Key: k.name, Value: nil}},
+			}})
+		}
+		or = append(or, cond)
+	}
+	return bson.D{{Key: "$or", Value: or}}
+}
+
+type cursorToken struct {
+	Sort     string `bson:"s"`
+	Values   bson.A `bson:"v"`
+	Backward bool   `bson:"b,omitempty"`
+}
+
+func sortKeysString(keys []cursorSortKey) string {
+	arr := make([]string, len(keys))
+	for i, k := range keys {
+		arr[i] = k.String()
+	}
+	return strings.Join(arr, ",")
+}
+
+func encodeCursor(keys []cursorSortKey, doc bson.Raw, backward bool) (string, errx.Error) {
+	token := cursorToken{Sort: sortKeysString(keys), Backward: backward}
+	for _, k := range keys {
+		v, err := doc.LookupErr(strings.Split(k.name, ".")...)
+		if err != nil || v.Type == bson.TypeNull || v.Type == bson.TypeUndefined {
+			token.Values = append(token.Values, nil)
+			continue
+		}
+		token.Values = append(token.Values, v)
+	}
+	data, err := bson.Marshal(token)
+	if err != nil {
+		return "", errx.Wrap(err).AppendMsg("encode cursor failed").Err()
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(s string, keys []cursorSortKey) (*cursorToken, errx.Error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errx.Validation.WithMsg("invalid cursor").Err()
+	}
+	var token cursorToken
+	if err = bson.Unmarshal(data, &token); err != nil {
+		return nil, errx.Validation.WithMsg("invalid cursor").Err()
+	}
+	if token.Sort != sortKeysString(keys) || len(token.Values) != len(keys) {
+		return nil, errx.Validation.WithMsg("cursor does not match the sort options").Err()
+	}
+	return &token, nil
+}
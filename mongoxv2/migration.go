@@ -0,0 +1,457 @@
+package mongox
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tencent-go/pkg/ctxx"
+	"github.com/tencent-go/pkg/errx"
+	"github.com/tencent-go/pkg/initialization"
+	"github.com/tencent-go/pkg/keylocker"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const defaultMigrationCollection = "_migrations"
+
+// Migration is one numbered step of a database. Up and Down are database commands run in order,
+// UpFunc and DownFunc run after them for changes that need code.
+//
+// An applied migration is detected as modified by its checksum, which is computed from Version, Name,
+// Up and Down only. UpFunc and DownFunc are not part of it: set Checksum explicitly for a migration
+// with code and change it whenever the code changes.
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       []bson.D
+	Down     []bson.D
+	UpFunc   func(ctx context.Context, db *mongo.Database) errx.Error
+	DownFunc func(ctx context.Context, db *mongo.Database) errx.Error
+	// Checksum replaces the computed checksum, it is required to detect changes of UpFunc and DownFunc.
+	Checksum string
+}
+
+type MigrationStatus struct {
+	Version   int64  `json:"version"`
+	Name      string `json:"name"`
+	Applied   bool   `json:"applied"`
+	AppliedAt int64  `json:"appliedAt,omitempty"`
+	Modified  bool   `json:"modified,omitempty"` // 已執行後被修改
+	Missing   bool   `json:"missing,omitempty"`  // 已執行但未定義
+}
+
+type Migrator interface {
+	WithDatabase(string) Migrator
+	WithClient(func() *mongo.Client) Migrator
+	WithCollectionName(string) Migrator
+	WithLocker(func(key string) keylocker.Locker) Migrator
+	Add(migrations ...Migration) Migrator
+	Status(ctx context.Context) ([]MigrationStatus, errx.Error)
+	// Up applies the pending migrations in version order and returns them.
+	Up(ctx context.Context, opts ...*MigrateOptions) ([]MigrationStatus, errx.Error)
+	// Down reverts the applied migrations above the target version, by default only the last one.
+	Down(ctx context.Context, opts ...*MigrateOptions) ([]MigrationStatus, errx.Error)
+	// Register runs Up when the process is started with INITIALIZING=true.
+	Register()
+	// Command runs "status", "up" or "down" with the optional -dry-run and -to flags and prints the result to w.
+	Command(ctx context.Context, args []string, w io.Writer) errx.Error
+}
+
+func NewMigrator() Migrator {
+	return &migrator{
+		collName: defaultMigrationCollection,
+		locker:   keylocker.Etcd,
+	}
+}
+
+type migrator struct {
+	dbName     string
+	collName   string
+	cli        func() *mongo.Client
+	locker     func(key string) keylocker.Locker
+	migrations []Migration
+}
+
+type migrationRecord struct {
+	Version    int64  `bson:"_id"`
+	Name       string `bson:"name"`
+	Checksum   string `bson:"checksum"`
+	AppliedAt  int64  `bson:"appliedAt"`
+	DurationMs int64  `bson:"durationMs"`
+}
+
+func (m *migrator) copy() *migrator {
+	return &migrator{
+		dbName:     m.dbName,
+		collName:   m.collName,
+		cli:        m.cli,
+		locker:     m.locker,
+		migrations: slices.Clone(m.migrations),
+	}
+}
+
+func (m *migrator) WithDatabase(s string) Migrator {
+	c := m.copy()
+	c.dbName = s
+	return c
+}
+
+func (m *migrator) WithClient(cli func() *mongo.Client) Migrator {
+	c := m.copy()
+	c.cli = cli
+	return c
+}
+
+func (m *migrator) WithCollectionName(s string) Migrator {
+	c := m.copy()
+	c.collName = s
+	return c
+}
+
+func (m *migrator) WithLocker(locker func(key string) keylocker.Locker) Migrator {
+	c := m.copy()
+	c.locker = locker
+	return c
+}
+
+func (m *migrator) Add(migrations ...Migration) Migrator {
+	c := m.copy()
+	for _, mg := range migrations {
+		if mg.Version <= 0 {
+			logrus.Panicf("invalid migration version %d", mg.Version)
+		}
+		if len(mg.Up) == 0 && mg.UpFunc == nil {
+			logrus.Panicf("migration %d has no up step", mg.Version)
+		}
+		if slices.ContainsFunc(c.migrations, func(e Migration) bool { return e.Version == mg.Version }) {
+			logrus.Panicf("duplicate migration version %d", mg.Version)
+		}
+		c.migrations = append(c.migrations, mg)
+	}
+	slices.SortFunc(c.migrations, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	return c
+}
+
+func getMigrateOptions(opts []*MigrateOptions) *MigrateOptions {
+	if len(opts) > 0 && opts[0] != nil {
+		return opts[0]
+	}
+	return Migrate()
+}
+
+func (m *migrator) database() *mongo.Database {
+	cli := m.cli
+	if cli == nil {
+		cli = GetDefaultClient
+	}
+	dbName := m.dbName
+	if dbName == "" {
+		dbName = configReader.Read().DefaultDBName
+	}
+	return cli().Database(dbName)
+}
+
+func (m *migrator) Register() {
+	initialization.Register(func(ctx ctxx.Context) {
+		if _, err := m.Up(ctx); err != nil {
+			logrus.WithError(err).Fatal("mongodb migration failed")
+		}
+	})
+}
+
+func (m *migrator) Status(ctx context.Context) ([]MigrationStatus, errx.Error) {
+	records, err := m.records(ctx, m.database())
+	if err != nil {
+		return nil, err
+	}
+	return m.status(records), nil
+}
+
+func (m *migrator) Up(ctx context.Context, opts ...*MigrateOptions) ([]MigrationStatus, errx.Error) {
+	opt := getMigrateOptions(opts)
+	db := m.database()
+	ctx, unlock, err := m.lock(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	records, err := m.records(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if err = m.verify(records); err != nil {
+		return nil, err
+	}
+	log := logrus.WithField("database", db.Name())
+	var done []MigrationStatus
+	for _, mg := range m.migrations {
+		if _, ok := records[mg.Version]; ok {
+			continue
+		}
+		if opt.Target != nil && mg.Version > *opt.Target {
+			break
+		}
+		if opt.DryRun != nil && *opt.DryRun {
+			done = append(done, MigrationStatus{Version: mg.Version, Name: mg.Name})
+			continue
+		}
+		start := time.Now()
+		if err = runMigrationSteps(ctx, db, mg.Up, mg.UpFunc); err != nil {
+			return done, errx.Wrap(err).AppendMsgf("migration %d %s up failed", mg.Version, mg.Name).Err()
+		}
+		record := migrationRecord{
+			Version:    mg.Version,
+			Name:       mg.Name,
+			Checksum:   migrationChecksum(mg),
+			AppliedAt:  time.Now().UnixMilli(),
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+		if _, e := db.Collection(m.collName).InsertOne(ctx, record); e != nil {
+			return done, errx.Wrap(e).AppendMsgf("save migration %d failed", mg.Version).Err()
+		}
+		log.Infof("migration %d %s applied in %dms", mg.Version, mg.Name, record.DurationMs)
+		done = append(done, MigrationStatus{Version: mg.Version, Name: mg.Name, Applied: true, AppliedAt: record.AppliedAt})
+	}
+	return done, nil
+}
+
+func (m *migrator) Down(ctx context.Context, opts ...*MigrateOptions) ([]MigrationStatus, errx.Error) {
+	opt := getMigrateOptions(opts)
+	db := m.database()
+	ctx, unlock, err := m.lock(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	records, err := m.records(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if err = m.verify(records); err != nil {
+		return nil, err
+	}
+	applied := make([]int64, 0, len(records))
+	for v := range records {
+		applied = append(applied, v)
+	}
+	slices.Sort(applied)
+	slices.Reverse(applied)
+	if len(applied) == 0 {
+		return nil, nil
+	}
+	target := int64(0)
+	if opt.Target != nil {
+		target = *opt.Target
+	} else if len(applied) > 1 {
+		target = applied[1]
+	}
+	log := logrus.WithField("database", db.Name())
+	var done []MigrationStatus
+	for _, v := range applied {
+		if v <= target {
+			break
+		}
+		i := slices.IndexFunc(m.migrations, func(e Migration) bool { return e.Version == v })
+		if i < 0 {
+			return done, errx.Newf("migration %d is applied but not defined", v)
+		}
+		mg := m.migrations[i]
+		if len(mg.Down) == 0 && mg.DownFunc == nil {
+			return done, errx.Newf("migration %d %s has no down step", mg.Version, mg.Name)
+		}
+		if opt.DryRun != nil && *opt.DryRun {
+			done = append(done, MigrationStatus{Version: mg.Version, Name: mg.Name, Applied: true})
+			continue
+		}
+		if err = runMigrationSteps(ctx, db, mg.Down, mg.DownFunc); err != nil {
+			return done, errx.Wrap(err).AppendMsgf("migration %d %s down failed", mg.Version, mg.Name).Err()
+		}
+		if _, e := db.Collection(m.collName).DeleteOne(ctx, bson.M{"_id": mg.Version}); e != nil {
+			return done, errx.Wrap(e).AppendMsgf("delete migration %d failed", mg.Version).Err()
+		}
+		log.Infof("migration %d %s reverted", mg.Version, mg.Name)
+		done = append(done, MigrationStatus{Version: mg.Version, Name: mg.Name})
+	}
+	return done, nil
+}
+
+func (m *migrator) Command(ctx context.Context, args []string, w io.Writer) errx.Error {
+	cmd := "status"
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(w)
+	dryRun := fs.Bool("dry-run", false, "print the migrations without running them")
+	to := fs.String("to", "", "target version")
+	if err := fs.Parse(args); err != nil {
+		return errx.Validation.WithMsg(err.Error()).Err()
+	}
+	opt := Migrate().SetDryRun(*dryRun)
+	if *to != "" {
+		v, err := strconv.ParseInt(*to, 10, 64)
+		if err != nil {
+			return errx.Validation.WithMsgf("invalid target version %s", *to).Err()
+		}
+		opt.SetTarget(v)
+	}
+	var list []MigrationStatus
+	var err errx.Error
+	switch cmd {
+	case "status":
+		list, err = m.Status(ctx)
+	case "up":
+		list, err = m.Up(ctx, opt)
+	case "down":
+		list, err = m.Down(ctx, opt)
+	default:
+		return errx.Validation.WithMsgf("unknown migration command %s, expected status, up or down", cmd).Err()
+	}
+	printMigrations(w, list, *dryRun)
+	return err
+}
+
+func printMigrations(w io.Writer, list []MigrationStatus, dryRun bool) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+	for _, s := range list {
+		state := "pending"
+		switch {
+		case s.Missing:
+			state = "missing"
+		case s.Modified:
+			state = "modified"
+		case s.Applied:
+			state = "applied"
+		}
+		if dryRun {
+			state = "dry-run"
+		}
+		appliedAt := ""
+		if s.AppliedAt > 0 {
+			appliedAt = time.UnixMilli(s.AppliedAt).Format(time.RFC3339)
+		}
+		_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+	}
+	_ = tw.Flush()
+}
+
+// lock waits for the migration lock of db until ctx is done, the returned context is canceled when
+// the lock is lost.
+func (m *migrator) lock(ctx context.Context, db *mongo.Database) (context.Context, func(), errx.Error) {
+	if m.locker == nil {
+		return ctx, func() {}, nil
+	}
+	lease, err := m.locker("mongo-migration:" + db.Name()).LockCtx(ctx)
+	if err != nil {
+		return nil, nil, errx.Wrap(err).AppendMsgf("lock migrations of %s failed", db.Name()).Err()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-lease.Lost():
+			logrus.WithField("database", db.Name()).Error("migration lock lost")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		cancel()
+		lease.Unlock()
+	}, nil
+}
+
+func (m *migrator) records(ctx context.Context, db *mongo.Database) (map[int64]migrationRecord, errx.Error) {
+	cur, err := db.Collection(m.collName).Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, errx.Wrap(err).AppendMsg("list migrations failed").Err()
+	}
+	var list []migrationRecord
+	if err = cur.All(ctx, &list); err != nil {
+		return nil, errx.Wrap(err).AppendMsg("decode migrations failed").Err()
+	}
+	res := make(map[int64]migrationRecord, len(list))
+	for _, r := range list {
+		res[r.Version] = r
+	}
+	return res, nil
+}
+
+func (m *migrator) status(records map[int64]migrationRecord) []MigrationStatus {
+	var list []MigrationStatus
+	for _, mg := range m.migrations {
+		s := MigrationStatus{Version: mg.Version, Name: mg.Name}
+		if r, ok := records[mg.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = r.AppliedAt
+			s.Modified = r.Checksum != migrationChecksum(mg)
+		}
+		list = append(list, s)
+	}
+	for v, r := range records {
+		if !slices.ContainsFunc(m.migrations, func(e Migration) bool { return e.Version == v }) {
+			list = append(list, MigrationStatus{Version: v, Name: r.Name, Applied: true, AppliedAt: r.AppliedAt, Missing: true})
+		}
+	}
+	slices.SortFunc(list, func(a, b MigrationStatus) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	return list
+}
+
+// verify fails when an applied migration has been edited afterwards.
+func (m *migrator) verify(records map[int64]migrationRecord) errx.Error {
+	for _, s := range m.status(records) {
+		if s.Modified {
+			return errx.Conflict.WithMsgf("migration %d %s has been modified after it was applied", s.Version, s.Name).Err()
+		}
+		if s.Missing {
+			logrus.Warnf("migration %d %s is applied but not defined", s.Version, s.Name)
+		}
+	}
+	return nil
+}
+
+func runMigrationSteps(ctx context.Context, db *mongo.Database, commands []bson.D, fn func(context.Context, *mongo.Database) errx.Error) errx.Error {
+	for _, cmd := range commands {
+		if err := db.RunCommand(ctx, cmd).Err(); err != nil {
+			return errx.Wrap(err).Err()
+		}
+	}
+	if fn != nil {
+		return fn(ctx, db)
+	}
+	return nil
+}
+
+func migrationChecksum(mg Migration) string {
+	if mg.Checksum != "" {
+		return mg.Checksum
+	}
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "%d:%s", mg.Version, mg.Name)
+	for _, steps := range [][]bson.D{mg.Up, mg.Down} {
+		h.Write([]byte{0})
+		for _, cmd := range steps {
+			data, err := bson.Marshal(cmd)
+			if err != nil {
+				logrus.Panicf("migration %d command can not be encoded: %v", mg.Version, err)
+			}
+			h.Write(data)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,160 @@
+package mongox
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tencent-go/pkg/errx"
+	"github.com/tencent-go/pkg/keylocker"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func testMigrations() []Migration {
+	index := func(name string) []bson.D {
+		return []bson.D{{{Key: "createIndexes", Value: "orders"}, {Key: "indexes", Value: bson.A{bson.D{{Key: "name", Value: name}}}}}}
+	}
+	return []Migration{
+		{Version: 1, Name: "status", Up: index("status"), Down: index("status")},
+		{Version: 2, Name: "buyer", Up: index("buyer"), Down: index("buyer")},
+		{Version: 3, Name: "backfill", UpFunc: func(context.Context, *mongo.Database) errx.Error { return nil }, Checksum: "v1"},
+	}
+}
+
+func migrationRecords(list ...Migration) []bson.D {
+	docs := make([]bson.D, len(list))
+	for i, mg := range list {
+		docs[i] = bson.D{
+			{Key: "_id", Value: mg.Version},
+			{Key: "name", Value: mg.Name},
+			{Key: "checksum", Value: migrationChecksum(mg)},
+			{Key: "appliedAt", Value: int64(1700000000000)},
+		}
+	}
+	return docs
+}
+
+func TestMigrationChecksum(t *testing.T) {
+	mg := testMigrations()[0]
+	sum := migrationChecksum(mg)
+	assert.Len(t, sum, 64)
+	assert.Equal(t, sum, migrationChecksum(mg))
+
+	renamed := mg
+	renamed.Name = "status2"
+	assert.NotEqual(t, sum, migrationChecksum(renamed))
+
+	changed := mg
+	changed.Down = nil
+	assert.NotEqual(t, sum, migrationChecksum(changed))
+
+	// Up 與 Down 交換也不同
+	swapped := mg
+	swapped.Up, swapped.Down = nil, mg.Up
+	assert.NotEqual(t, migrationChecksum(changed), migrationChecksum(swapped))
+
+	withFunc := testMigrations()[2]
+	assert.Equal(t, "v1", migrationChecksum(withFunc))
+}
+
+func TestMigrationStatus(t *testing.T) {
+	list := testMigrations()
+	m := NewMigrator().Add(list...).(*migrator)
+
+	t.Run("狀態", func(t *testing.T) {
+		records := map[int64]migrationRecord{
+			1:  {Version: 1, Name: "status", Checksum: migrationChecksum(list[0]), AppliedAt: 1},
+			2:  {Version: 2, Name: "buyer", Checksum: "old", AppliedAt: 2},
+			10: {Version: 10, Name: "removed", AppliedAt: 3},
+		}
+		assert.Equal(t, []MigrationStatus{
+			{Version: 1, Name: "status", Applied: true, AppliedAt: 1},
+			{Version: 2, Name: "buyer", Applied: true, AppliedAt: 2, Modified: true},
+			{Version: 3, Name: "backfill"},
+			{Version: 10, Name: "removed", Applied: true, AppliedAt: 3, Missing: true},
+		}, m.status(records))
+	})
+
+	t.Run("驗證", func(t *testing.T) {
+		records := map[int64]migrationRecord{
+			1:  {Version: 1, Checksum: migrationChecksum(list[0])},
+			10: {Version: 10, Name: "removed"},
+		}
+		assert.NoError(t, m.verify(records))
+		records[2] = migrationRecord{Version: 2, Checksum: "old"}
+		err := m.verify(records)
+		if assert.Error(t, err) {
+			assert.Equal(t, errx.TypeConflict, err.Type())
+		}
+	})
+}
+
+func TestMigrator(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	list := testMigrations()
+	migrator := func(mt *mtest.T) Migrator {
+		return NewMigrator().
+			WithClient(func() *mongo.Client { return mt.Client }).
+			WithDatabase("test").
+			WithLocker(keylocker.Local).
+			Add(list...)
+	}
+	dryRun := func() *MigrateOptions {
+		return Migrate().SetDryRun(true)
+	}
+
+	mt.Run("待執行", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test._migrations", mtest.FirstBatch, migrationRecords(list[0])...))
+		done, err := migrator(mt).Up(context.Background(), dryRun().SetTarget(2))
+		assert.NoError(mt, err)
+		assert.Equal(mt, []MigrationStatus{{Version: 2, Name: "buyer"}}, done)
+	})
+
+	mt.Run("回滾最後一個", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test._migrations", mtest.FirstBatch, migrationRecords(list[:2]...)...))
+		done, err := migrator(mt).Down(context.Background(), dryRun())
+		assert.NoError(mt, err)
+		assert.Equal(mt, []MigrationStatus{{Version: 2, Name: "buyer", Applied: true}}, done)
+	})
+
+	mt.Run("回滾到目標", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test._migrations", mtest.FirstBatch, migrationRecords(list[:2]...)...))
+		done, err := migrator(mt).Down(context.Background(), dryRun().SetTarget(0))
+		assert.NoError(mt, err)
+		assert.Equal(mt, []MigrationStatus{
+			{Version: 2, Name: "buyer", Applied: true},
+			{Version: 1, Name: "status", Applied: true},
+		}, done)
+	})
+
+	mt.Run("無回滾步驟", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test._migrations", mtest.FirstBatch, migrationRecords(list...)...))
+		_, err := migrator(mt).Down(context.Background(), dryRun().SetTarget(2))
+		assert.Error(mt, err)
+	})
+
+	mt.Run("已修改", func(mt *mtest.T) {
+		modified := list[0]
+		modified.Checksum = "edited"
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test._migrations", mtest.FirstBatch, migrationRecords(modified)...))
+		_, err := migrator(mt).Up(context.Background(), dryRun())
+		if assert.Error(mt, err) {
+			assert.Equal(mt, errx.TypeConflict, err.Type())
+		}
+	})
+
+	mt.Run("等待鎖逾時", func(mt *mtest.T) {
+		l := keylocker.Local("mongo-migration:test")
+		l.Lock()
+		defer l.Unlock()
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := migrator(mt).Up(ctx, dryRun())
+		if assert.Error(mt, err) {
+			assert.Equal(mt, errx.TypeTimeout, err.Type())
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package mongox
 
 import (
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -63,3 +64,84 @@ func ChangeStream() *ChangeStreamOptions {
 		ChangeStreamOptions: options.ChangeStream(),
 	}
 }
+
+type AggregateOptions struct {
+	*options.AggregateOptions
+	ExplainVerbosity *string
+	ExplainHandler   func(explain bson.M)
+}
+
+// SetExplain runs the explain command before the aggregation and passes its output to handler,
+// verbosity is one of "queryPlanner", "executionStats" and "allPlansExecution".
+func (o *AggregateOptions) SetExplain(verbosity string, handler func(explain bson.M)) *AggregateOptions {
+	o.ExplainVerbosity = &verbosity
+	o.ExplainHandler = handler
+	return o
+}
+
+func Aggregation() *AggregateOptions {
+	return &AggregateOptions{
+		AggregateOptions: options.Aggregate(),
+	}
+}
+
+type BulkOptions struct {
+	BatchSize       *int
+	IgnoreZeroValue *bool
+	OptimisticLock  *bool
+	*options.BulkWriteOptions
+}
+
+// SetOrdered stops at the first document that fails to prepare or to write when b is true (the default),
+// otherwise every document is attempted. An update that matches nothing is reported in its BulkResult
+// but does not stop the write.
+func (o *BulkOptions) SetOrdered(b bool) *BulkOptions {
+	if o.BulkWriteOptions == nil {
+		o.BulkWriteOptions = options.BulkWrite()
+	}
+	o.BulkWriteOptions.SetOrdered(b)
+	return o
+}
+
+// SetBatchSize sets the number of documents sent in one round trip, 1000 by default.
+func (o *BulkOptions) SetBatchSize(n int) *BulkOptions {
+	o.BatchSize = &n
+	return o
+}
+
+func (o *BulkOptions) SetIgnoreZeroValue(b bool) *BulkOptions {
+	o.IgnoreZeroValue = &b
+	return o
+}
+
+func (o *BulkOptions) SetOptimisticLock(b bool) *BulkOptions {
+	o.OptimisticLock = &b
+	return o
+}
+
+func Bulk() *BulkOptions {
+	return &BulkOptions{
+		BulkWriteOptions: options.BulkWrite(),
+	}
+}
+
+type MigrateOptions struct {
+	DryRun *bool
+	Target *int64
+}
+
+// SetDryRun only lists the migrations that would run.
+func (o *MigrateOptions) SetDryRun(b bool) *MigrateOptions {
+	o.DryRun = &b
+	return o
+}
+
+// SetTarget stops Up after version v and makes Down revert every migration above v.
+func (o *MigrateOptions) SetTarget(v int64) *MigrateOptions {
+	o.Target = &v
+	return o
+}
+
+func Migrate() *MigrateOptions {
+	return &MigrateOptions{}
+}
@@ -0,0 +1,431 @@
+package mongox
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+	"github.com/tencent-go/pkg/page"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Filter is a query document bound to the entity type T. It encodes as a plain
+// bson document, so it can be passed to GetOne, GetList and CountDocuments directly.
+type Filter[T any] bson.D
+
+// Field is a typed reference to the bson path of an entity field. Create it once with
+// NewField (usually as a package level variable) so that unknown paths fail at startup.
+type Field[T any, V any] struct {
+	name string
+}
+
+// ArrayField is a typed reference to a slice field whose elements are of type E.
+type ArrayField[T any, E any] struct {
+	name string
+}
+
+// NewField checks that name is a bson path of T ("a.b.c" for nested documents) and that
+// the field type matches V, and panics otherwise.
+func NewField[T any, V any](name string) Field[T, V] {
+	ft := lookupFieldType[T](name)
+	vt := reflect.TypeOf((*V)(nil)).Elem()
+	if !fieldTypeMatches(ft, vt) {
+		logrus.Panicf("mongodb field %s of %s is %s, not %s", name, entityTypeName[T](), ft.String(), vt.String())
+	}
+	return Field[T, V]{name: name}
+}
+
+// NewArrayField is like NewField but for slice fields with element type E.
+func NewArrayField[T any, E any](name string) ArrayField[T, E] {
+	ft := indirectType(lookupFieldType[T](name))
+	et := reflect.TypeOf((*E)(nil)).Elem()
+	if (ft.Kind() != reflect.Slice && ft.Kind() != reflect.Array) || !fieldTypeMatches(ft.Elem(), et) {
+		logrus.Panicf("mongodb field %s of %s is %s, not an array of %s", name, entityTypeName[T](), ft.String(), et.String())
+	}
+	return ArrayField[T, E]{name: name}
+}
+
+// HasField reports whether name is a known bson path of T.
+func HasField[T any](name string) bool {
+	_, ok := getFieldTypes(reflect.TypeOf((*T)(nil)).Elem())[name]
+	return ok
+}
+
+func (f Field[T, V]) Name() string {
+	return f.name
+}
+
+func (f Field[T, V]) Eq(v V) Filter[T] {
+	return Filter[T]{{Key: f.name, Value: v}}
+}
+
+func (f Field[T, V]) Ne(v V) Filter[T] {
+	return f.op("$ne", v)
+}
+
+func (f Field[T, V]) Gt(v V) Filter[T] {
+	return f.op("$gt", v)
+}
+
+func (f Field[T, V]) Gte(v V) Filter[T] {
+	return f.op("$gte", v)
+}
+
+func (f Field[T, V]) Lt(v V) Filter[T] {
+	return f.op("$lt", v)
+}
+
+func (f Field[T, V]) Lte(v V) Filter[T] {
+	return f.op("$lte", v)
+}
+
+// Between matches min <= value < max.
+func (f Field[T, V]) Between(min, max V) Filter[T] {
+	return Filter[T]{{Key: f.name, Value: bson.D{{Key: "$gte", Value: min}, {Key: "$lt", Value: max}}}}
+}
+
+func (f Field[T, V]) In(values ...V) Filter[T] {
+	return f.op("$in", nonNilSlice(values))
+}
+
+func (f Field[T, V]) Nin(values ...V) Filter[T] {
+	return f.op("$nin", nonNilSlice(values))
+}
+
+func (f Field[T, V]) Exists(b bool) Filter[T] {
+	return f.op("$exists", b)
+}
+
+// Regex matches string fields against pattern, options are the mongodb regex flags such as "i".
+func (f Field[T, V]) Regex(pattern string, options string) Filter[T] {
+	return f.op("$regex", primitive.Regex{Pattern: pattern, Options: options})
+}
+
+func (f Field[T, V]) Asc() SortKey[T] {
+	return SortKey[T]{name: f.name, direction: page.DirectionAsc}
+}
+
+func (f Field[T, V]) Desc() SortKey[T] {
+	return SortKey[T]{name: f.name, direction: page.DirectionDesc}
+}
+
+func (f Field[T, V]) Set(v V) UpdateOp[T] {
+	return UpdateOp[T]{op: "$set", name: f.name, value: v}
+}
+
+func (f Field[T, V]) SetOnInsert(v V) UpdateOp[T] {
+	return UpdateOp[T]{op: "$setOnInsert", name: f.name, value: v}
+}
+
+func (f Field[T, V]) Unset() UpdateOp[T] {
+	return UpdateOp[T]{op: "$unset", name: f.name, value: ""}
+}
+
+// Inc only makes sense for numeric fields, the server rejects it otherwise.
+func (f Field[T, V]) Inc(v V) UpdateOp[T] {
+	return UpdateOp[T]{op: "$inc", name: f.name, value: v}
+}
+
+func (f Field[T, V]) Mul(v V) UpdateOp[T] {
+	return UpdateOp[T]{op: "$mul", name: f.name, value: v}
+}
+
+func (f Field[T, V]) Min(v V) UpdateOp[T] {
+	return UpdateOp[T]{op: "$min", name: f.name, value: v}
+}
+
+func (f Field[T, V]) Max(v V) UpdateOp[T] {
+	return UpdateOp[T]{op: "$max", name: f.name, value: v}
+}
+
+func (f Field[T, V]) op(op string, v any) Filter[T] {
+	return Filter[T]{{Key: f.name, Value: bson.D{{Key: op, Value: v}}}}
+}
+
+func (f ArrayField[T, E]) Name() string {
+	return f.name
+}
+
+func (f ArrayField[T, E]) Exists(b bool) Filter[T] {
+	return f.op("$exists", b)
+}
+
+// Contains matches documents whose array holds v.
+func (f ArrayField[T, E]) Contains(v E) Filter[T] {
+	return Filter[T]{{Key: f.name, Value: v}}
+}
+
+// ContainsAny matches documents whose array holds at least one of values.
+func (f ArrayField[T, E]) ContainsAny(values ...E) Filter[T] {
+	return f.op("$in", nonNilSlice(values))
+}
+
+// All matches documents whose array holds every one of values.
+func (f ArrayField[T, E]) All(values ...E) Filter[T] {
+	return f.op("$all", nonNilSlice(values))
+}
+
+func (f ArrayField[T, E]) Size(n int) Filter[T] {
+	return f.op("$size", n)
+}
+
+// ElemMatch matches documents with at least one element satisfying filter, built from fields of E.
+func (f ArrayField[T, E]) ElemMatch(filter Filter[E]) Filter[T] {
+	return f.op("$elemMatch", filter.nonNil())
+}
+
+func (f ArrayField[T, E]) Set(values []E) UpdateOp[T] {
+	return UpdateOp[T]{op: "$set", name: f.name, value: nonNilSlice(values)}
+}
+
+func (f ArrayField[T, E]) Unset() UpdateOp[T] {
+	return UpdateOp[T]{op: "$unset", name: f.name, value: ""}
+}
+
+func (f ArrayField[T, E]) Push(values ...E) UpdateOp[T] {
+	return UpdateOp[T]{op: "$push", name: f.name, value: bson.D{{Key: "$each", Value: nonNilSlice(values)}}}
+}
+
+func (f ArrayField[T, E]) AddToSet(values ...E) UpdateOp[T] {
+	return UpdateOp[T]{op: "$addToSet", name: f.name, value: bson.D{{Key: "$each", Value: nonNilSlice(values)}}}
+}
+
+func (f ArrayField[T, E]) Pull(values ...E) UpdateOp[T] {
+	return UpdateOp[T]{op: "$pullAll", name: f.name, value: nonNilSlice(values)}
+}
+
+// PullWhere removes every element matching filter.
+func (f ArrayField[T, E]) PullWhere(filter Filter[E]) UpdateOp[T] {
+	return UpdateOp[T]{op: "$pull", name: f.name, value: filter.nonNil()}
+}
+
+func (f ArrayField[T, E]) PopFirst() UpdateOp[T] {
+	return UpdateOp[T]{op: "$pop", name: f.name, value: -1}
+}
+
+func (f ArrayField[T, E]) PopLast() UpdateOp[T] {
+	return UpdateOp[T]{op: "$pop", name: f.name, value: 1}
+}
+
+func (f ArrayField[T, E]) op(op string, v any) Filter[T] {
+	return Filter[T]{{Key: f.name, Value: bson.D{{Key: op, Value: v}}}}
+}
+
+func (f Filter[T]) nonNil() Filter[T] {
+	if f == nil {
+		return Filter[T]{}
+	}
+	return f
+}
+
+// And combines filters with $and, an empty call matches every document.
+func And[T any](filters ...Filter[T]) Filter[T] {
+	return combine("$and", filters)
+}
+
+func Or[T any](filters ...Filter[T]) Filter[T] {
+	return combine("$or", filters)
+}
+
+func Nor[T any](filters ...Filter[T]) Filter[T] {
+	return Filter[T]{{Key: "$nor", Value: nonNilFilters(filters)}}
+}
+
+func Not[T any](filter Filter[T]) Filter[T] {
+	return Nor(filter)
+}
+
+// Text runs a $text search, the collection needs a text index.
+func Text[T any](search string) Filter[T] {
+	return Filter[T]{{Key: "$text", Value: bson.D{{Key: "$search", Value: search}}}}
+}
+
+func TextWithLanguage[T any](search string, language string) Filter[T] {
+	return Filter[T]{{Key: "$text", Value: bson.D{{Key: "$search", Value: search}, {Key: "$language", Value: language}}}}
+}
+
+func combine[T any](op string, filters []Filter[T]) Filter[T] {
+	var list []Filter[T]
+	for _, f := range filters {
+		if len(f) > 0 {
+			list = append(list, f)
+		}
+	}
+	switch len(list) {
+	case 0:
+		return Filter[T]{}
+	case 1:
+		return list[0]
+	default:
+		return Filter[T]{{Key: op, Value: list}}
+	}
+}
+
+func nonNilFilters[T any](filters []Filter[T]) []Filter[T] {
+	list := make([]Filter[T], 0, len(filters))
+	for _, f := range filters {
+		list = append(list, f.nonNil())
+	}
+	return list
+}
+
+func nonNilSlice[E any](values []E) []E {
+	if values == nil {
+		return []E{}
+	}
+	return values
+}
+
+// UpdateOp is a single field modification, see BuildUpdate.
+type UpdateOp[T any] struct {
+	op    string
+	name  string
+	value any
+}
+
+// BuildUpdate groups ops by operator into an update document such as {$set: {...}, $inc: {...}}.
+func BuildUpdate[T any](ops ...UpdateOp[T]) bson.D {
+	res := bson.D{}
+	index := make(map[string]int)
+	for _, o := range ops {
+		i, ok := index[o.op]
+		if !ok {
+			i = len(res)
+			index[o.op] = i
+			res = append(res, bson.E{Key: o.op, Value: bson.D{}})
+		}
+		res[i].Value = append(res[i].Value.(bson.D), bson.E{Key: o.name, Value: o.value})
+	}
+	return res
+}
+
+// SortKey implements page.Sortable, so it can be passed to GetPaginationOptions.
+type SortKey[T any] struct {
+	name      string
+	direction page.Direction
+}
+
+func (s SortKey[T]) GetSort() string {
+	return s.name
+}
+
+func (s SortKey[T]) GetDirection() page.Direction {
+	return s.direction
+}
+
+// Sort builds a multi-field sort document for options.Find().SetSort.
+func Sort[T any](keys ...SortKey[T]) bson.D {
+	res := bson.D{}
+	for _, k := range keys {
+		d := 1
+		if k.direction == page.DirectionDesc {
+			d = -1
+		}
+		res = append(res, bson.E{Key: k.name, Value: d})
+	}
+	return res
+}
+
+var fieldTypesCache sync.Map
+
+func lookupFieldType[T any](name string) reflect.Type {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	ft, ok := getFieldTypes(typ)[name]
+	if !ok {
+		logrus.Panicf("mongodb field %s not found in %s", name, entityTypeName[T]())
+	}
+	return ft
+}
+
+func entityTypeName[T any]() string {
+	t := indirectType(reflect.TypeOf((*T)(nil)).Elem())
+	return t.PkgPath() + "." + t.Name()
+}
+
+// getFieldTypes lists every bson path of typ, including nested documents and documents inside arrays.
+func getFieldTypes(typ reflect.Type) map[string]reflect.Type {
+	if v, ok := fieldTypesCache.Load(typ); ok {
+		return v.(map[string]reflect.Type)
+	}
+	res := make(map[string]reflect.Type)
+	collectFieldTypes(typ, "", res, map[reflect.Type]bool{})
+	fieldTypesCache.Store(typ, res)
+	return res
+}
+
+func collectFieldTypes(typ reflect.Type, prefix string, dst map[string]reflect.Type, visiting map[reflect.Type]bool) {
+	typ = indirectType(typ)
+	if visiting[typ] {
+		return
+	}
+	visiting[typ] = true
+	defer delete(visiting, typ)
+	for _, f := range getValueFields(typ) {
+		ft, ok := fieldTypeByPath(typ, f.path)
+		if !ok {
+			continue
+		}
+		key := f.bsonKey
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		dst[key] = ft
+		if doc := nestedDocumentType(ft); doc != nil {
+			collectFieldTypes(doc, key, dst, visiting)
+		}
+	}
+}
+
+func fieldTypeByPath(typ reflect.Type, path []string) (reflect.Type, bool) {
+	var ft reflect.Type = typ
+	for _, s := range path {
+		sf, ok := indirectType(ft).FieldByName(s)
+		if !ok || !sf.IsExported() {
+			return nil, false
+		}
+		ft = sf.Type
+	}
+	return ft, true
+}
+
+var (
+	bsonMarshalerType      = reflect.TypeOf((*bson.Marshaler)(nil)).Elem()
+	bsonValueMarshalerType = reflect.TypeOf((*bson.ValueMarshaler)(nil)).Elem()
+	leafStructTypes        = map[reflect.Type]bool{
+		reflect.TypeOf(time.Time{}):       true,
+		reflect.TypeOf(decimal.Decimal{}): true,
+	}
+)
+
+func nestedDocumentType(t reflect.Type) reflect.Type {
+	t = indirectType(t)
+	if (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() != reflect.Uint8 {
+		t = indirectType(t.Elem())
+	}
+	if t.Kind() != reflect.Struct || leafStructTypes[t] || strings.HasPrefix(t.PkgPath(), "go.mongodb.org/") {
+		return nil
+	}
+	if t.Implements(bsonMarshalerType) || t.Implements(bsonValueMarshalerType) ||
+		reflect.PointerTo(t).Implements(bsonMarshalerType) || reflect.PointerTo(t).Implements(bsonValueMarshalerType) {
+		return nil
+	}
+	return t
+}
+
+func fieldTypeMatches(fieldType, valueType reflect.Type) bool {
+	if valueType.Kind() == reflect.Interface {
+		return valueType.NumMethod() == 0 || fieldType.Implements(valueType)
+	}
+	return indirectType(fieldType) == indirectType(valueType)
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
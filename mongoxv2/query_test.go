@@ -0,0 +1,124 @@
+package mongox
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tencent-go/pkg/page"
+	"github.com/tencent-go/pkg/types"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type queryItem struct {
+	SKU   string `bson:"sku"`
+	Count int    `bson:"count"`
+}
+
+type queryOrder struct {
+	types.Entity `bson:",inline"`
+	Status       string      `bson:"status"`
+	Amount       *int64      `bson:"amount,omitempty"`
+	Tags         []string    `bson:"tags"`
+	Items        []queryItem `bson:"items"`
+	Buyer        struct {
+		Name string `bson:"name"`
+	} `bson:"buyer"`
+}
+
+func TestQueryBuilder(t *testing.T) {
+	var (
+		id     = NewField[queryOrder, types.ID]("_id")
+		status = NewField[queryOrder, string]("status")
+		amount = NewField[queryOrder, int64]("amount")
+		buyer  = NewField[queryOrder, string]("buyer.name")
+		tags   = NewArrayField[queryOrder, string]("tags")
+		items  = NewArrayField[queryOrder, queryItem]("items")
+		sku    = NewField[queryItem, string]("sku")
+	)
+
+	t.Run("字段校驗", func(t *testing.T) {
+		assert.True(t, HasField[queryOrder]("items.sku"))
+		assert.True(t, HasField[queryOrder]("version"))
+		assert.False(t, HasField[queryOrder]("missing"))
+		assert.Panics(t, func() { NewField[queryOrder, string]("missing") })
+		assert.Panics(t, func() { NewField[queryOrder, int]("status") })
+		assert.Panics(t, func() { NewArrayField[queryOrder, string]("status") })
+	})
+
+	t.Run("過濾條件", func(t *testing.T) {
+		f := And(
+			status.In("paid", "shipped"),
+			Or(amount.Gte(100), buyer.Eq("bob")),
+			tags.All("a", "b"),
+			items.ElemMatch(sku.Eq("x")),
+			Not(id.Eq(1)),
+		)
+		raw, err := bson.Marshal(f)
+		assert.NoError(t, err)
+		var m bson.M
+		assert.NoError(t, bson.Unmarshal(raw, &m))
+		assert.Len(t, m["$and"], 5)
+		assert.Equal(t, Filter[queryOrder]{}, And[queryOrder]())
+	})
+
+	t.Run("更新文檔", func(t *testing.T) {
+		u := BuildUpdate(status.Set("paid"), amount.Inc(5), buyer.Set("bob"), tags.Push("c"))
+		assert.Equal(t, bson.D{
+			{Key: "$set", Value: bson.D{{Key: "status", Value: "paid"}, {Key: "buyer.name", Value: "bob"}}},
+			{Key: "$inc", Value: bson.D{{Key: "amount", Value: int64(5)}}},
+			{Key: "$push", Value: bson.D{{Key: "tags", Value: bson.D{{Key: "$each", Value: []string{"c"}}}}}},
+		}, u)
+	})
+
+	t.Run("排序", func(t *testing.T) {
+		opts := GetPaginationOptions(&page.Pagination{}, status.Desc())
+		assert.Equal(t, bson.M{"status": -1}, opts.Sort)
+		assert.Equal(t, bson.D{{Key: "status", Value: 1}, {Key: "_id", Value: -1}}, Sort(status.Asc(), id.Desc()))
+	})
+}
+
+func TestCursorToken(t *testing.T) {
+	keys, err := getCursorSortKeys[queryOrder]([]types.Sortable{types.SortOption[string]{Sort: "status", Direction: types.DirectionDesc}})
+	assert.Nil(t, err)
+	assert.Equal(t, "-status,-_id", sortKeysString(keys))
+
+	_, err = getCursorSortKeys[queryOrder]([]types.Sortable{types.SortOption[string]{Sort: "missing"}})
+	assert.NotNil(t, err)
+
+	doc, _ := bson.Marshal(bson.M{"_id": int64(7), "status": "paid"})
+	s, err := encodeCursor(keys, doc, true)
+	assert.Nil(t, err)
+	token, err := decodeCursor(s, keys)
+	assert.Nil(t, err)
+	assert.True(t, token.Backward)
+	assert.Equal(t, bson.A{"paid", int64(7)}, token.Values)
+
+	_, err = decodeCursor(s, keys[1:])
+	assert.NotNil(t, err)
+
+	f := cursorFilter(keys, token.Values, false)
+	assert.Equal(t, bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: "status", Value: bson.D{{Key: "$lt", Value: "paid"}}}},
+			bson.D{{Key: "status", Value: nil}},
+		}}},
+		bson.D{{Key: "status", Value: "paid"}, {Key: "_id", Value: bson.D{{Key: "$lt", Value: int64(7)}}}},
+	}}}, f, "降冪時空值排在最後")
+
+	t.Run("空排序值", func(t *testing.T) {
+		doc, _ := bson.Marshal(bson.M{"_id": int64(7), "status": nil})
+		s, err := encodeCursor(keys, doc, false)
+		assert.Nil(t, err)
+		token, err := decodeCursor(s, keys)
+		assert.Nil(t, err)
+		assert.Equal(t, bson.A{nil, int64(7)}, token.Values)
+
+		assert.Equal(t, bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: "status", Value: nil}, {Key: "_id", Value: bson.D{{Key: "$lt", Value: int64(7)}}}},
+		}}}, cursorFilter(keys, token.Values, false), "降冪時空值之後只剩同為空值者")
+		assert.Equal(t, bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: "status", Value: bson.D{{Key: "$ne", Value: nil}}}},
+			bson.D{{Key: "status", Value: nil}, {Key: "_id", Value: bson.D{{Key: "$gt", Value: int64(7)}}}},
+		}}}, cursorFilter(keys, token.Values, true), "往前翻頁時非空值皆在之前")
+	})
+}
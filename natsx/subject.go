@@ -23,7 +23,16 @@ func NewSubjectBuilder[T any](subject string) SubjectBuilder[T] {
 	return &subjectBuilder[T]{subjectOptions: subjectOptions{subject: subject}}
 }
 
+// SubjectDefinition 不含payload泛型的subject描述,供文檔生成使用
+type SubjectDefinition interface {
+	Subject() string // 含佔位符的原始subject
+	MessageType() reflect.Type
+	StreamName() string // 未綁定stream時為空
+	Description() string
+}
+
 type Subject[T any] interface {
+	SubjectDefinition
 	Conn() *nats.Conn
 	Publisher() (Publisher[T], errx.Error)
 	MustPublisher() Publisher[T]
@@ -49,6 +58,7 @@ type SubjectBuilder[T any] interface {
 	WithStream(stream Stream) SubjectBuilder[T]
 	WithHandlerProcessTimeout(timeout time.Duration) SubjectBuilder[T] //默認為consumer config ack wait,僅durable subscribe有效
 	WithConsumerConfig(consumerConfig jetstream.ConsumerConfig) SubjectBuilder[T]
+	WithDescription(description string) SubjectBuilder[T]
 }
 
 func validateSubject(subject string) bool {
@@ -73,6 +83,7 @@ type subjectOptions struct {
 	stream                Stream
 	consumerConfig        *jetstream.ConsumerConfig
 	handlerProcessTimeout time.Duration
+	description           string
 }
 
 type subjectBuilder[T any] struct {
@@ -167,6 +178,31 @@ func (s *subjectBuilder[T]) WithHandlerProcessTimeout(timeout time.Duration) Sub
 	return &subjectBuilder[T]{subjectOptions: o}
 }
 
+func (s *subjectBuilder[T]) WithDescription(description string) SubjectBuilder[T] {
+	o := s.subjectOptions
+	o.description = description
+	return &subjectBuilder[T]{subjectOptions: o}
+}
+
+func (s *subjectBuilder[T]) Subject() string {
+	return s.subject
+}
+
+func (s *subjectBuilder[T]) MessageType() reflect.Type {
+	return reflect.TypeOf(*(new(T)))
+}
+
+func (s *subjectBuilder[T]) StreamName() string {
+	if s.stream == nil {
+		return ""
+	}
+	return s.stream.Config().Name
+}
+
+func (s *subjectBuilder[T]) Description() string {
+	return s.description
+}
+
 func (s *subjectBuilder[T]) Conn() *nats.Conn {
 	return s.getConn()
 }
@@ -3,6 +3,7 @@ package api
 import (
 	"github.com/tencent-go/pkg/errx"
 	"github.com/tencent-go/pkg/types"
+	"github.com/tencent-go/pkg/validation"
 )
 
 type ContentType string
@@ -95,7 +96,8 @@ type HeaderParams struct {
 }
 
 type ErrorDetails struct {
-	Code    int       `json:"code"`
-	Message string    `json:"message"`
-	Type    errx.Type `json:"type"`
+	Code       int                   `json:"code"`
+	Message    string                `json:"message"`
+	Type       errx.Type             `json:"type"`
+	Violations validation.Violations `json:"violations,omitempty"`
 }
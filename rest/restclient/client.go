@@ -0,0 +1,309 @@
+package restclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tencent-go/pkg/ctxx"
+	"github.com/tencent-go/pkg/errx"
+	"github.com/tencent-go/pkg/rest/api"
+	"github.com/tencent-go/pkg/rest/router"
+	"github.com/tencent-go/pkg/types"
+	"github.com/tencent-go/pkg/util"
+)
+
+var registry = struct {
+	sync.RWMutex
+	routes map[api.Endpoint]api.Route
+}{routes: make(map[api.Endpoint]api.Route)}
+
+// Register makes the routes of groups known to Call, so that the path and the content types
+// inherited from the groups are used. Endpoints which are not registered are called as children
+// of api.DefaultGroup.
+func Register(groups ...api.Group) {
+	registry.Lock()
+	defer registry.Unlock()
+	for _, g := range groups {
+		for _, r := range g.Routes() {
+			registry.routes[r.Endpoint()] = r
+		}
+	}
+}
+
+func getRoute(endpoint api.Endpoint) (api.Route, errx.Error) {
+	registry.RLock()
+	r, ok := registry.routes[endpoint]
+	registry.RUnlock()
+	if ok {
+		return r, nil
+	}
+	routes := api.DefaultGroup().WithChildren(endpoint).Routes()
+	if len(routes) == 0 {
+		return nil, errx.New("invalid endpoint")
+	}
+	return routes[0], nil
+}
+
+type serializerKey struct {
+	typ         reflect.Type
+	contentType api.ContentType
+}
+
+var serializers util.LazyMap[serializerKey, any]
+
+func getSerializer[I any](contentType api.ContentType) (util.HttpSerializer[I], bool) {
+	key := serializerKey{typ: reflect.TypeOf((*I)(nil)).Elem(), contentType: contentType}
+	s, _ := serializers.LoadOrLazyStore(key, func() any {
+		tags := []util.StructTag{util.TagHeader, util.TagPath, util.TagQuery}
+		switch contentType {
+		case api.ContentTypeApplicationJson:
+			tags = append(tags, util.TagJson)
+		case api.ContentTypeApplicationFormUrlencoded:
+			tags = append(tags, util.TagForm)
+		}
+		s, _ := util.NewHttpSerializer[I](tags...)
+		return s
+	})
+	res, ok := s.(util.HttpSerializer[I])
+	return res, ok && res != nil
+}
+
+// Call requests endpoint on baseURL (scheme, host and optional prefix) with input. The trace ID,
+// caller, operator and locale of ctx are sent as headers. A wrapped output is unwrapped and its
+// error is returned with the type, code, message and violations sent by the server.
+func Call[I, O any](ctx ctxx.Context, baseURL string, endpoint api.EndpointBuilder[I, O], input I, opts ...Option) (*O, errx.Error) {
+	if ctx == nil {
+		ctx = ctxx.Background()
+	}
+	o := getOptions(opts...)
+	route, err := getRoute(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	req, err := newRequest(route, baseURL, input)
+	if err != nil {
+		return nil, err
+	}
+	writeRequestHeader(ctx, req.header, o.header)
+	attempts := 1
+	if o.retryable(endpoint.Method()) {
+		attempts += o.retries
+	}
+	for attempt := 0; ; attempt++ {
+		var res *O
+		var retry bool
+		res, retry, err = do[O](ctx, o, route, req)
+		if err == nil || !retry || attempt+1 >= attempts {
+			return res, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, errx.Wrap(ctx.Err()).AppendMsgf("call %s %s canceled", req.method, req.url).Err()
+		case <-time.After(o.delay(attempt)):
+		}
+	}
+}
+
+type request struct {
+	method      string
+	url         string
+	header      http.Header
+	body        []byte
+	contentType api.ContentType
+}
+
+func newRequest[I any](route api.Route, baseURL string, input I) (*request, errx.Error) {
+	method := route.Endpoint().Method()
+	req := &request{
+		method: string(method),
+		header: make(http.Header),
+	}
+	path := route.Path()
+	var query url.Values
+	if s, ok := getSerializer[I](route.RequestContentType()); ok {
+		data, err := s.Serialize(input)
+		if err != nil {
+			return nil, err
+		}
+		for k, vs := range data.Header {
+			for _, v := range vs {
+				req.header.Add(k, v)
+			}
+		}
+		query = data.Query
+		var missing string
+		path = util.PlaceholderRegex.ReplaceAllStringFunc(path, func(p string) string {
+			key := p[1 : len(p)-1]
+			v, ok := data.Path[key]
+			if !ok && missing == "" {
+				missing = key
+			}
+			return url.PathEscape(v)
+		})
+		if missing != "" {
+			return nil, errx.Newf("path parameter %s of %s is missing", missing, route.Path())
+		}
+		if method != api.MethodGet && data.Body != nil {
+			req.body = data.Body
+			req.contentType = route.RequestContentType()
+		}
+	}
+	if b, ok := any(input).([]byte); ok && method != api.MethodGet {
+		req.body = b
+		req.contentType = route.RequestContentType()
+	}
+	req.url = strings.TrimRight(baseURL, "/") + path
+	if len(query) > 0 {
+		req.url += "?" + query.Encode()
+	}
+	return req, nil
+}
+
+func writeRequestHeader(ctx ctxx.Context, h http.Header, extra http.Header) {
+	h.Set("X-Request-Id", strconv.FormatInt(int64(ctx.GetTraceID()), 10))
+	h.Set("X-Caller", ctx.GetCaller())
+	h.Set("X-Operator", ctx.GetOperator())
+	if l := ctx.GetLocale(); l != "" {
+		h.Set("X-Locale", string(l))
+		h.Set("Accept-Language", string(l))
+	}
+	for k, vs := range extra {
+		for _, v := range vs {
+			h.Add(k, v)
+		}
+	}
+}
+
+// do sends req once, retry reports whether the failure is worth another attempt.
+func do[O any](ctx ctxx.Context, o options, route api.Route, req *request) (res *O, retry bool, err errx.Error) {
+	var c context.Context = ctx
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		c, cancel = context.WithTimeout(c, o.timeout)
+		defer cancel()
+	}
+	var body io.Reader
+	if req.body != nil {
+		body = bytes.NewReader(req.body)
+	}
+	r, e := http.NewRequestWithContext(c, req.method, req.url, body)
+	if e != nil {
+		return nil, false, errx.Wrap(e).AppendMsgf("create request failed. target: %s", req.url).Err()
+	}
+	for k, vs := range req.header {
+		r.Header[k] = vs
+	}
+	if req.contentType != "" {
+		r.Header.Set("Content-Type", string(req.contentType))
+	}
+	resp, e := o.client.Do(r)
+	if e != nil {
+		t := errx.TypeNetwork
+		if errors.Is(e, context.DeadlineExceeded) {
+			t = errx.TypeTimeout
+		}
+		return nil, ctx.Err() == nil, errx.Wrap(e).WithType(t).AppendMsgf("call %s %s failed", req.method, req.url).Err()
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, e := io.ReadAll(resp.Body)
+	if e != nil {
+		return nil, true, errx.Wrap(e).WithType(errx.TypeNetwork).AppendMsgf("read response of %s %s failed", req.method, req.url).Err()
+	}
+	res, err = parseResponse[O](route, resp.StatusCode, data)
+	if err != nil {
+		switch {
+		case resp.StatusCode == http.StatusBadGateway, resp.StatusCode == http.StatusServiceUnavailable, resp.StatusCode == http.StatusGatewayTimeout:
+			retry = true
+		case err.Type() == errx.TypeRateLimit, err.Type() == errx.TypeTimeout, err.Type() == errx.TypeNetwork:
+			retry = true
+		}
+	}
+	return res, retry, err
+}
+
+func parseResponse[O any](route api.Route, status int, data []byte) (*O, errx.Error) {
+	contentType := route.ResponseContentType()
+	if contentType == api.ContentTypeApplicationJson && route.RequireWrapOutput() {
+		var w router.JsonResponseWrapper
+		if err := util.Json().Unmarshal(data, &w); err != nil {
+			if status >= http.StatusBadRequest {
+				return nil, statusError(status, data)
+			}
+			return nil, err
+		}
+		if !w.Success {
+			if w.Error == nil {
+				return nil, statusError(status, data)
+			}
+			return nil, detailsError(w.Error)
+		}
+		data = w.Data
+	} else if status >= http.StatusBadRequest {
+		return nil, statusError(status, data)
+	}
+	var output O
+	if len(data) == 0 || types.IsNilValue(output) {
+		return &output, nil
+	}
+	switch contentType {
+	case api.ContentTypeApplicationJson:
+		if err := util.Json().Unmarshal(data, &output); err != nil {
+			return nil, err
+		}
+	case api.ContentTypeApplicationFormUrlencoded:
+		if err := util.Form().Unmarshal(data, &output); err != nil {
+			return nil, err
+		}
+	default:
+		b, ok := any(&output).(*[]byte)
+		if !ok {
+			return nil, errx.Newf("output of %s must be []byte for content type %s", route.Path(), contentType)
+		}
+		*b = data
+	}
+	return &output, nil
+}
+
+func detailsError(d *router.ErrorDetails) errx.Error {
+	if len(d.Violations) > 0 {
+		return errx.Wrap(d.Violations).WithMsg(d.Message).WithType(d.Type).WithCode(d.Code).Err()
+	}
+	return errx.Define().WithMsg(d.Message).WithType(d.Type).WithCode(d.Code).Err()
+}
+
+// statusError maps the status of a response without error details to an error type.
+func statusError(status int, body []byte) errx.Error {
+	t := errx.TypeBusiness
+	switch {
+	case status == http.StatusUnauthorized:
+		t = errx.TypeAuthentication
+	case status == http.StatusForbidden:
+		t = errx.TypeAuthorization
+	case status == http.StatusNotFound:
+		t = errx.TypeNotFound
+	case status == http.StatusConflict:
+		t = errx.TypeConflict
+	case status == http.StatusTooManyRequests:
+		t = errx.TypeRateLimit
+	case status == http.StatusRequestTimeout, status == http.StatusGatewayTimeout:
+		t = errx.TypeTimeout
+	case status == http.StatusBadGateway, status == http.StatusServiceUnavailable:
+		t = errx.TypeNetwork
+	case status >= http.StatusInternalServerError:
+		t = errx.TypeInternal
+	}
+	msg := http.StatusText(status)
+	if len(body) > 0 && len(body) <= 512 {
+		msg = strings.TrimSpace(string(body))
+	}
+	return errx.Define().WithType(t).WithMsgf("http status %d: %s", status, msg).Err()
+}
@@ -0,0 +1,77 @@
+package restclient
+
+import (
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tencent-go/pkg/ctxx"
+	"github.com/tencent-go/pkg/errx"
+	"github.com/tencent-go/pkg/rest/api"
+	"github.com/tencent-go/pkg/rest/router"
+	"github.com/tencent-go/pkg/types"
+	"github.com/tencent-go/pkg/validation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCall(t *testing.T) {
+	type Input struct {
+		ID    string `path:"id"`
+		Page  int    `query:"page"`
+		Value string `json:"value" validate:"min=2"`
+	}
+	type Output struct {
+		ID      string   `json:"id"`
+		Page    int      `json:"page"`
+		Value   string   `json:"value"`
+		TraceID types.ID `json:"traceId"`
+	}
+
+	update := api.NewEndpoint[Input, Output]().WithPath("items/{id}").WithName("update").WithMethod(api.MethodPut)
+	flaky := api.NewEndpoint[struct{}, Output]().WithPath("flaky").WithName("flaky").WithMethod(api.MethodGet)
+	group := api.DefaultGroup().WithPath("api/v1").WithRequireAuthentication(false).WithChildren(update, flaky)
+
+	r := router.NewWithDefaultMiddlewares()
+	r.AddNodes(group)
+	router.RegisterEndpointHandler(r, update, func(ctx router.Context, params Input) (*Output, errx.Error) {
+		return &Output{ID: params.ID, Page: params.Page, Value: params.Value, TraceID: ctx.GetTraceID()}, nil
+	})
+	var calls atomic.Int32
+	router.RegisterEndpointHandler(r, flaky, func(ctx router.Context, params struct{}) (*Output, errx.Error) {
+		if calls.Add(1) < 3 {
+			return nil, errx.Define().WithType(errx.TypeRateLimit).WithMsg("slow down").Err()
+		}
+		return &Output{}, nil
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	Register(group)
+
+	t.Run("request and response", func(t *testing.T) {
+		ctx := ctxx.Background()
+		res, err := Call(ctx, srv.URL, update, Input{ID: "a b", Page: 2, Value: "hello"})
+		assert.NoError(t, err)
+		assert.Equal(t, Output{ID: "a b", Page: 2, Value: "hello", TraceID: ctx.GetTraceID()}, *res)
+	})
+
+	t.Run("error details", func(t *testing.T) {
+		_, err := Call(ctxx.Background(), srv.URL, update, Input{ID: "1", Page: 1, Value: "x"})
+		assert.Error(t, err)
+		assert.Equal(t, errx.TypeValidation, err.Type())
+		vs := validation.GetViolations(err)
+		if assert.Len(t, vs, 1) {
+			assert.Equal(t, "value", vs[0].Field)
+			assert.Equal(t, "min", vs[0].Rule)
+		}
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		_, err := Call(ctxx.Background(), srv.URL, flaky, struct{}{}, WithRetry(1, time.Millisecond))
+		assert.Error(t, err)
+		assert.Equal(t, errx.TypeRateLimit, err.Type())
+		_, err = Call(ctxx.Background(), srv.URL, flaky, struct{}{}, WithRetry(2, time.Millisecond))
+		assert.NoError(t, err)
+		assert.Equal(t, int32(3), calls.Load())
+	})
+}
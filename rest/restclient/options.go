@@ -0,0 +1,100 @@
+package restclient
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/tencent-go/pkg/rest/api"
+)
+
+const (
+	defaultTimeout = 8 * time.Second
+	defaultBackoff = 200 * time.Millisecond
+	maxBackoff     = 5 * time.Second
+)
+
+type options struct {
+	client         *http.Client
+	timeout        time.Duration
+	retries        int
+	backoff        time.Duration
+	retryAnyMethod bool
+	header         http.Header
+}
+
+type Option func(*options)
+
+// WithHttpClient replaces http.DefaultClient.
+func WithHttpClient(client *http.Client) Option {
+	return func(o *options) {
+		o.client = client
+	}
+}
+
+// WithTimeout bounds every attempt, the default is 8s, a negative value disables it.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithRetry retries up to retries times when the server is unreachable, answers 502, 503 or 504,
+// or reports a rate_limit, timeout or network error. The delay starts at backoff and doubles on
+// every attempt. Only GET, PUT and DELETE are retried unless RetryAnyMethod is given.
+func WithRetry(retries int, backoff time.Duration) Option {
+	return func(o *options) {
+		o.retries = retries
+		o.backoff = backoff
+	}
+}
+
+// RetryAnyMethod also retries POST and PATCH, use it for endpoints which are idempotent.
+func RetryAnyMethod() Option {
+	return func(o *options) {
+		o.retryAnyMethod = true
+	}
+}
+
+// WithHeader adds a header to the request, e.g. Authorization.
+func WithHeader(key, value string) Option {
+	return func(o *options) {
+		if o.header == nil {
+			o.header = make(http.Header)
+		}
+		o.header.Add(key, value)
+	}
+}
+
+func getOptions(opts ...Option) options {
+	o := options{
+		client:  http.DefaultClient,
+		timeout: defaultTimeout,
+		backoff: defaultBackoff,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func (o options) retryable(method api.Method) bool {
+	if o.retryAnyMethod {
+		return true
+	}
+	switch method {
+	case api.MethodGet, api.MethodPut, api.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func (o options) delay(attempt int) time.Duration {
+	if o.backoff <= 0 {
+		return 0
+	}
+	d := o.backoff << attempt
+	if d < o.backoff || d > maxBackoff {
+		return maxBackoff
+	}
+	return d
+}
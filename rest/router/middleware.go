@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/tencent-go/pkg/errx"
+	"github.com/tencent-go/pkg/validation"
 	"github.com/sirupsen/logrus"
 )
 
@@ -47,9 +48,10 @@ func LoggerMiddleware() HandlerFunc {
 }
 
 type ErrorDetails struct {
-	Code    int       `json:"code"`
-	Message string    `json:"message"`
-	Type    errx.Type `json:"type"`
+	Code       int                   `json:"code"`
+	Message    string                `json:"message"`
+	Type       errx.Type             `json:"type"`
+	Violations validation.Violations `json:"violations,omitempty"`
 }
 
 type JsonResponseWrapper struct {
@@ -94,7 +96,8 @@ func JsonResponseWrapMiddleware() HandlerFunc {
 						Type: err.Type(),
 					}
 					if err.Type() != errx.TypeInternal {
-						ed.Message = err.Error()
+						ed.Message = errx.Localize(err, ctx.GetLocale())
+						ed.Violations = validation.GetViolations(err).Localized(ctx.GetLocale())
 					}
 					w.Error = ed
 				}
@@ -3,11 +3,13 @@ package router
 import (
 	"github.com/sirupsen/logrus"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/tencent-go/pkg/ctxx"
 	"github.com/tencent-go/pkg/errx"
 	"github.com/tencent-go/pkg/rest/api"
+	"github.com/tencent-go/pkg/types"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
 )
@@ -64,6 +66,18 @@ func noHandler(ctx Context) {
 	ctx.State().HttpStatus = http.StatusServiceUnavailable
 }
 
+// requestMetadata continues the trace of a caller such as restclient.Call.
+func requestMetadata(request *http.Request) ctxx.Metadata {
+	m := ctxx.Metadata{
+		Locale: types.ParseAcceptLanguage(request.Header.Get("Accept-Language")),
+		Caller: request.Header.Get("X-Caller"),
+	}
+	if id, err := strconv.ParseInt(request.Header.Get("X-Request-Id"), 10, 64); err == nil {
+		m.TraceID = types.ID(id)
+	}
+	return m
+}
+
 func (r *router) GetRoute(endpoint api.Endpoint) (api.Route, bool) {
 	for _, route := range r.rootGroup.Routes() {
 		if route.Endpoint() == endpoint {
@@ -139,9 +153,7 @@ func (r *router) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 
 	// 创建上下文
 	ctx := &context{
-		Context: ctxx.WithMetadata(request.Context(), ctxx.Metadata{
-			Locale: language2Locale(request.Header.Get("Accept-Language")),
-		}),
+		Context:      ctxx.WithMetadata(request.Context(), requestMetadata(request)),
 		MatchedRoute: matchedRoute,
 		request:      request,
 		response:     &responseWriter{ResponseWriter: writer},
@@ -2,15 +2,12 @@ package router
 
 import (
 	"bytes"
-	"fmt"
 	"io"
 	"net/http"
 	"reflect"
-	"sort"
 	"strings"
 	"sync"
 
-	"github.com/tencent-go/pkg/types"
 	"github.com/tencent-go/pkg/util"
 	"github.com/tencent-go/pkg/validation"
 	"github.com/sirupsen/logrus"
@@ -19,51 +16,6 @@ import (
 	"github.com/tencent-go/pkg/rest/api"
 )
 
-func language2Locale(value string) types.Locale {
-	if value == "" {
-		return ""
-	}
-
-	type item struct {
-		l types.Locale
-		w float64
-	}
-
-	var items []item
-	parts := strings.Split(value, ",")
-
-	for _, part := range parts {
-		langAndQuality := strings.Split(strings.TrimSpace(part), ";")
-		if len(langAndQuality) == 0 {
-			continue
-		}
-
-		lang := types.Locale(langAndQuality[0])
-		if ok := lang.Enum().Contains(lang); !ok {
-			continue
-		}
-
-		weight := 1.0
-		if len(langAndQuality) > 1 && strings.HasPrefix(langAndQuality[1], "q=") {
-			if _, e := fmt.Sscanf(langAndQuality[1], "q=%f", &weight); e != nil {
-				continue
-			}
-		}
-
-		items = append(items, item{l: lang, w: weight})
-	}
-
-	sort.Slice(items, func(i, j int) bool {
-		return items[i].w > items[j].w
-	})
-
-	if len(items) > 0 {
-		return items[0].l
-	}
-
-	return ""
-}
-
 var apiHeaderSerializer = sync.OnceValue(func() util.HttpSerializer[api.HeaderParams] {
 	res, _ := util.NewHttpSerializer[api.HeaderParams](util.TagHeader)
 	return res
@@ -141,7 +93,7 @@ func NewEndpointHandler[I, O any](endpoint api.EndpointBuilder[I, O], handler fu
 			s, _ := util.NewHttpSerializer[I](t, util.TagHeader, util.TagPath, util.TagQuery)
 			return s
 		})
-		validate, _, _ = validation.GetOrCreateValidator(endpoint.InputType(), validation.WithLabelTags("query", "path", string(t)))
+		validate, _, _ = validation.GetOrCreateValidator(endpoint.InputType(), validation.WithLabelTags("query", "path", string(t)), validation.CollectAll())
 		return s
 	}
 
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -21,8 +22,7 @@ import (
 	"golang.org/x/net/http2"
 )
 
-func getURL(o options) (string, errx.Error) {
-	etcd := o.etcd
+func getDiscover(etcd *clientv3.Client) *discover {
 	if etcd == nil {
 		etcd = defaultEtcd()
 	}
@@ -32,6 +32,30 @@ func getURL(o options) (string, errx.Error) {
 			return newDiscover(etcd)
 		})
 	}
+	return d
+}
+
+// ListServices returns the names of the online services handling the method path, sorted.
+func ListServices(path string, etcd ...*clientv3.Client) []string {
+	var c *clientv3.Client
+	if len(etcd) > 0 {
+		c = etcd[0]
+	}
+	services, _ := getDiscover(c).find(strings.Trim(path, "/"), "")
+	seen := map[string]bool{}
+	var res []string
+	for _, s := range services {
+		if !seen[s.serviceName] {
+			seen[s.serviceName] = true
+			res = append(res, s.serviceName)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+func getURL(o options) (string, errx.Error) {
+	d := getDiscover(o.etcd)
 	services, ok := d.find(o.path, o.serviceName)
 	if !ok {
 		return "", errx.Newf("rpc service %s not found", o.path)
@@ -11,6 +11,7 @@ import (
 	"sync"
 
 	"github.com/tencent-go/pkg/errx"
+	"github.com/tencent-go/pkg/types"
 	"github.com/sirupsen/logrus"
 	"github.com/vmihailenco/msgpack/v5"
 )
@@ -48,17 +49,17 @@ func ProxyRoute[I, O any](path string, met Method[I, O], description ...string)
 		handler: func(w http.ResponseWriter, r *http.Request) {
 			u, err := met.GetURL()
 			if err != nil {
-				WriteError(w, err)
+				WriteError(w, err, requestLocale(r))
 				return
 			}
 			req, e := http.NewRequestWithContext(r.Context(), "POST", u, r.Body)
 			if e != nil {
-				WriteError(w, errx.Wrap(e).Err())
+				WriteError(w, errx.Wrap(e).Err(), requestLocale(r))
 				return
 			}
 			res, e := httpClient.Do(req)
 			if e != nil {
-				WriteError(w, errx.Wrap(e).Err())
+				WriteError(w, errx.Wrap(e).Err(), requestLocale(r))
 				return
 			}
 			defer func() {
@@ -72,7 +73,7 @@ func ProxyRoute[I, O any](path string, met Method[I, O], description ...string)
 			w.WriteHeader(res.StatusCode)
 			if res.StatusCode >= 500 {
 				w.Header().Del("Content-Length")
-				WriteError(w, errx.Internal.Err())
+				WriteError(w, errx.Internal.Err(), requestLocale(r))
 				return
 			}
 			if _, e = io.Copy(w, res.Body); e != nil {
@@ -134,7 +135,7 @@ type gateway struct {
 func (g *gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	item, ok := g.routeByPath.Load(r.URL.Path)
 	if !ok {
-		WriteError(w, errx.NotFound.WithMsgf("path [%s] not found", r.URL.Path).Err())
+		WriteError(w, errx.NotFound.WithMsgf("path [%s] not found", r.URL.Path).Err(), requestLocale(r))
 		return
 	}
 	matchedRoute := item.(*pathRoute)
@@ -146,6 +147,14 @@ func (g *gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	matchedRoute.route.ServeHTTP(w, r)
 }
 
+// requestLocale prefers the locale forwarded by rpc callers over the Accept-Language header of frontends.
+func requestLocale(r *http.Request) types.Locale {
+	if l := r.Header.Get("rpc-locale"); l != "" {
+		return types.Locale(l)
+	}
+	return types.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+}
+
 func (g *gateway) Groups() []Group {
 	return g.groups
 }
@@ -234,7 +243,7 @@ func NewJsonTranscoder(dst string) http.HandlerFunc {
 		if resp.ContentLength != 0 {
 			var v any
 			if e = msgpack.NewDecoder(resp.Body).Decode(&v); e != nil {
-				WriteError(w, errx.Wrap(e).Err())
+				WriteError(w, errx.Wrap(e).Err(), requestLocale(r))
 				return
 			}
 			resBody, e = json.Marshal(v)
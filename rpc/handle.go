@@ -125,7 +125,7 @@ func (s *httpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 var httpServers = util.LazyMap[int, *httpServer]{}
 
 func newHttpHandler[I, O any](handler Handler[I, O], timeout time.Duration) http.HandlerFunc {
-	validate, shouldValidate, _ := validation.GetOrCreateValidator(reflect.TypeOf(new(I)))
+	validate, shouldValidate, _ := validation.GetOrCreateValidator(reflect.TypeOf(new(I)), validation.WithNameTag("json"), validation.CollectAll())
 	return func(w http.ResponseWriter, req *http.Request) {
 		log := logrus.WithField("path", req.URL.Path)
 		w.Header().Set("Content-Type", "application/msgpack")
@@ -140,13 +140,13 @@ func newHttpHandler[I, O any](handler Handler[I, O], timeout time.Duration) http
 		input, err := ReadRequestBody[I](req)
 		if err != nil {
 			log.WithError(err).Error("read data failed")
-			WriteError(w, err)
+			WriteError(w, err, ctx.GetLocale())
 			return
 		}
 		if shouldValidate {
 			if err = validate(input); err != nil {
 				log.WithError(err).Error("validate failed")
-				WriteError(w, err)
+				WriteError(w, err, ctx.GetLocale())
 				return
 			}
 		}
@@ -163,7 +163,7 @@ func newHttpHandler[I, O any](handler Handler[I, O], timeout time.Duration) http
 		}
 
 		if err != nil {
-			WriteError(w, err)
+			WriteError(w, err, ctx.GetLocale())
 			if err.Type() == errx.TypeInternal {
 				log.WithError(err).Error("handle rpc request failed")
 			} else {
@@ -0,0 +1,56 @@
+package rpc
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tencent-go/pkg/errx"
+	"github.com/tencent-go/pkg/validation"
+)
+
+type listFilter struct {
+	Name string `json:"name" validate:"required=false,max=3"`
+}
+
+type listInput struct {
+	Page    int        `json:"page"`
+	Active  bool       `json:"active"`
+	Keyword string     `json:"keyword"`
+	Size    int        `json:"size" validate:"required=false,max=100"`
+	Filter  listFilter `json:"filter" validate:"dive"`
+}
+
+func TestHttpHandler(t *testing.T) {
+	handler := newHttpHandler(func(ctx Context, params listInput) (*int, errx.Error) {
+		return &params.Page, nil
+	}, 0)
+	call := func(input listInput) (*int, errx.Error) {
+		body, err := NewRequestBody(input)
+		if err != nil {
+			return nil, err
+		}
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest("POST", "/test/list", body))
+		return ParseResponse[int](w.Result())
+	}
+
+	t.Run("零值輸入", func(t *testing.T) {
+		res, err := call(listInput{})
+		if assert.NoError(t, err) {
+			assert.Equal(t, 0, *res)
+		}
+	})
+
+	t.Run("違規路徑使用json名稱", func(t *testing.T) {
+		_, err := call(listInput{Page: 1, Size: 200, Filter: listFilter{Name: "long"}})
+		if assert.Error(t, err) {
+			assert.Equal(t, errx.TypeValidation, err.Type())
+			var fields []string
+			for _, v := range validation.GetViolations(err) {
+				fields = append(fields, v.Field)
+			}
+			assert.Equal(t, []string{"size", "filter.name"}, fields)
+		}
+	})
+}
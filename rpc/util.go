@@ -9,11 +9,13 @@ import (
   "github.com/tencent-go/pkg/ctxx"
   "github.com/tencent-go/pkg/errx"
   "github.com/tencent-go/pkg/types"
+  "github.com/tencent-go/pkg/validation"
   "github.com/sirupsen/logrus"
   "github.com/vmihailenco/msgpack/v5"
 )
 
-func WriteError(w http.ResponseWriter, err errx.Error) {
+// WriteError writes err as an ErrorDetail, the message is rendered in locale when given.
+func WriteError(w http.ResponseWriter, err errx.Error, locale ...types.Locale) {
   switch err.Type() {
   case errx.TypeAuthorization:
     w.WriteHeader(http.StatusForbidden)
@@ -24,10 +26,15 @@ func WriteError(w http.ResponseWriter, err errx.Error) {
   default:
     w.WriteHeader(http.StatusBadGateway)
   }
+  var l types.Locale
+  if len(locale) > 0 {
+    l = locale[0]
+  }
   detail := ErrorDetail{
-    Message: err.Error(),
-    Type:    err.Type(),
-    Code:    err.Code(),
+    Message:    errx.Localize(err, l),
+    Type:       err.Type(),
+    Code:       err.Code(),
+    Violations: validation.GetViolations(err).Localized(l),
   }
   encoder := msgpack.GetEncoder()
   defer msgpack.PutEncoder(encoder)
@@ -108,6 +115,9 @@ func ParseResponse[T any](resp *http.Response) (*T, errx.Error) {
     if e := decoder.Decode(&detail); e != nil {
       return nil, errx.Wrap(e).Err()
     }
+    if len(detail.Violations) > 0 {
+      return nil, errx.Wrap(detail.Violations).WithMsg(detail.Message).WithType(detail.Type).WithCode(detail.Code).Err()
+    }
     return nil, errx.Define().WithMsg(detail.Message).WithType(detail.Type).WithCode(detail.Code).Err()
   }
   var output T
@@ -128,7 +138,8 @@ func ParseResponse[T any](resp *http.Response) (*T, errx.Error) {
 }
 
 type ErrorDetail struct {
-  Message string    `json:"message"`
-  Type    errx.Type `json:"type"`
-  Code    int       `json:"code"`
+  Message    string                `json:"message"`
+  Type       errx.Type             `json:"type"`
+  Code       int                   `json:"code"`
+  Violations validation.Violations `json:"violations,omitempty"`
 }
@@ -1,6 +1,10 @@
 package types
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
 	"github.com/tencent-go/pkg/errx"
 )
 
@@ -89,6 +93,56 @@ func (l Locale) Enum() Enum {
 	return RegisterEnum(DefaultLocale, ZhCN, ZhTW, ZhHK, EnUS, EnGB, EnAU, EnCA, EnIN, FrFR, FrCA, DeDE, FilPH, DeCH, EsES, EsMX, EsUS, JaJP, KoKR, RuRU, PtBR, PtPT, ArSA, ArEG, HiIN, ItIT, ItCH, NlNL, NlBE, PlPL, ViVN, ThTH, ElGR, TrTR, SvSE)
 }
 
+func (l Locale) String() string {
+	return string(l)
+}
+
+// ParseAcceptLanguage 解析 Accept-Language 標頭，回傳權重最高且已支援的地區設定
+func ParseAcceptLanguage(value string) Locale {
+	if value == "" {
+		return ""
+	}
+
+	type item struct {
+		l Locale
+		w float64
+	}
+
+	var items []item
+	parts := strings.Split(value, ",")
+
+	for _, part := range parts {
+		langAndQuality := strings.Split(strings.TrimSpace(part), ";")
+		if len(langAndQuality) == 0 {
+			continue
+		}
+
+		lang := Locale(langAndQuality[0])
+		if ok := lang.Enum().Contains(lang); !ok {
+			continue
+		}
+
+		weight := 1.0
+		if len(langAndQuality) > 1 && strings.HasPrefix(langAndQuality[1], "q=") {
+			if _, e := fmt.Sscanf(langAndQuality[1], "q=%f", &weight); e != nil {
+				continue
+			}
+		}
+
+		items = append(items, item{l: lang, w: weight})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].w > items[j].w
+	})
+
+	if len(items) > 0 {
+		return items[0].l
+	}
+
+	return ""
+}
+
 type LocalizedValues[T any] map[Locale]T
 
 func (v LocalizedValues[T]) Get(l Locale) T {
@@ -10,10 +10,11 @@ type CursorQuery[T any] struct {
 }
 
 type CursorQueryResult[Q any, T any] struct {
-	List    []T  `json:"list"`
-	Query   Q    `json:"query"`
-	HasMore bool `json:"hasMore"`
-	Cursor  any  `json:"cursor,omitempty"`
+	List       []T  `json:"list"`
+	Query      Q    `json:"query"`
+	HasMore    bool `json:"hasMore"`              // 按查詢方向是否還有更多數據
+	Cursor     any  `json:"cursor,omitempty"`     // 下一頁
+	PrevCursor any  `json:"prevCursor,omitempty"` // 上一頁
 }
 
 type Sortable interface {
@@ -13,10 +13,12 @@ import (
 
 type Config struct {
 	required           bool
+	name               string
 	label              string
 	mapConfig          *MapConfig
 	arrayConfig        *Config
 	structFieldsConfig []StructFieldConfig
+	structValidatable  bool
 	validators         []Validator
 }
 
@@ -28,19 +30,29 @@ func (c *Config) GetLabel() string {
 	return c.label
 }
 
+// GetName returns the field name from the label tag, it is the segment of Violation.Field.
+func (c *Config) GetName() string {
+	if c.name == "" {
+		return c.label
+	}
+	return c.name
+}
+
 type MapConfig struct {
 	KeyConfig   *Config
 	ValueConfig *Config
 }
 
 type StructFieldConfig struct {
-	Index  int
-	Config *Config
+	Index      int
+	Config     *Config
+	Validators []FieldValidator // cross-field rules, run after Config passed
 }
 
 type options struct {
 	alwaysValidate bool
-	labelTags      map[string]bool
+	collectAll     bool
+	labelTags      map[string]bool // false for the tags of WithNameTag
 }
 
 type Option func(*options)
@@ -51,6 +63,13 @@ func AlwaysValidate() Option {
 	}
 }
 
+// CollectAll keeps validating after the first failure, the error then lists every Violation.
+func CollectAll() Option {
+	return func(o *options) {
+		o.collectAll = true
+	}
+}
+
 func WithLabelTag(labelTag string) Option {
 	return func(o *options) {
 		o.labelTags[labelTag] = true
@@ -65,6 +84,16 @@ func WithLabelTags(labelTags ...string) Option {
 	}
 }
 
+// WithNameTag names the fields in labels and violation paths after labelTag. Unlike WithLabelTag, a
+// field carrying only labelTag is not validated, and omitempty and "-" in it are ignored.
+func WithNameTag(labelTag string) Option {
+	return func(o *options) {
+		if _, ok := o.labelTags[labelTag]; !ok {
+			o.labelTags[labelTag] = false
+		}
+	}
+}
+
 func GetOrCreateValidator(typ reflect.Type, opts ...Option) (func(value any) errx.Error, bool, errx.Error) {
 	if typ.Implements(validatableInterface) {
 		return func(value any) errx.Error {
@@ -78,6 +107,11 @@ func GetOrCreateValidator(typ reflect.Type, opts ...Option) (func(value any) err
 	if !ok {
 		return nil, false, nil
 	}
+	if getOptions(opts...).collectAll {
+		return func(value any) errx.Error {
+			return config.ValidateAll(reflect.ValueOf(value))
+		}, true, nil
+	}
 	return func(value any) errx.Error {
 		return config.Validate(reflect.ValueOf(value))
 	}, true, nil
@@ -92,13 +126,18 @@ var sortedTags = map[string]int{
 	"form":   5,
 }
 
-func getOrCreateConfig(typ reflect.Type, opts ...Option) (*Config, bool, errx.Error) {
+func getOptions(opts ...Option) *options {
 	o := &options{
 		labelTags: map[string]bool{},
 	}
 	for _, opt := range opts {
 		opt(o)
 	}
+	return o
+}
+
+func getOrCreateConfig(typ reflect.Type, opts ...Option) (*Config, bool, errx.Error) {
+	o := getOptions(opts...)
 	for typ.Kind() == reflect.Ptr {
 		typ = typ.Elem()
 	}
@@ -106,7 +145,10 @@ func getOrCreateConfig(typ reflect.Type, opts ...Option) (*Config, bool, errx.Er
 	var key string
 	if len(o.labelTags) > 0 {
 		tags := make([]string, 0, len(o.labelTags))
-		for t := range o.labelTags {
+		for t, validates := range o.labelTags {
+			if !validates {
+				t += "(name)"
+			}
 			tags = append(tags, t)
 		}
 		sort.Strings(tags)
@@ -136,7 +178,7 @@ func getOrCreateConfig(typ reflect.Type, opts ...Option) (*Config, bool, errx.Er
 var cachedConfig = util.LazyMap[string, *Config]{}
 
 func (c *Config) hasValidators() bool {
-	return len(c.validators) > 0 || c.mapConfig != nil || c.arrayConfig != nil || c.structFieldsConfig != nil
+	return len(c.validators) > 0 || c.mapConfig != nil || c.arrayConfig != nil || c.structFieldsConfig != nil || c.structValidatable
 }
 
 var validatableInterface = reflect.TypeOf((*Validatable)(nil)).Elem()
@@ -162,7 +204,7 @@ func CreateConfig(typ reflect.Type, rule Rule, alwaysValidate bool, labelTags ma
 	if rule.Required != nil {
 		res.required = *rule.Required
 	} else {
-		res.required = true
+		res.required = rule.RequiredIf == nil && rule.RequiredWithout == nil
 	}
 	for typ.Kind() == reflect.Ptr {
 		typ = typ.Elem()
@@ -190,6 +232,7 @@ func CreateConfig(typ reflect.Type, rule Rule, alwaysValidate bool, labelTags ma
 	}
 
 	if typ.Kind() == reflect.Struct {
+		res.structValidatable = typ.Implements(structValidatableInterface) || reflect.PointerTo(typ).Implements(structValidatableInterface)
 		res.structFieldsConfig, err = CreateStructConfig(typ, alwaysValidate, labelTags)
 		return
 	}
@@ -249,17 +292,14 @@ func CreateStructConfig(typ reflect.Type, alwaysValidate bool, labelTags map[str
 	for i := 0; i < typ.NumField(); i++ {
 		field := typ.Field(i)
 		if len(labelTags) == 0 {
-			fieldConfig, ok, err := CreateStructFieldConfig(field, alwaysValidate, "")
+			c, ok, err := createStructFieldConfig(typ, field, alwaysValidate, "", false)
 			if err != nil {
 				return nil, err
 			}
 			if !ok {
 				continue
 			}
-			c := &StructFieldConfig{
-				Index:  i,
-				Config: fieldConfig,
-			}
+			c.Index = i
 			res = append(res, *c)
 		} else {
 			tags := make([]string, 0, len(labelTags))
@@ -277,18 +317,21 @@ func CreateStructConfig(typ reflect.Type, alwaysValidate bool, labelTags map[str
 				}
 				return oi < oj
 			})
+			// 欄位帶有的標籤優先, 皆無時才以Go欄位名稱命名
+			sort.SliceStable(tags, func(i, j int) bool {
+				_, oi := field.Tag.Lookup(tags[i])
+				_, oj := field.Tag.Lookup(tags[j])
+				return oi && !oj
+			})
 			for _, tag := range tags {
-				fieldConfig, ok, err := CreateStructFieldConfig(field, alwaysValidate, tag)
+				c, ok, err := createStructFieldConfig(typ, field, alwaysValidate, tag, labelTags[tag])
 				if err != nil {
 					return nil, err
 				}
 				if !ok {
 					continue
 				}
-				c := &StructFieldConfig{
-					Index:  i,
-					Config: fieldConfig,
-				}
+				c.Index = i
 				res = append(res, *c)
 				break //僅作用與第一個
 			}
@@ -298,6 +341,16 @@ func CreateStructConfig(typ reflect.Type, alwaysValidate bool, labelTags map[str
 }
 
 func CreateStructFieldConfig(field reflect.StructField, alwaysValidate bool, labelTag string) (*Config, bool, errx.Error) {
+	c, ok, err := createStructFieldConfig(nil, field, alwaysValidate, labelTag, labelTag != "")
+	if !ok || err != nil || c.Config == nil {
+		return nil, false, err
+	}
+	return c.Config, true, nil
+}
+
+// createStructFieldConfig also compiles the cross-field rules of field when parent is given. The label tag
+// only names the field unless validates is true.
+func createStructFieldConfig(parent reflect.Type, field reflect.StructField, alwaysValidate bool, labelTag string, validates bool) (*StructFieldConfig, bool, errx.Error) {
 	if !field.IsExported() {
 		return nil, false, nil
 	}
@@ -312,7 +365,7 @@ func CreateStructFieldConfig(field reflect.StructField, alwaysValidate bool, lab
 		if len(vTag) > 0 && (vTag[0] == '-' || strings.HasPrefix(vTag, "ignore")) {
 			ignore = true
 		}
-		if len(lTag) > 0 && lTag[0] == '-' {
+		if validates && len(lTag) > 0 && lTag[0] == '-' {
 			ignore = true
 		}
 		if ignore {
@@ -321,7 +374,7 @@ func CreateStructFieldConfig(field reflect.StructField, alwaysValidate bool, lab
 	}
 	labelTags := map[string]bool{}
 	if labelTag != "" {
-		labelTags[field.Name] = true
+		labelTags[labelTag] = validates
 	}
 	if field.Anonymous {
 		fields, err := CreateStructConfig(field.Type, alwaysValidate, labelTags)
@@ -331,23 +384,27 @@ func CreateStructFieldConfig(field reflect.StructField, alwaysValidate bool, lab
 		if len(fields) == 0 {
 			return nil, false, nil
 		}
-		return &Config{
+		return &StructFieldConfig{Config: &Config{
 			structFieldsConfig: fields,
-		}, true, nil
+		}}, true, nil
 	}
 
-	if !alwaysValidate && vTag == "" && lTag == "" {
+	if !alwaysValidate && vTag == "" && (lTag == "" || !validates) {
 		return nil, false, nil
 	}
 
 	rule := Rule{}
+	name := field.Name
 	if lTag != "" {
-		if strings.HasSuffix(lTag, ",omitempty") {
-			lTag = lTag[:len(lTag)-len(",omitempty")]
+		if name, opts, _ := strings.Cut(lTag, ","); !validates {
+			lTag = strings.TrimPrefix(name, "-")
+		} else if opts == "omitempty" {
+			lTag = name
 			rule.SetRequired(false)
 		}
 		if len(lTag) > 0 {
 			rule.SetLabel(lTag)
+			name = lTag
 		}
 	}
 	if vTag != "" {
@@ -358,5 +415,25 @@ func CreateStructFieldConfig(field reflect.StructField, alwaysValidate bool, lab
 	if rule.Label == nil {
 		rule.SetLabel(field.Name)
 	}
-	return CreateConfig(field.Type, rule, alwaysValidate, labelTags)
+	c, ok, err := CreateConfig(field.Type, rule, alwaysValidate, labelTags)
+	if err != nil {
+		return nil, false, err
+	}
+	res := &StructFieldConfig{}
+	if ok {
+		c.name = name
+		res.Config = c
+	}
+	if parent != nil {
+		if res.Validators, err = createFieldValidators(parent, field, &rule); err != nil {
+			return nil, false, err
+		}
+	}
+	if res.Config == nil && len(res.Validators) == 0 {
+		return nil, false, nil
+	}
+	if res.Config == nil {
+		res.Config = &Config{name: name, label: *rule.Label}
+	}
+	return res, true, nil
 }
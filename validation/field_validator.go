@@ -0,0 +1,238 @@
+package validation
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tencent-go/pkg/errx"
+	"github.com/shopspring/decimal"
+)
+
+// FieldValidator validates a struct field together with parent, the struct value holding it.
+type FieldValidator func(value reflect.Value, parent reflect.Value) errx.Error
+
+// FieldValidatorBuilder compiles the cross-field rules of field, parent is the type of the struct
+// holding it. Like ValidatorBuilder it reports false when the rule does not apply.
+type FieldValidatorBuilder func(parent reflect.Type, field reflect.StructField, rule *Rule) (FieldValidator, bool)
+
+var defaultFieldValidatorBuilders = []FieldValidatorBuilder{
+	requiredIfValidatorBuilder,
+	requiredWithoutValidatorBuilder,
+	eqFieldValidatorBuilder,
+	gtFieldValidatorBuilder,
+}
+
+var customFieldValidatorBuilders []FieldValidatorBuilder
+
+// RegisterFieldValidatorBuilder is the cross-field counterpart of RegisterValidatorBuilder.
+func RegisterFieldValidatorBuilder(builder FieldValidatorBuilder) {
+	customFieldValidatorBuilders = append(customFieldValidatorBuilders, builder)
+}
+
+// StructValidatable is called with the whole struct after its fields passed validation. Returning
+// Violations (see Violations.Err) reports them relative to the struct.
+type StructValidatable interface {
+	ValidateStruct() errx.Error
+}
+
+var structValidatableInterface = reflect.TypeOf((*StructValidatable)(nil)).Elem()
+
+func createFieldValidators(parent reflect.Type, field reflect.StructField, rule *Rule) ([]FieldValidator, errx.Error) {
+	for _, name := range []*string{rule.EqField, rule.GtField, rule.RequiredWithout} {
+		if name != nil {
+			if _, ok := parent.FieldByName(*name); !ok {
+				return nil, errx.Newf("Invalid validate tag of %s.%s, field %s not found", parent.Name(), field.Name, *name)
+			}
+		}
+	}
+	if rule.RequiredIf != nil {
+		if _, ok := parent.FieldByName(rule.RequiredIf.Field); !ok {
+			return nil, errx.Newf("Invalid validate tag of %s.%s, field %s not found", parent.Name(), field.Name, rule.RequiredIf.Field)
+		}
+	}
+	// 型別不符時規則永不成立, 建立時即拒絕
+	if rule.EqField != nil {
+		sibling, _ := parent.FieldByName(*rule.EqField)
+		if a, b := elemType(field.Type), elemType(sibling.Type); a != b {
+			return nil, errx.Newf("Invalid validate tag of %s.%s, field %s of type %s can't equal %s", parent.Name(), field.Name, *rule.EqField, b, a)
+		}
+	}
+	if rule.GtField != nil {
+		sibling, _ := parent.FieldByName(*rule.GtField)
+		if a, b := elemType(field.Type), elemType(sibling.Type); !orderable(a, b) {
+			return nil, errx.Newf("Invalid validate tag of %s.%s, field %s of type %s is not comparable with %s", parent.Name(), field.Name, *rule.GtField, b, a)
+		}
+	}
+	var res []FieldValidator
+	for _, builder := range slices.Concat(customFieldValidatorBuilders, defaultFieldValidatorBuilders) {
+		if v, ok := builder(parent, field, rule); ok {
+			res = append(res, v)
+		}
+	}
+	return res, nil
+}
+
+// siblingValue returns the dereferenced sibling field, false when it or an embedded pointer on its way is nil.
+func siblingValue(parent reflect.Value, index []int) (reflect.Value, bool) {
+	v, err := parent.FieldByIndexErr(index)
+	if err != nil {
+		return reflect.Value{}, false
+	}
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}, false
+		}
+		v = v.Elem()
+	}
+	return v, true
+}
+
+func requiredIfValidatorBuilder(parent reflect.Type, _ reflect.StructField, rule *Rule) (FieldValidator, bool) {
+	if rule.RequiredIf == nil {
+		return nil, false
+	}
+	cond := *rule.RequiredIf
+	sibling, _ := parent.FieldByName(cond.Field)
+	params := map[string]string{"field": cond.Field, "values": strings.Join(cond.Values, "|")}
+	return func(value reflect.Value, p reflect.Value) errx.Error {
+		s, ok := siblingValue(p, sibling.Index)
+		if !ok || !slices.Contains(cond.Values, fmt.Sprint(s.Interface())) {
+			return nil
+		}
+		if isNilOrZero(value) {
+			return newRuleError("required_if", params, "value is required when %s is %s.", cond.Field, s.Interface())
+		}
+		return nil
+	}, true
+}
+
+func requiredWithoutValidatorBuilder(parent reflect.Type, _ reflect.StructField, rule *Rule) (FieldValidator, bool) {
+	if rule.RequiredWithout == nil {
+		return nil, false
+	}
+	name := *rule.RequiredWithout
+	sibling, _ := parent.FieldByName(name)
+	params := map[string]string{"field": name}
+	return func(value reflect.Value, p reflect.Value) errx.Error {
+		if s, ok := siblingValue(p, sibling.Index); ok && !isZero(s) {
+			return nil
+		}
+		if isNilOrZero(value) {
+			return newRuleError("required_without", params, "value is required when %s is empty.", name)
+		}
+		return nil
+	}, true
+}
+
+func eqFieldValidatorBuilder(parent reflect.Type, _ reflect.StructField, rule *Rule) (FieldValidator, bool) {
+	if rule.EqField == nil {
+		return nil, false
+	}
+	name := *rule.EqField
+	sibling, _ := parent.FieldByName(name)
+	params := map[string]string{"field": name}
+	return func(value reflect.Value, p reflect.Value) errx.Error {
+		if isNilOrZero(value) {
+			return nil
+		}
+		v, _ := dereference(value)
+		s, ok := siblingValue(p, sibling.Index)
+		if !ok || !reflect.DeepEqual(v.Interface(), s.Interface()) {
+			return newRuleError("eqfield", params, "value must be equal to %s.", name)
+		}
+		return nil
+	}, true
+}
+
+func gtFieldValidatorBuilder(parent reflect.Type, _ reflect.StructField, rule *Rule) (FieldValidator, bool) {
+	if rule.GtField == nil {
+		return nil, false
+	}
+	name := *rule.GtField
+	sibling, _ := parent.FieldByName(name)
+	params := map[string]string{"field": name}
+	return func(value reflect.Value, p reflect.Value) errx.Error {
+		if isNilOrZero(value) {
+			return nil
+		}
+		s, ok := siblingValue(p, sibling.Index)
+		if !ok {
+			return nil
+		}
+		v, _ := dereference(value)
+		c, comparable := compareValues(v, s)
+		if !comparable {
+			return errx.Newf("field %s is not comparable with %s", name, v.Type())
+		}
+		if c <= 0 {
+			return newRuleError("gtfield", params, "value must be greater than %s.", name)
+		}
+		return nil
+	}, true
+}
+
+func isNilOrZero(value reflect.Value) bool {
+	if !value.IsValid() {
+		return true
+	}
+	if value.Kind() == reflect.Ptr {
+		return value.IsNil()
+	}
+	return isZero(value)
+}
+
+func elemType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// orderable reports whether compareValues can order the values of a and b.
+func orderable(a, b reflect.Type) bool {
+	if a.ConvertibleTo(timeType) && b.ConvertibleTo(timeType) {
+		return true
+	}
+	if a.ConvertibleTo(decimalType) && b.ConvertibleTo(decimalType) {
+		return true
+	}
+	kind := func(t reflect.Type) string {
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return "int"
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return "uint"
+		case reflect.Float32, reflect.Float64:
+			return "float"
+		case reflect.String:
+			return "string"
+		}
+		return ""
+	}
+	return kind(a) != "" && kind(a) == kind(b)
+}
+
+// compareValues orders two numbers, strings, times or decimals, false when they can't be compared.
+func compareValues(a, b reflect.Value) (int, bool) {
+	if a.Type().ConvertibleTo(timeType) && b.Type().ConvertibleTo(timeType) {
+		return a.Convert(timeType).Interface().(time.Time).Compare(b.Convert(timeType).Interface().(time.Time)), true
+	}
+	if a.Type().ConvertibleTo(decimalType) && b.Type().ConvertibleTo(decimalType) {
+		return a.Convert(decimalType).Interface().(decimal.Decimal).Cmp(b.Convert(decimalType).Interface().(decimal.Decimal)), true
+	}
+	switch {
+	case a.CanInt() && b.CanInt():
+		return cmp.Compare(a.Int(), b.Int()), true
+	case a.CanUint() && b.CanUint():
+		return cmp.Compare(a.Uint(), b.Uint()), true
+	case a.CanFloat() && b.CanFloat():
+		return cmp.Compare(a.Float(), b.Float()), true
+	case a.Kind() == reflect.String && b.Kind() == reflect.String:
+		return cmp.Compare(a.String(), b.String()), true
+	}
+	return 0, false
+}
@@ -0,0 +1,56 @@
+package validation
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/tencent-go/pkg/errx"
+	"github.com/tencent-go/pkg/types"
+)
+
+var labelCatalogue = struct {
+	sync.RWMutex
+	labels map[string]map[string]string
+}{labels: make(map[string]map[string]string)}
+
+// RegisterLabels registers the translations of field labels in locale, labels maps the label
+// from the struct tag (or the field name) to its translation. A locale such as "zh" applies to
+// every region of the language.
+func RegisterLabels(locale types.Locale, labels map[string]string) {
+	labelCatalogue.Lock()
+	defer labelCatalogue.Unlock()
+	for label, translation := range labels {
+		m, ok := labelCatalogue.labels[label]
+		if !ok {
+			m = make(map[string]string)
+			labelCatalogue.labels[label] = m
+		}
+		m[string(locale)] = translation
+	}
+}
+
+// fieldLabel is the label path of a field in an error message, it is rendered with the registered
+// translations when the error is localized by errx.Localize.
+type fieldLabel []string
+
+func (f fieldLabel) String() string {
+	return strings.Join(f, ".")
+}
+
+func (f fieldLabel) Localize(l errx.Locale) string {
+	locale := l.String()
+	language, _, _ := strings.Cut(locale, "-")
+	labelCatalogue.RLock()
+	defer labelCatalogue.RUnlock()
+	arr := make([]string, len(f))
+	for i, label := range f {
+		arr[i] = label
+		m := labelCatalogue.labels[label]
+		if t, ok := m[locale]; ok {
+			arr[i] = t
+		} else if t, ok = m[language]; ok {
+			arr[i] = t
+		}
+	}
+	return strings.Join(arr, ".")
+}
@@ -8,13 +8,25 @@ import (
 )
 
 type Rule struct {
-	Required *bool
-	Label    *string
-	Min      *string
-	Max      *string
-	Pattern  *regexp.Regexp
-	Dive     *Rule
-	MapKey   *Rule
+	Required        *bool
+	Label           *string
+	Min             *string
+	Max             *string
+	Pattern         *regexp.Regexp
+	Dive            *Rule
+	MapKey          *Rule
+	RequiredIf      *FieldCondition // required_if=Status:paid|shipped
+	RequiredWithout *string         // required_without=Phone
+	EqField         *string         // eqfield=Password
+	GtField         *string         // gtfield=StartTime
+	OneOf           []string        // oneof=a|b|c
+	Unique          *bool           // unique
+}
+
+// FieldCondition matches when the sibling Field equals one of Values.
+type FieldCondition struct {
+	Field  string
+	Values []string
 }
 
 func (o *Rule) SetRequired(required bool) *Rule {
@@ -52,11 +64,41 @@ func (o *Rule) SetMapKey(mapKey *Rule) *Rule {
 	return o
 }
 
-var re1 = regexp.MustCompile("^[a-z]+$")
-var re2 = regexp.MustCompile("^[a-z]+,")
-var re3 = regexp.MustCompile("^([a-z]+)='(.+)'(?:,[a-z]+)?")
-var re4 = regexp.MustCompile("^([a-z]+)=(.+)$")
-var re4Option = regexp.MustCompile(`,([a-z]+)`)
+func (o *Rule) SetRequiredIf(field string, values ...string) *Rule {
+	o.RequiredIf = &FieldCondition{Field: field, Values: values}
+	return o
+}
+
+func (o *Rule) SetRequiredWithout(field string) *Rule {
+	o.RequiredWithout = &field
+	return o
+}
+
+func (o *Rule) SetEqField(field string) *Rule {
+	o.EqField = &field
+	return o
+}
+
+func (o *Rule) SetGtField(field string) *Rule {
+	o.GtField = &field
+	return o
+}
+
+func (o *Rule) SetOneOf(values ...string) *Rule {
+	o.OneOf = values
+	return o
+}
+
+func (o *Rule) SetUnique(unique bool) *Rule {
+	o.Unique = &unique
+	return o
+}
+
+var re1 = regexp.MustCompile("^[a-z_]+$")
+var re2 = regexp.MustCompile("^[a-z_]+,")
+var re3 = regexp.MustCompile("^([a-z_]+)='(.+)'(?:,[a-z_]+)?")
+var re4 = regexp.MustCompile("^([a-z_]+)=(.+)$")
+var re4Option = regexp.MustCompile(`,([a-z_]+)`)
 
 func (o *Rule) Parse(tag string) errx.Error {
 	waiting := strings.ReplaceAll(tag, " ", "")
@@ -133,6 +175,25 @@ func (o *Rule) Parse(tag string) errx.Error {
 				return err
 			}
 			o.SetMapKey(r)
+		case "required_if":
+			field, values, ok := strings.Cut(value, ":")
+			if !ok || field == "" || values == "" {
+				return errx.Newf("Invalid validate tag:%s, required_if:%s", tag, value)
+			}
+			o.SetRequiredIf(field, strings.Split(values, "|")...)
+		case "required_without":
+			o.SetRequiredWithout(value)
+		case "eqfield":
+			o.SetEqField(value)
+		case "gtfield":
+			o.SetGtField(value)
+		case "oneof":
+			if value == "" {
+				return errx.Newf("Invalid validate tag:%s, oneof is empty", tag)
+			}
+			o.SetOneOf(strings.Split(value, "|")...)
+		case "unique":
+			o.SetUnique(value != "false")
 		default:
 			return errx.Newf("Invalid validate tag:%s, unknown key:%s", tag, key)
 		}
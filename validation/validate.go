@@ -3,6 +3,8 @@ package validation
 import (
 	"fmt"
 	"reflect"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/tencent-go/pkg/errx"
@@ -17,76 +19,159 @@ func ValidateStructWithCache[T any](value T, opts ...Option) errx.Error {
 	if !ok {
 		return nil
 	}
+	if getOptions(opts...).collectAll {
+		return c.ValidateAll(v)
+	}
 	return c.Validate(v)
 }
 
-func (c *Config) Validate(value reflect.Value, parentLabels ...string) (err errx.Error) {
-	labels := parentLabels
-	if c.label != "" {
-		labels = append(labels, c.label)
+// Validate stops at the first failure, the returned error carries a single Violation.
+func (c *Config) Validate(value reflect.Value, parentLabels ...string) errx.Error {
+	s := &validateState{}
+	if err := c.validate(value, newFieldPath(parentLabels), s); err != nil {
+		return err
 	}
-	defer func() {
-		if err == nil {
-			return
-		}
-		if len(labels) > 0 {
-			err = errx.Wrap(err).AppendMsgf("Field '%s'", strings.Join(labels, ".")).Err()
+	return s.violations.Err()
+}
+
+// ValidateAll validates every field and returns all violations in one error, see GetViolations.
+func (c *Config) ValidateAll(value reflect.Value, parentLabels ...string) errx.Error {
+	s := &validateState{collectAll: true}
+	if err := c.validate(value, newFieldPath(parentLabels), s); err != nil {
+		return err
+	}
+	return s.violations.Err()
+}
+
+type validateState struct {
+	collectAll bool
+	violations Violations
+	stopped    bool
+}
+
+func (s *validateState) add(err errx.Error, path fieldPath) {
+	s.violations = append(s.violations, newViolation(err, path))
+	if !s.collectAll {
+		s.stopped = true
+	}
+}
+
+// addAll adds err, or the violations it carries relative to path.
+func (s *validateState) addAll(err errx.Error, path fieldPath) {
+	if err == nil {
+		return
+	}
+	vs := GetViolations(err)
+	if len(vs) == 0 {
+		s.add(err, path)
+		return
+	}
+	for _, v := range vs {
+		if v.Field != "" {
+			names := strings.Split(v.Field, ".")
+			labels := names
+			if len(v.label) == len(names) {
+				labels = v.label
+			}
+			v.Field = strings.Join(append(slices.Clip(path.names), names...), ".")
+			v.label = fieldLabel(append(slices.Clip(path.labels), labels...))
+		} else {
+			v.Field = strings.Join(path.names, ".")
+			v.label = fieldLabel(path.labels)
 		}
-	}()
+		s.violations = append(s.violations, v)
+	}
+	if !s.collectAll {
+		s.stopped = true
+	}
+}
+
+// fieldPath holds the names (from the label tags) and the labels of the enclosing fields.
+type fieldPath struct {
+	names  []string
+	labels []string
+}
+
+func newFieldPath(labels []string) fieldPath {
+	return fieldPath{names: labels, labels: labels}
+}
+
+func (p fieldPath) child(name, label string) fieldPath {
+	if name == "" && label == "" {
+		return p
+	}
+	if name == "" {
+		name = label
+	}
+	if label == "" {
+		label = name
+	}
+	return fieldPath{
+		names:  append(slices.Clip(p.names), name),
+		labels: append(slices.Clip(p.labels), label),
+	}
+}
+
+// validate records the failed rules in s, the returned error means the value could not be validated at all.
+func (c *Config) validate(value reflect.Value, parent fieldPath, s *validateState) errx.Error {
+	path := parent.child(c.name, c.label)
 	if !value.IsValid() {
-		return errx.New("value is not valid")
+		s.add(newRuleError("valid", nil, "value is not valid"), path)
+		return nil
 	}
 	if value.Kind() == reflect.Ptr {
 		if value.IsNil() {
 			if c.required {
-				return errx.Validation.WithMsg("value is required").Err()
+				s.add(newRuleError("required", nil, "value is required"), path)
 			}
 			return nil
 		}
-		value, err = dereference(value)
+		value, err := dereference(value)
 		if err != nil {
 			return err
 		}
-		return c.runValidators(value, labels...)
+		return c.runValidators(value, path, s)
 	}
 	if c.required {
 		if c.hasValidators() {
-			return c.runValidators(value, labels...)
+			return c.runValidators(value, path, s)
 		}
 		if isZero(value) {
-			return errx.Validation.WithMsg("value is required").Err()
+			s.add(newRuleError("required", nil, "value is required"), path)
 		}
 		return nil
 	}
 	if isZero(value) {
 		return nil
 	}
-	return c.runValidators(value, labels...)
+	return c.runValidators(value, path, s)
 }
 
-func (c *Config) runValidators(value reflect.Value, labels ...string) errx.Error {
+func (c *Config) runValidators(value reflect.Value, path fieldPath, s *validateState) errx.Error {
 	for _, validator := range c.validators {
 		if err := validator(value); err != nil {
-			return err
+			s.add(err, path)
+			return nil
 		}
 	}
 	if c.mapConfig != nil && value.Kind() == reflect.Map {
 		for _, key := range value.MapKeys() {
-			var l []string
+			var p fieldPath
 			{
 				k, e := dereference(key)
 				if e != nil {
 					return e
 				}
-				l = append(labels, fmt.Sprint(k.Interface()))
+				name := fmt.Sprint(k.Interface())
+				p = path.child(name, name)
 			}
 			if conf := c.mapConfig.KeyConfig; conf != nil {
-				if err := conf.Validate(key, l...); err != nil {
+				if err := conf.validate(key, p, s); err != nil || s.stopped {
 					return err
 				}
 			}
 			if conf := c.mapConfig.ValueConfig; conf != nil {
-				if err := conf.Validate(value.MapIndex(key), l...); err != nil {
+				if err := conf.validate(value.MapIndex(key), p, s); err != nil || s.stopped {
 					return err
 				}
 			}
@@ -94,13 +179,14 @@ func (c *Config) runValidators(value reflect.Value, labels ...string) errx.Error
 	}
 	if c.arrayConfig != nil && (value.Kind() == reflect.Slice || value.Kind() == reflect.Array) {
 		for i := 0; i < value.Len(); i++ {
-			l := append(labels, fmt.Sprintf("%d", i))
-			if err := c.arrayConfig.Validate(value.Index(i), l...); err != nil {
+			index := strconv.Itoa(i)
+			if err := c.arrayConfig.validate(value.Index(i), path.child(index, index), s); err != nil || s.stopped {
 				return err
 			}
 		}
 	}
-	if c.structFieldsConfig != nil && value.Kind() == reflect.Struct {
+	if (c.structFieldsConfig != nil || c.structValidatable) && value.Kind() == reflect.Struct {
+		before := len(s.violations)
 		for _, conf := range c.structFieldsConfig {
 			if conf.Index < 0 || conf.Index >= value.NumField() {
 				return errx.Newf("invalid struct field index: %d", conf.Index)
@@ -108,14 +194,44 @@ func (c *Config) runValidators(value reflect.Value, labels ...string) errx.Error
 			if conf.Config == nil {
 				return errx.Newf("invalid struct field config: %d", conf.Index)
 			}
-			if err := conf.Config.Validate(value.Field(conf.Index), labels...); err != nil {
+			field := value.Field(conf.Index)
+			n := len(s.violations)
+			if err := conf.Config.validate(field, path, s); err != nil || s.stopped {
 				return err
 			}
+			if len(s.violations) > n {
+				continue
+			}
+			for _, validator := range conf.Validators {
+				if err := validator(field, value); err != nil {
+					s.add(err, path.child(conf.Config.name, conf.Config.label))
+					break
+				}
+			}
+			if s.stopped {
+				return nil
+			}
+		}
+		if c.structValidatable && len(s.violations) == before {
+			s.addAll(validateStruct(value), path)
 		}
 	}
 	return nil
 }
 
+// validateStruct calls the StructValidatable hook, also when it has a pointer receiver.
+func validateStruct(value reflect.Value) errx.Error {
+	if v, ok := value.Interface().(StructValidatable); ok {
+		return v.ValidateStruct()
+	}
+	p := reflect.New(value.Type())
+	p.Elem().Set(value)
+	if v, ok := p.Interface().(StructValidatable); ok {
+		return v.ValidateStruct()
+	}
+	return nil
+}
+
 func dereference(value reflect.Value) (reflect.Value, errx.Error) {
 	if !value.IsValid() {
 		return reflect.Value{}, errx.New("Value is not valid")
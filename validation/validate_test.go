@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/tencent-go/pkg/errx"
+	"github.com/tencent-go/pkg/types"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -350,4 +351,182 @@ func TestAdditionalValidationScenarios(t *testing.T) {
 			err.Error() == "Field 'age': value is required" ||
 			err.Error() == "Field 'email': format error")
 	})
+
+	t.Run("本地化錯誤訊息", func(t *testing.T) {
+		RegisterLabels(types.ZhTW, map[string]string{"street": "街道"})
+		errx.RegisterLocaleMsg("value is required", types.Locale("zh"), "此欄位為必填")
+		errx.RegisterLocaleMsg("Field '%s'", types.Locale("zh"), "欄位「%s」")
+		t.Cleanup(func() {
+			errx.UnregisterLocaleMsg("value is required", types.Locale("zh"))
+			errx.UnregisterLocaleMsg("Field '%s'", types.Locale("zh"))
+		})
+		err := ValidateStructWithCache(&Address{City: "北京"}, WithLabelTag("json"))
+		assert.Error(t, err)
+		assert.Equal(t, "欄位「街道」: 此欄位為必填", errx.Localize(err, types.ZhTW))
+		assert.Equal(t, "欄位「street」: 此欄位為必填", errx.Localize(err, types.ZhCN))
+		assert.Equal(t, err.Error(), errx.Localize(err, types.EnUS))
+	})
+
+	t.Run("依欄位帶有的標籤命名", func(t *testing.T) {
+		type input struct {
+			ID    string `path:"id" validate:"min=3"`
+			Page  string `query:"page" validate:"min=3"`
+			Value string `json:"value" validate:"min=3"`
+			Plain string `validate:"min=3"`
+		}
+		err := ValidateStructWithCache(&input{ID: "a", Page: "b", Value: "c", Plain: "d"}, WithLabelTags("query", "path", "json"), CollectAll())
+		var fields []string
+		for _, v := range GetViolations(err) {
+			fields = append(fields, v.Field)
+		}
+		assert.ElementsMatch(t, []string{"id", "page", "value", "Plain"}, fields)
+	})
+
+	t.Run("僅命名的標籤", func(t *testing.T) {
+		type query struct {
+			Page  int    `json:"page"`
+			Query string `json:"query"`
+			Name  string `json:"name,omitempty" validate:"min=1"`
+			Token string `json:"-" validate:"required=false,max=3"`
+		}
+		assert.Error(t, ValidateStructWithCache(&query{Name: "a"}, WithLabelTag("json")))
+		assert.NoError(t, ValidateStructWithCache(&query{Name: "a"}, WithNameTag("json")))
+		err := ValidateStructWithCache(&query{}, WithNameTag("json"))
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "Field 'name'")
+		}
+		err = ValidateStructWithCache(&query{Name: "a", Token: "long"}, WithNameTag("json"))
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "Field 'Token'")
+		}
+	})
+
+	t.Run("嵌入結構體的標籤", func(t *testing.T) {
+		type withEmbedded struct {
+			Address
+			Note string `json:"note"`
+		}
+		err := ValidateStructWithCache(&withEmbedded{Address: Address{City: "北京"}}, WithLabelTag("json"))
+		if assert.Error(t, err) {
+			assert.Equal(t, "Field 'street': value is required", err.Error())
+		}
+	})
+
+	t.Run("收集全部錯誤", func(t *testing.T) {
+		type Item struct {
+			Name string `validate:"required" json:"name"`
+		}
+		type Order struct {
+			Title string  `validate:"min=2" json:"title"`
+			Count int     `validate:"required,min=1,max=10" json:"count"`
+			Items []Item  `json:"items"`
+			Addr  Address `json:"address"`
+		}
+		v := &Order{Title: "a", Count: 11, Items: []Item{{Name: "x"}, {}}}
+		err := ValidateStructWithCache(v, WithLabelTag("json"), CollectAll())
+		assert.Error(t, err)
+		assert.Equal(t, errx.TypeValidation, err.Type())
+		vs := GetViolations(err)
+		assert.Len(t, vs, 5)
+		assert.Equal(t, "title", vs[0].Field)
+		assert.Equal(t, "min", vs[0].Rule)
+		assert.Equal(t, map[string]string{"min": "2"}, vs[0].Params)
+		assert.Equal(t, "value length must be greater than or equal to 2.", vs[0].Message)
+		assert.Equal(t, "count", vs[1].Field)
+		assert.Equal(t, "max", vs[1].Rule)
+		assert.Equal(t, "items.1.name", vs[2].Field)
+		assert.Equal(t, "required", vs[2].Rule)
+		assert.Equal(t, "address.street", vs[3].Field)
+		assert.Equal(t, "address.city", vs[4].Field)
+
+		err = ValidateStructWithCache(v, WithLabelTag("json"))
+		assert.Len(t, GetViolations(err), 1)
+		assert.Equal(t, "Field 'title': value length must be greater than or equal to 2.", err.Error())
+	})
+}
+
+type signUp struct {
+	Contact   string    `validate:"oneof=email|phone" json:"contact"`
+	Email     string    `validate:"required_if=Contact:email" json:"email"`
+	Phone     string    `validate:"required_without=Email" json:"phone"`
+	Password  string    `validate:"min=6" json:"password"`
+	Confirm   string    `validate:"eqfield=Password" json:"confirm"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `validate:"gtfield=StartTime" json:"endTime"`
+	Tags      []string  `validate:"unique,required=false" json:"tags"`
+}
+
+func (s signUp) ValidateStruct() errx.Error {
+	if s.Email == s.Password {
+		return Violations{{Field: "password", Rule: "custom", Message: "password must differ from email"}}.Err()
+	}
+	return nil
+}
+
+func TestCrossFieldRules(t *testing.T) {
+	now := time.Now()
+	valid := signUp{Contact: "email", Email: "a@b.c", Password: "secret", Confirm: "secret", StartTime: now, EndTime: now.Add(time.Hour), Tags: []string{"a", "b"}}
+	assert.NoError(t, ValidateStructWithCache(valid, WithLabelTag("json")))
+
+	v := signUp{Contact: "fax", Password: "secret", Confirm: "other", StartTime: now, EndTime: now, Tags: []string{"a", "a"}}
+	vs := GetViolations(ValidateStructWithCache(v, WithLabelTag("json"), CollectAll()))
+	rules := map[string]string{}
+	for _, item := range vs {
+		rules[item.Field] = item.Rule
+	}
+	assert.Equal(t, map[string]string{
+		"contact": "oneof",
+		"phone":   "required_without",
+		"confirm": "eqfield",
+		"endTime": "gtfield",
+		"tags":    "unique",
+	}, rules)
+
+	v = valid
+	v.Contact = "email"
+	v.Email = ""
+	v.Phone = "123"
+	err := ValidateStructWithCache(v, WithLabelTag("json"))
+	assert.Equal(t, "required_if", GetViolations(err)[0].Rule)
+
+	v = valid
+	v.Email, v.Password, v.Confirm = "secret", "secret", "secret"
+	err = ValidateStructWithCache(v, WithLabelTag("json"))
+	assert.Equal(t, "password", GetViolations(err)[0].Field)
+
+	type broken struct {
+		A string `validate:"eqfield=Missing"`
+	}
+	assert.Error(t, ValidateStructWithCache(broken{}))
+
+	// 拼錯或型別不符的欄位在建立時即被拒絕, 不會靜默停用規則
+	type misspelled struct {
+		Start int
+		End   int `validate:"gtfield=Strat"`
+	}
+	err = ValidateStructWithCache(misspelled{Start: 2, End: 1})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "field Strat not found")
+	}
+	type mismatched struct {
+		Start string
+		End   int `validate:"gtfield=Start"`
+	}
+	err = ValidateStructWithCache(mismatched{End: 1})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "not comparable")
+	}
+	type mismatchedEq struct {
+		Code  int
+		Again *string `validate:"eqfield=Code"`
+	}
+	assert.Error(t, ValidateStructWithCache(mismatchedEq{}))
+	type pointers struct {
+		Start *time.Time
+		End   time.Time `validate:"gtfield=Start"`
+		Min   int32
+		Max   *int64 `validate:"gtfield=Min"`
+	}
+	start, max := now.Add(-time.Hour), int64(2)
+	assert.NoError(t, ValidateStructWithCache(pointers{Start: &start, End: now, Min: 1, Max: &max}))
 }
@@ -1,8 +1,10 @@
 package validation
 
 import (
+	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/tencent-go/pkg/errx"
@@ -26,10 +28,14 @@ var defaultValidatorBuilders = []ValidatorBuilder{
 	lengthRangeValidatorBuilder,
 	patternValidatorBuilder,
 	enumValidatorBuilder,
+	oneOfValidatorBuilder,
+	uniqueValidatorBuilder,
 }
 
 var customValidatorBuilders []ValidatorBuilder
 
+// RegisterValidatorBuilder adds a builder that runs before the built-in ones (min/max, pattern,
+// enum, oneof, unique). Rules relating sibling fields are built by RegisterFieldValidatorBuilder.
 func RegisterValidatorBuilder(builder ValidatorBuilder) {
 	customValidatorBuilders = append(customValidatorBuilders, builder)
 }
@@ -60,10 +66,11 @@ func intRangeValidatorBuilder(typ reflect.Type, rule *Rule) (Validator, bool) {
 		}
 		maxValue = &cond
 	}
+	params := rangeParams(rule)
 	if minValue != nil && maxValue != nil {
 		return func(value reflect.Value) errx.Error {
 			if value.Int() < *minValue || value.Int() > *maxValue {
-				return errx.Validation.WithMsgf("value must be greater than or equal to %d and less than or equal to %d.", *minValue, *maxValue).Err()
+				return newRuleError(rangeRule(value.Int() < *minValue), params, "value must be greater than or equal to %d and less than or equal to %d.", *minValue, *maxValue)
 			}
 			return nil
 		}, true
@@ -71,7 +78,7 @@ func intRangeValidatorBuilder(typ reflect.Type, rule *Rule) (Validator, bool) {
 	if minValue != nil {
 		return func(value reflect.Value) errx.Error {
 			if value.Int() < *minValue {
-				return errx.Validation.WithMsgf("value must be greater than or equal to %d.", *minValue).Err()
+				return newRuleError("min", params, "value must be greater than or equal to %d.", *minValue)
 			}
 			return nil
 		}, true
@@ -79,7 +86,7 @@ func intRangeValidatorBuilder(typ reflect.Type, rule *Rule) (Validator, bool) {
 	if maxValue != nil {
 		return func(value reflect.Value) errx.Error {
 			if value.Int() > *maxValue {
-				return errx.Validation.WithMsgf("value must be less than or equal to %d.", *maxValue).Err()
+				return newRuleError("max", params, "value must be less than or equal to %d.", *maxValue)
 			}
 			return nil
 		}, true
@@ -104,6 +111,7 @@ func decimalRangeValidatorBuilder(typ reflect.Type, rule *Rule) (Validator, bool
 			maxValue = &cond
 		}
 	}
+	params := rangeParams(rule)
 	if minValue != nil && maxValue != nil {
 		return func(value reflect.Value) errx.Error {
 			d, ok := value.Interface().(decimal.Decimal)
@@ -111,7 +119,7 @@ func decimalRangeValidatorBuilder(typ reflect.Type, rule *Rule) (Validator, bool
 				return errx.Newf("value is not decimal")
 			}
 			if d.LessThan(*minValue) || d.GreaterThan(*maxValue) {
-				return errx.Validation.WithMsgf("value must be greater than or equal to %s and less than or equal to %s.", *minValue, *maxValue).Err()
+				return newRuleError(rangeRule(d.LessThan(*minValue)), params, "value must be greater than or equal to %s and less than or equal to %s.", *minValue, *maxValue)
 			}
 			return nil
 		}, true
@@ -123,7 +131,7 @@ func decimalRangeValidatorBuilder(typ reflect.Type, rule *Rule) (Validator, bool
 				return errx.Newf("value is not decimal")
 			}
 			if d.LessThan(*minValue) {
-				return errx.Validation.WithMsgf("value must be greater than or equal to %s.", *minValue).Err()
+				return newRuleError("min", params, "value must be greater than or equal to %s.", *minValue)
 			}
 			return nil
 		}, true
@@ -135,7 +143,7 @@ func decimalRangeValidatorBuilder(typ reflect.Type, rule *Rule) (Validator, bool
 				return errx.Newf("value is not decimal")
 			}
 			if d.GreaterThan(*maxValue) {
-				return errx.Validation.WithMsgf("value must be less than or equal to %s.", *maxValue).Err()
+				return newRuleError("max", params, "value must be less than or equal to %s.", *maxValue)
 			}
 			return nil
 		}, true
@@ -162,11 +170,12 @@ func floatRangeValidatorBuilder(typ reflect.Type, rule *Rule) (Validator, bool)
 		}
 		maxValue = &cond
 	}
+	params := rangeParams(rule)
 	if minValue != nil && maxValue != nil {
 		return func(value reflect.Value) errx.Error {
 			f := value.Float()
 			if f < *minValue || f > *maxValue {
-				return errx.Validation.WithMsgf("value must be greater than or equal to %f and less than or equal to %f.", *minValue, *maxValue).Err()
+				return newRuleError(rangeRule(f < *minValue), params, "value must be greater than or equal to %f and less than or equal to %f.", *minValue, *maxValue)
 			}
 			return nil
 		}, true
@@ -175,7 +184,7 @@ func floatRangeValidatorBuilder(typ reflect.Type, rule *Rule) (Validator, bool)
 		return func(value reflect.Value) errx.Error {
 			f := value.Float()
 			if f < *minValue {
-				return errx.Validation.WithMsgf("value must be greater than or equal to %f.", *minValue).Err()
+				return newRuleError("min", params, "value must be greater than or equal to %f.", *minValue)
 			}
 			return nil
 		}, true
@@ -184,7 +193,7 @@ func floatRangeValidatorBuilder(typ reflect.Type, rule *Rule) (Validator, bool)
 		return func(value reflect.Value) errx.Error {
 			f := value.Float()
 			if f > *maxValue {
-				return errx.Validation.WithMsgf("value must be less than or equal to %f.", *maxValue).Err()
+				return newRuleError("max", params, "value must be less than or equal to %f.", *maxValue)
 			}
 			return nil
 		}, true
@@ -209,6 +218,7 @@ func timeRangeValidatorBuilder(typ reflect.Type, rule *Rule) (Validator, bool) {
 			maxTime = &cond
 		}
 	}
+	params := rangeParams(rule)
 	if minTime != nil && maxTime != nil {
 		return func(value reflect.Value) errx.Error {
 			t, ok := value.Interface().(time.Time)
@@ -216,7 +226,7 @@ func timeRangeValidatorBuilder(typ reflect.Type, rule *Rule) (Validator, bool) {
 				return errx.Newf("value is not time")
 			}
 			if t.Before(*minTime) || t.After(*maxTime) {
-				return errx.Validation.WithMsgf("value must be greater than or equal to %s and less than or equal to %s.", *minTime, *maxTime).Err()
+				return newRuleError(rangeRule(t.Before(*minTime)), params, "value must be greater than or equal to %s and less than or equal to %s.", *minTime, *maxTime)
 			}
 			return nil
 		}, true
@@ -228,7 +238,7 @@ func timeRangeValidatorBuilder(typ reflect.Type, rule *Rule) (Validator, bool) {
 				return errx.Newf("value is not time")
 			}
 			if t.Before(*minTime) {
-				return errx.Validation.WithMsgf("value must be greater than or equal to %s.", *minTime).Err()
+				return newRuleError("min", params, "value must be greater than or equal to %s.", *minTime)
 			}
 			return nil
 		}, true
@@ -240,7 +250,7 @@ func timeRangeValidatorBuilder(typ reflect.Type, rule *Rule) (Validator, bool) {
 				return errx.Newf("value is not time")
 			}
 			if t.After(*maxTime) {
-				return errx.Validation.WithMsgf("value must be less than or equal to %s.", *maxTime).Err()
+				return newRuleError("max", params, "value must be less than or equal to %s.", *maxTime)
 			}
 			return nil
 		}, true
@@ -270,11 +280,12 @@ func lengthRangeValidatorBuilder(typ reflect.Type, rule *Rule) (Validator, bool)
 		}
 		maxLen = &cond
 	}
+	params := rangeParams(rule)
 	if minLen != nil && maxLen != nil {
 		return func(value reflect.Value) errx.Error {
 			l := value.Len()
 			if l < *minLen || l > *maxLen {
-				return errx.Validation.WithMsgf("value length must be greater than or equal to %d and less than or equal to %d.", *minLen, *maxLen).Err()
+				return newRuleError(rangeRule(l < *minLen), params, "value length must be greater than or equal to %d and less than or equal to %d.", *minLen, *maxLen)
 			}
 			return nil
 		}, true
@@ -283,7 +294,7 @@ func lengthRangeValidatorBuilder(typ reflect.Type, rule *Rule) (Validator, bool)
 		return func(value reflect.Value) errx.Error {
 			l := value.Len()
 			if l < *minLen {
-				return errx.Validation.WithMsgf("value length must be greater than or equal to %d.", *minLen).Err()
+				return newRuleError("min", params, "value length must be greater than or equal to %d.", *minLen)
 			}
 			return nil
 		}, true
@@ -292,7 +303,7 @@ func lengthRangeValidatorBuilder(typ reflect.Type, rule *Rule) (Validator, bool)
 		return func(value reflect.Value) errx.Error {
 			l := value.Len()
 			if l > *maxLen {
-				return errx.Validation.WithMsgf("value length must be less than or equal to %d.", *maxLen).Err()
+				return newRuleError("max", params, "value length must be less than or equal to %d.", *maxLen)
 			}
 			return nil
 		}, true
@@ -309,7 +320,7 @@ func patternValidatorBuilder(typ reflect.Type, rule *Rule) (Validator, bool) {
 	}
 	return func(value reflect.Value) errx.Error {
 		if !rule.Pattern.MatchString(value.String()) {
-			return errx.Validation.WithMsg("format error").Err()
+			return newRuleError("pattern", map[string]string{"pattern": rule.Pattern.String()}, "format error")
 		}
 		return nil
 	}, true
@@ -322,13 +333,83 @@ func enumValidatorBuilder(typ reflect.Type, rule *Rule) (Validator, bool) {
 	return func(value reflect.Value) errx.Error {
 		if v, ok := value.Interface().(types.IEnum); ok {
 			if !v.Enum().Contains(v) {
-				return errx.Validation.WithMsgf("value is not valid").Err()
+				return newRuleError("enum", nil, "value is not valid")
 			}
 		}
 		return nil
 	}, true
 }
 
+func oneOfValidatorBuilder(typ reflect.Type, rule *Rule) (Validator, bool) {
+	if len(rule.OneOf) == 0 {
+		return nil, false
+	}
+	switch typ.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+	default:
+		return nil, false
+	}
+	values := make(map[string]bool, len(rule.OneOf))
+	for _, v := range rule.OneOf {
+		values[v] = true
+	}
+	params := map[string]string{"values": strings.Join(rule.OneOf, "|")}
+	display := strings.Join(rule.OneOf, ", ")
+	return func(value reflect.Value) errx.Error {
+		if !values[fmt.Sprint(value.Interface())] {
+			return newRuleError("oneof", params, "value must be one of %s.", display)
+		}
+		return nil
+	}, true
+}
+
+func uniqueValidatorBuilder(typ reflect.Type, rule *Rule) (Validator, bool) {
+	if rule.Unique == nil || !*rule.Unique {
+		return nil, false
+	}
+	if typ.Kind() != reflect.Slice && typ.Kind() != reflect.Array {
+		return nil, false
+	}
+	return func(value reflect.Value) errx.Error {
+		seen := make(map[any]bool, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			item := value.Index(i)
+			for item.Kind() == reflect.Ptr && !item.IsNil() {
+				item = item.Elem()
+			}
+			var key any = fmt.Sprintf("%#v", item.Interface())
+			if item.Comparable() {
+				key = item.Interface()
+			}
+			if seen[key] {
+				return newRuleError("unique", nil, "value must not contain duplicates.")
+			}
+			seen[key] = true
+		}
+		return nil
+	}, true
+}
+
+// rangeRule names the violated bound of a min/max range.
+func rangeRule(belowMin bool) string {
+	if belowMin {
+		return "min"
+	}
+	return "max"
+}
+
+func rangeParams(rule *Rule) map[string]string {
+	params := make(map[string]string)
+	if rule.Min != nil {
+		params["min"] = *rule.Min
+	}
+	if rule.Max != nil {
+		params["max"] = *rule.Max
+	}
+	return params
+}
+
 var (
 	timeType      = reflect.TypeOf(time.Time{})
 	decimalType   = reflect.TypeOf(decimal.Decimal{})
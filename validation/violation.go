@@ -0,0 +1,124 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tencent-go/pkg/errx"
+	"github.com/tencent-go/pkg/types"
+)
+
+// Violation is one failed rule of a field. Field is the dotted path built from the label tags
+// (json, query, path...), e.g. "items.0.name". Rule is one of required, min, max, pattern, enum,
+// valid or custom for Validatable values and registered validators.
+type Violation struct {
+	Field   string            `json:"field"`
+	Rule    string            `json:"rule"`
+	Params  map[string]string `json:"params,omitempty"`
+	Message string            `json:"message"`
+
+	err   errx.Error
+	label fieldLabel
+}
+
+// Violations is the cause of the errors returned by Config.Validate and Config.ValidateAll.
+type Violations []Violation
+
+func (vs Violations) Error() string {
+	arr := make([]string, len(vs))
+	for i, v := range vs {
+		arr[i] = v.Message
+		if len(v.label) > 0 {
+			arr[i] = fmt.Sprintf("Field '%s': %s", v.label, v.Message)
+		}
+	}
+	return strings.Join(arr, "; ")
+}
+
+// Localize renders the violations in locale, it makes Violations usable as an errx message argument.
+func (vs Violations) Localize(l errx.Locale) string {
+	arr := make([]string, len(vs))
+	for i, v := range vs {
+		err := v.err
+		if err == nil {
+			err = errx.New(v.Message)
+		}
+		if len(v.label) > 0 {
+			err = errx.Wrap(err).AppendMsgf("Field '%s'", v.label).Err()
+		}
+		arr[i] = errx.Localize(err, l)
+	}
+	return strings.Join(arr, "; ")
+}
+
+// Localized returns a copy whose messages are rendered in locale.
+func (vs Violations) Localized(l types.Locale) Violations {
+	if len(vs) == 0 {
+		return nil
+	}
+	res := make(Violations, len(vs))
+	for i, v := range vs {
+		res[i] = v
+		res[i].Message = v.localizedMessage(l)
+	}
+	return res
+}
+
+// Err wraps the violations into a validation error, nil when there is none.
+func (vs Violations) Err() errx.Error {
+	if len(vs) == 0 {
+		return nil
+	}
+	return errx.Wrap(vs).WithType(errx.TypeValidation).WithMsgf("%s", vs).Err()
+}
+
+func (v Violation) localizedMessage(l errx.Locale) string {
+	if v.err == nil {
+		return v.Message
+	}
+	return errx.Localize(v.err, l)
+}
+
+// GetViolations returns the violations carried by err, also after it went through rpc.
+func GetViolations(err error) Violations {
+	var vs Violations
+	if errors.As(err, &vs) {
+		return vs
+	}
+	return nil
+}
+
+func newViolation(err errx.Error, path fieldPath) Violation {
+	v := Violation{
+		Field:   strings.Join(path.names, "."),
+		Rule:    "custom",
+		Message: err.Error(),
+		err:     err,
+		label:   fieldLabel(path.labels),
+	}
+	var re *ruleError
+	if errors.As(err, &re) {
+		v.Rule = re.rule
+		v.Params = re.params
+	}
+	return v
+}
+
+// ruleError marks the errors of the built-in validators with the failed rule.
+type ruleError struct {
+	rule   string
+	params map[string]string
+}
+
+func (e *ruleError) Error() string {
+	return e.rule
+}
+
+func newRuleError(rule string, params map[string]string, format string, a ...any) errx.Error {
+	b := errx.Wrap(&ruleError{rule: rule, params: params}).WithType(errx.TypeValidation)
+	if len(a) == 0 {
+		return b.WithMsg(format).Err()
+	}
+	return b.WithMsgf(format, a...).Err()
+}
@@ -5,23 +5,26 @@ import (
 
 	"github.com/tencent-go/pkg/errx"
 	"github.com/tencent-go/pkg/natsx"
-	"github.com/tencent-go/pkg/util"
 	"github.com/sirupsen/logrus"
 	"github.com/vmihailenco/msgpack/v5"
 )
 
 type EventChannel interface {
-	Topic() string
+	Topic() string // 可含佔位符, 如 order.{orderId}
 	MessageType() reflect.Type
-	Subscribe(conn Conn) (Subscription, errx.Error)
-	Publish(conn Conn, data msgpack.RawMessage) errx.Error
+	Authorize(conn Conn, topic string) errx.Error                 // topic為客戶端訂閱或發佈的實際topic
+	Subscribe(conn Conn, topic string) (Subscription, errx.Error) // topic為客戶端訂閱的實際topic
+	Publish(conn Conn, data msgpack.RawMessage) errx.Error // data以連線的格式編碼, 見 Conn.Format
 	Description() string
 }
 
 type EventChannelBuilder[T any] interface {
 	EventChannel
 	WithSubscriber(subscriber func(conn Conn, send func(message T) errx.Error) (unsubscribe func(), err errx.Error)) EventChannelBuilder[T]
+	WithParamsSubscriber(subscriber func(conn Conn, params TopicParams, send func(message T) errx.Error) (unsubscribe func(), err errx.Error)) EventChannelBuilder[T]
 	WithPublisher(publisher func(conn Conn, data T) errx.Error) EventChannelBuilder[T]
+	WithAuthorizer(authorize func(conn Conn, params TopicParams) errx.Error) EventChannelBuilder[T] // 拒絕時回傳 TypeAuthorization 的錯誤
+	WithArgs(args ...string) EventChannelBuilder[T]                                                 // 依序替換佔位符, 用於發送或訂閱特定的topic
 	WithDescription(description string) EventChannelBuilder[T]
 }
 
@@ -31,26 +34,63 @@ type Subscription interface {
 
 func NewEventChannel[T any](topic string) EventChannelBuilder[T] {
 	return &eventChannelBuilder[T]{
-		channelOption: channelOption{topic: topic},
+		channelOption: channelOption{topic: topic, template: parseTopicTemplate(topic)},
 	}
 }
 
 type channelOption struct {
 	topic       string
-	subscriber  func(conn Conn) (Subscription, errx.Error)
+	template    *topicTemplate // nil when the topic has no placeholder
+	subscriber  func(conn Conn, topic string, params TopicParams) (Subscription, errx.Error)
 	publisher   func(conn Conn, data msgpack.RawMessage) errx.Error
+	authorize   func(conn Conn, params TopicParams) errx.Error
 	description string
 }
 
+// params extracts the placeholders of the template from topic, Authorize and Subscribe fail on invalid values.
+func (o *channelOption) params(topic string) (TopicParams, errx.Error) {
+	if o.template == nil {
+		if topic != o.topic {
+			return nil, errx.Define().WithType(errx.TypeNotFound).WithMsgf("topic %s does not match %s", topic, o.topic).Err()
+		}
+		return TopicParams{}, nil
+	}
+	params, ok := o.template.match(topic)
+	if !ok {
+		return nil, errx.Define().WithType(errx.TypeNotFound).WithMsgf("topic %s does not match %s", topic, o.topic).Err()
+	}
+	for _, name := range o.template.names {
+		if !validTopicParam(params[name]) {
+			return nil, errx.Validation.WithMsgf("invalid value %q of placeholder %s", params[name], name).Err()
+		}
+	}
+	return params, nil
+}
+
+func (o *channelOption) Authorize(conn Conn, topic string) errx.Error {
+	params, err := o.params(topic)
+	if err != nil {
+		return err
+	}
+	if o.authorize == nil {
+		return nil
+	}
+	return o.authorize(conn, params)
+}
+
 type eventChannelBuilder[T any] struct {
 	channelOption
 }
 
-func (e *eventChannelBuilder[T]) Subscribe(conn Conn) (Subscription, errx.Error) {
+func (e *eventChannelBuilder[T]) Subscribe(conn Conn, topic string) (Subscription, errx.Error) {
 	if e.subscriber == nil {
 		return nil, nil
 	}
-	return e.subscriber(conn)
+	params, err := e.params(topic)
+	if err != nil {
+		return nil, err
+	}
+	return e.subscriber(conn, topic, params)
 }
 
 func (e *eventChannelBuilder[T]) Publish(conn Conn, data msgpack.RawMessage) errx.Error {
@@ -61,10 +101,16 @@ func (e *eventChannelBuilder[T]) Publish(conn Conn, data msgpack.RawMessage) err
 }
 
 func (e *eventChannelBuilder[T]) WithSubscriber(subscriber func(conn Conn, send func(message T) errx.Error) (unsubscribe func(), err errx.Error)) EventChannelBuilder[T] {
+	return e.WithParamsSubscriber(func(conn Conn, params TopicParams, send func(message T) errx.Error) (func(), errx.Error) {
+		return subscriber(conn, send)
+	})
+}
+
+func (e *eventChannelBuilder[T]) WithParamsSubscriber(subscriber func(conn Conn, params TopicParams, send func(message T) errx.Error) (unsubscribe func(), err errx.Error)) EventChannelBuilder[T] {
 	o := e.channelOption
-	o.subscriber = func(conn Conn) (Subscription, errx.Error) {
-		unsubscribe, err := subscriber(conn, func(message T) errx.Error {
-			return conn.Send(e.topic, message)
+	o.subscriber = func(conn Conn, topic string, params TopicParams) (Subscription, errx.Error) {
+		unsubscribe, err := subscriber(conn, params, func(message T) errx.Error {
+			return sendSubscribed(conn, topic, message)
 		})
 		if err != nil {
 			return nil, err
@@ -74,11 +120,24 @@ func (e *eventChannelBuilder[T]) WithSubscriber(subscriber func(conn Conn, send
 	return &eventChannelBuilder[T]{o}
 }
 
+func (e *eventChannelBuilder[T]) WithAuthorizer(authorize func(conn Conn, params TopicParams) errx.Error) EventChannelBuilder[T] {
+	o := e.channelOption
+	o.authorize = authorize
+	return &eventChannelBuilder[T]{o}
+}
+
+func (e *eventChannelBuilder[T]) WithArgs(args ...string) EventChannelBuilder[T] {
+	o := e.channelOption
+	o.topic = formatTopic(o.topic, args...)
+	o.template = parseTopicTemplate(o.topic)
+	return &eventChannelBuilder[T]{o}
+}
+
 func (e *eventChannelBuilder[T]) WithPublisher(publisher func(conn Conn, data T) errx.Error) EventChannelBuilder[T] {
 	o := e.channelOption
 	o.publisher = func(conn Conn, rawData msgpack.RawMessage) errx.Error {
 		var data T
-		err := util.Msgpack().Unmarshal(rawData, &data)
+		err := unmarshalData(conn, rawData, &data)
 		if err != nil {
 			return err
 		}
@@ -106,6 +165,89 @@ func (e *eventChannelBuilder[T]) Description() string {
 	return e.description
 }
 
+// RequestChannel is a publishable channel replying to every message, the reply carries the id of the
+// message and either the result or an ErrorDetail.
+type RequestChannel interface {
+	EventChannel
+	ResponseType() reflect.Type
+	Handle(conn Conn, data msgpack.RawMessage) (any, errx.Error)
+}
+
+type RequestChannelBuilder[I any, O any] interface {
+	RequestChannel
+	WithHandler(handler func(conn Conn, data I) (*O, errx.Error)) RequestChannelBuilder[I, O]
+	WithAuthorizer(authorize func(conn Conn, params TopicParams) errx.Error) RequestChannelBuilder[I, O]
+	WithDescription(description string) RequestChannelBuilder[I, O]
+}
+
+func NewRequestChannel[I any, O any](topic string) RequestChannelBuilder[I, O] {
+	return &requestChannelBuilder[I, O]{channelOption: channelOption{topic: topic}}
+}
+
+type requestChannelBuilder[I any, O any] struct {
+	channelOption
+	handler func(conn Conn, data I) (*O, errx.Error)
+}
+
+func (r *requestChannelBuilder[I, O]) Handle(conn Conn, rawData msgpack.RawMessage) (any, errx.Error) {
+	if r.handler == nil {
+		return nil, errx.Newf("no handler set")
+	}
+	var data I
+	if err := unmarshalData(conn, rawData, &data); err != nil {
+		return nil, err
+	}
+	res, err := r.handler(conn, data)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Publish handles the messages sent without id, the result is dropped.
+func (r *requestChannelBuilder[I, O]) Publish(conn Conn, data msgpack.RawMessage) errx.Error {
+	_, err := r.Handle(conn, data)
+	return err
+}
+
+func (r *requestChannelBuilder[I, O]) Subscribe(conn Conn, topic string) (Subscription, errx.Error) {
+	return nil, nil
+}
+
+func (r *requestChannelBuilder[I, O]) WithHandler(handler func(conn Conn, data I) (*O, errx.Error)) RequestChannelBuilder[I, O] {
+	o := *r
+	o.handler = handler
+	return &o
+}
+
+func (r *requestChannelBuilder[I, O]) WithAuthorizer(authorize func(conn Conn, params TopicParams) errx.Error) RequestChannelBuilder[I, O] {
+	o := *r
+	o.authorize = authorize
+	return &o
+}
+
+func (r *requestChannelBuilder[I, O]) WithDescription(description string) RequestChannelBuilder[I, O] {
+	o := *r
+	o.description = description
+	return &o
+}
+
+func (r *requestChannelBuilder[I, O]) Topic() string {
+	return r.topic
+}
+
+func (r *requestChannelBuilder[I, O]) MessageType() reflect.Type {
+	return reflect.TypeOf(*(new(I)))
+}
+
+func (r *requestChannelBuilder[I, O]) ResponseType() reflect.Type {
+	return reflect.TypeOf(*(new(O)))
+}
+
+func (r *requestChannelBuilder[I, O]) Description() string {
+	return r.description
+}
+
 type subscription struct {
 	unsubscribe func()
 }
@@ -0,0 +1,359 @@
+package wsx
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/tencent-go/pkg/ctxx"
+	"github.com/tencent-go/pkg/errx"
+	"github.com/tencent-go/pkg/natsx"
+	"github.com/tencent-go/pkg/types"
+	"github.com/tencent-go/pkg/util"
+	"github.com/nats-io/nats.go"
+	"github.com/sirupsen/logrus"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Registry indexes the connections of the local server.
+type Registry interface {
+	Conns() []Conn
+	UserConns(userID string) []Conn
+	SessionConns(sessionID string) []Conn
+}
+
+// Cluster routes messages to the connections of all the servers sharing the same cluster name through nats.
+type Cluster interface {
+	Registry
+	SendToUser(userID string, topic string, data any) errx.Error       // 不檢查訂閱, 如同Conn.Send
+	SendToSession(sessionID string, topic string, data any) errx.Error // 不檢查訂閱, 如同Conn.Send
+	BroadcastTopic(topic string, data any) errx.Error                  // 僅發送給已訂閱topic的連線
+	OnPresence(fn func(event PresenceEvent))                           // events of all the nodes, see PresenceEvent
+	Close()
+}
+
+type ClusterConfig struct {
+	Name       string                    // subjects are wsx.<Name>.deliver and wsx.<Name>.presence
+	UserKey    util.StorageValue[string] // user id stored by the authorizer
+	SessionKey util.StorageValue[string] // optional session id stored by the authorizer
+	Conn       *nats.Conn                // natsx default connection when nil
+}
+
+// PresenceEvent is published by a server when a connection of a user opens or closes. Events are per node,
+// Online is false once the user has no connection left on Node although the user may still be connected to
+// other nodes; listeners needing the global presence count the connections by node.
+type PresenceEvent struct {
+	UserID      string   `json:"userId"`
+	SessionID   string   `json:"sessionId,omitempty"`
+	Online      bool     `json:"online"`
+	Node        types.ID `json:"node"`
+	Connections int      `json:"connections"` // connections of the user left on the node
+}
+
+type clusterDelivery struct {
+	Origin     types.ID `json:"origin"`
+	Users      []string `json:"users,omitempty"`
+	Sessions   []string `json:"sessions,omitempty"`
+	Topic      string   `json:"topic"`
+	Data       []byte   `json:"data"`                 // msgpack encoded
+	Subscribed bool     `json:"subscribed,omitempty"` // only to the connections subscribing the topic
+}
+
+// NewCluster registers the connections of srv, including those already open, and subscribes the cluster subjects.
+func NewCluster(srv Server, config ClusterConfig) (Cluster, errx.Error) {
+	s, ok := srv.(*server)
+	if !ok {
+		return nil, errx.New("cluster requires a server created by NewServer")
+	}
+	if config.Name == "" || strings.ContainsAny(config.Name, ".*> \t") {
+		return nil, errx.Newf("invalid cluster name %q", config.Name)
+	}
+	if config.UserKey == nil {
+		return nil, errx.New("cluster user key is required")
+	}
+	delivery := natsx.NewSubjectBuilder[clusterDelivery]("wsx.{cluster}.deliver").WithArgs(config.Name)
+	presence := natsx.NewSubjectBuilder[PresenceEvent]("wsx.{cluster}.presence").WithArgs(config.Name)
+	if config.Conn != nil {
+		delivery = delivery.WithConn(config.Conn)
+		presence = presence.WithConn(config.Conn)
+	}
+	deliveryBus, err := newNatsBus(delivery)
+	if err != nil {
+		return nil, err
+	}
+	presenceBus, err := newNatsBus(presence)
+	if err != nil {
+		return nil, err
+	}
+	return startCluster(s, config, deliveryBus, presenceBus)
+}
+
+// clusterBus carries the messages of a cluster subject between the nodes.
+type clusterBus[T any] interface {
+	natsx.Publisher[T]
+	Subscribe(fn func(payload T)) (unsubscribe func(), err errx.Error)
+}
+
+type natsBus[T any] struct {
+	natsx.Publisher[T]
+	subscriber natsx.Subscriber[T]
+}
+
+func newNatsBus[T any](subject natsx.SubjectBuilder[T]) (*natsBus[T], errx.Error) {
+	publisher, err := subject.Publisher()
+	if err != nil {
+		return nil, err
+	}
+	return &natsBus[T]{Publisher: publisher, subscriber: subject.Subscriber()}, nil
+}
+
+func (b *natsBus[T]) Subscribe(fn func(payload T)) (func(), errx.Error) {
+	sub, err := b.subscriber.Subscribe(func(ctx natsx.NatsMessageContext, payload T) errx.Error {
+		fn(payload)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		if e := sub.Unsubscribe(); e != nil {
+			logrus.WithError(e).Error("unsubscribe websocket cluster failed")
+		}
+	}, nil
+}
+
+// startCluster subscribes the buses and registers the connections of s.
+func startCluster(s *server, config ClusterConfig, delivery clusterBus[clusterDelivery], presence clusterBus[PresenceEvent]) (*cluster, errx.Error) {
+	c := newCluster(s, config)
+	c.deliveryPublisher, c.presencePublisher = delivery, presence
+	unsubscribe, err := delivery.Subscribe(func(payload clusterDelivery) {
+		// 本節點發送時已直接投遞
+		if payload.Origin != c.node {
+			c.deliver(payload)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.unsubscribes = append(c.unsubscribes, unsubscribe)
+	unsubscribe, err = presence.Subscribe(func(payload PresenceEvent) {
+		c.mu.RLock()
+		listeners := c.presenceListeners
+		c.mu.RUnlock()
+		for _, fn := range listeners {
+			fn(payload)
+		}
+	})
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	c.unsubscribes = append(c.unsubscribes, unsubscribe)
+	c.removeHooks = s.addHooks(c.register, c.unregister)
+	return c, nil
+}
+
+// newCluster creates the registry of s, the publishers and subscriptions are left to startCluster.
+func newCluster(s *server, config ClusterConfig) *cluster {
+	return &cluster{
+		server:   s,
+		config:   config,
+		node:     types.NewID(),
+		conns:    make(map[Conn]struct{}),
+		users:    make(map[string]map[Conn]struct{}),
+		sessions: make(map[string]map[Conn]struct{}),
+	}
+}
+
+type cluster struct {
+	server            *server
+	config            ClusterConfig
+	node              types.ID
+	deliveryPublisher natsx.Publisher[clusterDelivery]
+	presencePublisher natsx.Publisher[PresenceEvent]
+	unsubscribes      []func()
+	removeHooks       func()
+	mu                sync.RWMutex
+	conns             map[Conn]struct{}
+	users             map[string]map[Conn]struct{}
+	sessions          map[string]map[Conn]struct{}
+	presenceListeners []func(event PresenceEvent)
+}
+
+func (c *cluster) keys(conn Conn) (userID, sessionID string) {
+	userID, _ = c.config.UserKey.Get(conn.Storage())
+	if c.config.SessionKey != nil {
+		sessionID, _ = c.config.SessionKey.Get(conn.Storage())
+	}
+	return
+}
+
+func (c *cluster) register(conn Conn) {
+	userID, sessionID := c.keys(conn)
+	c.mu.Lock()
+	c.conns[conn] = struct{}{}
+	addIndex(c.users, userID, conn)
+	addIndex(c.sessions, sessionID, conn)
+	count := len(c.users[userID])
+	c.mu.Unlock()
+	c.publishPresence(userID, sessionID, true, count)
+}
+
+func (c *cluster) unregister(conn Conn) {
+	userID, sessionID := c.keys(conn)
+	c.mu.Lock()
+	delete(c.conns, conn)
+	removeIndex(c.users, userID, conn)
+	removeIndex(c.sessions, sessionID, conn)
+	count := len(c.users[userID])
+	c.mu.Unlock()
+	c.publishPresence(userID, sessionID, false, count)
+}
+
+func (c *cluster) publishPresence(userID, sessionID string, online bool, count int) {
+	if userID == "" {
+		return
+	}
+	ev := PresenceEvent{
+		UserID:      userID,
+		SessionID:   sessionID,
+		Online:      online,
+		Node:        c.node,
+		Connections: count,
+	}
+	if err := c.presencePublisher.Publish(ctxx.Background(), ev); err != nil {
+		logrus.WithError(err).Error("publish websocket presence failed")
+	}
+}
+
+func addIndex(index map[string]map[Conn]struct{}, key string, conn Conn) {
+	if key == "" {
+		return
+	}
+	set, ok := index[key]
+	if !ok {
+		set = make(map[Conn]struct{})
+		index[key] = set
+	}
+	set[conn] = struct{}{}
+}
+
+func removeIndex(index map[string]map[Conn]struct{}, key string, conn Conn) {
+	if set, ok := index[key]; ok {
+		delete(set, conn)
+		if len(set) == 0 {
+			delete(index, key)
+		}
+	}
+}
+
+func setToSlice(set map[Conn]struct{}) []Conn {
+	res := make([]Conn, 0, len(set))
+	for conn := range set {
+		res = append(res, conn)
+	}
+	return res
+}
+
+func (c *cluster) Conns() []Conn {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return setToSlice(c.conns)
+}
+
+func (c *cluster) UserConns(userID string) []Conn {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return setToSlice(c.users[userID])
+}
+
+func (c *cluster) SessionConns(sessionID string) []Conn {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return setToSlice(c.sessions[sessionID])
+}
+
+func (c *cluster) SendToUser(userID string, topic string, data any) errx.Error {
+	return c.send(clusterDelivery{Users: []string{userID}, Topic: topic}, data)
+}
+
+func (c *cluster) SendToSession(sessionID string, topic string, data any) errx.Error {
+	return c.send(clusterDelivery{Sessions: []string{sessionID}, Topic: topic}, data)
+}
+
+func (c *cluster) BroadcastTopic(topic string, data any) errx.Error {
+	return c.send(clusterDelivery{Topic: topic, Subscribed: true}, data)
+}
+
+// send delivers to the local connections at once and to the other nodes through nats.
+func (c *cluster) send(d clusterDelivery, data any) errx.Error {
+	raw, err := util.Msgpack().Marshal(data)
+	if err != nil {
+		return err
+	}
+	d.Origin = c.node
+	d.Data = raw
+	c.deliver(d)
+	return c.deliveryPublisher.Publish(ctxx.Background(), d)
+}
+
+func (c *cluster) deliver(d clusterDelivery) {
+	set := make(map[Conn]struct{})
+	c.mu.RLock()
+	if len(d.Users) == 0 && len(d.Sessions) == 0 {
+		for conn := range c.conns {
+			set[conn] = struct{}{}
+		}
+	}
+	for _, u := range d.Users {
+		for conn := range c.users[u] {
+			set[conn] = struct{}{}
+		}
+	}
+	for _, s := range d.Sessions {
+		for conn := range c.sessions[s] {
+			set[conn] = struct{}{}
+		}
+	}
+	c.mu.RUnlock()
+	data := msgpack.RawMessage(d.Data)
+	if !d.Subscribed {
+		broadcast(sendMsgWrapper{Topic: d.Topic, Data: data}, setToSlice(set))
+		return
+	}
+	if c.server.sessions != nil {
+		// 斷線中的session仍持有訂閱, 訊息進入其buffer
+		for _, conn := range c.server.sessions.detached() {
+			set[conn] = struct{}{}
+		}
+	}
+	targets := make([]Conn, 0, len(set))
+	for conn := range set {
+		if conn.Subscriptions().Exists(d.Topic) {
+			targets = append(targets, conn)
+		}
+	}
+	broadcastTopic(d.Topic, data, targets)
+}
+
+func (c *cluster) OnPresence(fn func(event PresenceEvent)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.presenceListeners = append(c.presenceListeners, fn)
+}
+
+// Close stops routing, the connections opened afterwards are no longer registered.
+func (c *cluster) Close() {
+	if c.removeHooks != nil {
+		c.removeHooks()
+		c.removeHooks = nil
+	}
+	for _, unsubscribe := range c.unsubscribes {
+		unsubscribe()
+	}
+	c.unsubscribes = nil
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conns = make(map[Conn]struct{})
+	c.users = make(map[string]map[Conn]struct{})
+	c.sessions = make(map[string]map[Conn]struct{})
+}
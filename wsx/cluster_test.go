@@ -0,0 +1,354 @@
+package wsx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tencent-go/pkg/ctxx"
+	"github.com/tencent-go/pkg/errx"
+	"github.com/tencent-go/pkg/types"
+	"github.com/tencent-go/pkg/util"
+	"github.com/lxzan/gws"
+	"github.com/stretchr/testify/assert"
+)
+
+// testClient is a raw json client of the tests, frames are received in order.
+type testClient struct {
+	*gws.Conn
+	frames chan testFrame
+	closed chan struct{}
+}
+
+type testFrame struct {
+	Topic string          `json:"topic"`
+	ID    string          `json:"id,omitempty"`
+	Seq   uint64          `json:"seq,omitempty"`
+	Data  json.RawMessage `json:"data"`
+}
+
+func dialTest(t *testing.T, httpSrv *httptest.Server, header http.Header) *testClient {
+	c := &testClient{frames: make(chan testFrame, 256), closed: make(chan struct{})}
+	header = header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set("Sec-WebSocket-Protocol", SubprotocolJson)
+	conn, _, e := gws.NewClient(c, &gws.ClientOption{
+		Addr:          "ws" + strings.TrimPrefix(httpSrv.URL, "http"),
+		RequestHeader: header,
+	})
+	if !assert.NoError(t, e) {
+		t.FailNow()
+	}
+	c.Conn = conn
+	go conn.ReadLoop()
+	t.Cleanup(c.close)
+	return c
+}
+
+func (c *testClient) close() {
+	_ = c.WriteClose(1000, nil)
+	<-c.closed
+}
+
+func (c *testClient) OnOpen(socket *gws.Conn) {}
+
+func (c *testClient) OnClose(socket *gws.Conn, err error) {
+	close(c.closed)
+}
+
+func (c *testClient) OnPing(socket *gws.Conn, payload []byte) {}
+
+func (c *testClient) OnPong(socket *gws.Conn, payload []byte) {}
+
+func (c *testClient) OnMessage(socket *gws.Conn, message *gws.Message) {
+	defer func() {
+		_ = message.Close()
+	}()
+	var f testFrame
+	if e := json.Unmarshal(message.Bytes(), &f); e == nil {
+		c.frames <- f
+	}
+}
+
+func (c *testClient) publish(t *testing.T, topic string, data any) {
+	payload, e := json.Marshal(map[string]any{"topic": topic, "data": data})
+	if assert.NoError(t, e) {
+		assert.NoError(t, c.WriteMessage(gws.OpcodeText, payload))
+	}
+}
+
+// subscribe replaces the subscriptions and waits for the reply of the server.
+func (c *testClient) subscribe(t *testing.T, topics ...string) SubscribeTopicsEvent {
+	c.publish(t, SubscribeTopicsTopic, SubscribeTopicsEvent{Topics: append([]string{SubscribeTopicsTopic}, topics...)})
+	var res SubscribeTopicsEvent
+	for {
+		f := c.receive(t)
+		if f.Topic == SubscribeTopicsTopic {
+			assert.NoError(t, json.Unmarshal(f.Data, &res))
+			return res
+		}
+	}
+}
+
+func (c *testClient) receive(t *testing.T) testFrame {
+	select {
+	case f := <-c.frames:
+		return f
+	case <-time.After(3 * time.Second):
+		t.Fatal("no frame received")
+		return testFrame{}
+	}
+}
+
+func (c *testClient) assertNoFrame(t *testing.T) {
+	select {
+	case f := <-c.frames:
+		t.Errorf("unexpected frame %s: %s", f.Topic, f.Data)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+type fakePublisher[T any] struct {
+	mu   sync.Mutex
+	msgs []T
+}
+
+func (p *fakePublisher[T]) Publish(ctx ctxx.Context, msg T) errx.Error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.msgs = append(p.msgs, msg)
+	return nil
+}
+
+func (p *fakePublisher[T]) published() []T {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]T(nil), p.msgs...)
+}
+
+func TestCluster(t *testing.T) {
+	userKey, sessionKey := util.NewStorageValue[string]("user"), util.NewStorageValue[string]("session")
+	srv := NewServer().(*server)
+	srv.RegisterSubscribableChannels(NewEventChannel[string]("news"))
+	srv.SetAuthorizer(func(r *http.Request, storage util.Storage) bool {
+		userKey.Set(storage, r.Header.Get("X-User"))
+		if s := r.Header.Get("X-Session"); s != "" {
+			sessionKey.Set(storage, s)
+		}
+		return true
+	})
+	httpSrv := httptest.NewServer(http.HandlerFunc(srv.Upgrade))
+	defer httpSrv.Close()
+
+	c := newCluster(srv, ClusterConfig{Name: "test", UserKey: userKey, SessionKey: sessionKey})
+	deliveries, presences := &fakePublisher[clusterDelivery]{}, &fakePublisher[PresenceEvent]{}
+	c.deliveryPublisher, c.presencePublisher = deliveries, presences
+	c.removeHooks = srv.addHooks(c.register, c.unregister)
+
+	header := func(user, session string) http.Header {
+		return http.Header{"X-User": {user}, "X-Session": {session}}
+	}
+	a1 := dialTest(t, httpSrv, header("a", "s1"))
+	a2 := dialTest(t, httpSrv, header("a", "s2"))
+	b := dialTest(t, httpSrv, header("b", "s3"))
+	assert.Eventually(t, func() bool { return len(c.Conns()) == 3 }, 3*time.Second, 10*time.Millisecond)
+
+	t.Run("索引", func(t *testing.T) {
+		assert.Len(t, c.UserConns("a"), 2)
+		assert.Len(t, c.UserConns("b"), 1)
+		assert.Empty(t, c.UserConns("c"))
+		assert.Len(t, c.SessionConns("s1"), 1)
+		events := presences.published()
+		if assert.Len(t, events, 3) {
+			assert.ElementsMatch(t, []int{1, 2, 1}, []int{events[0].Connections, events[1].Connections, events[2].Connections})
+			assert.True(t, events[0].Online)
+			assert.Equal(t, c.node, events[0].Node)
+		}
+	})
+
+	t.Run("發送給用戶及session不檢查訂閱", func(t *testing.T) {
+		assert.Nil(t, c.SendToUser("a", "news", "to a"))
+		for _, conn := range []*testClient{a1, a2} {
+			f := conn.receive(t)
+			assert.Equal(t, "news", f.Topic)
+			assert.JSONEq(t, `"to a"`, string(f.Data))
+		}
+		b.assertNoFrame(t)
+
+		assert.Nil(t, c.SendToSession("s2", "news", "to s2"))
+		assert.JSONEq(t, `"to s2"`, string(a2.receive(t).Data))
+		a1.assertNoFrame(t)
+
+		sent := deliveries.published()
+		if assert.Len(t, sent, 2) {
+			assert.Equal(t, []string{"a"}, sent[0].Users)
+			assert.Equal(t, []string{"s2"}, sent[1].Sessions)
+			assert.Equal(t, c.node, sent[1].Origin)
+		}
+	})
+
+	t.Run("廣播僅發送給訂閱者", func(t *testing.T) {
+		assert.Empty(t, b.subscribe(t, "news").Errors)
+		assert.Nil(t, c.BroadcastTopic("news", "headline"))
+		assert.JSONEq(t, `"headline"`, string(b.receive(t).Data))
+		a1.assertNoFrame(t)
+		a2.assertNoFrame(t)
+	})
+
+	t.Run("其他節點的投遞", func(t *testing.T) {
+		raw, err := util.Msgpack().Marshal("remote")
+		assert.Nil(t, err)
+		c.deliver(clusterDelivery{Origin: types.NewID(), Users: []string{"b"}, Sessions: []string{"s1"}, Topic: "news", Data: raw})
+		assert.JSONEq(t, `"remote"`, string(b.receive(t).Data))
+		assert.JSONEq(t, `"remote"`, string(a1.receive(t).Data))
+		a2.assertNoFrame(t)
+	})
+
+	t.Run("斷線", func(t *testing.T) {
+		a1.close()
+		assert.Eventually(t, func() bool { return len(c.UserConns("a")) == 1 }, 3*time.Second, 10*time.Millisecond)
+		events := presences.published()
+		last := events[len(events)-1]
+		assert.False(t, last.Online, "the event is per node")
+		assert.Equal(t, 1, last.Connections)
+		assert.Empty(t, c.SessionConns("s1"))
+	})
+
+	t.Run("關閉後不再登記連線", func(t *testing.T) {
+		c.Close()
+		assert.Empty(t, srv.connHooks())
+		dialTest(t, httpSrv, header("c", ""))
+		time.Sleep(100 * time.Millisecond)
+		assert.Empty(t, c.Conns())
+		assert.Empty(t, c.UserConns("c"))
+	})
+}
+
+// memoryBus connects the clusters of a test like a nats subject, every subscriber receives the messages
+// of all the nodes, its own included.
+type memoryBus[T any] struct {
+	mu   sync.Mutex
+	subs map[int]func(payload T)
+	next int
+}
+
+func (b *memoryBus[T]) Publish(ctx ctxx.Context, msg T) errx.Error {
+	b.mu.Lock()
+	subs := make([]func(payload T), 0, len(b.subs))
+	for _, fn := range b.subs {
+		subs = append(subs, fn)
+	}
+	b.mu.Unlock()
+	for _, fn := range subs {
+		fn(msg)
+	}
+	return nil
+}
+
+func (b *memoryBus[T]) Subscribe(fn func(payload T)) (func(), errx.Error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subs == nil {
+		b.subs = make(map[int]func(payload T))
+	}
+	id := b.next
+	b.next++
+	b.subs[id] = fn
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs, id)
+	}, nil
+}
+
+func TestClusterNodes(t *testing.T) {
+	userKey := util.NewStorageValue[string]("user")
+	newNode := func() (*server, *httptest.Server) {
+		srv := NewServer().(*server)
+		srv.RegisterSubscribableChannels(NewEventChannel[string]("news"))
+		srv.SetAuthorizer(func(r *http.Request, storage util.Storage) bool {
+			userKey.Set(storage, r.Header.Get("X-User"))
+			return true
+		})
+		httpSrv := httptest.NewServer(http.HandlerFunc(srv.Upgrade))
+		t.Cleanup(httpSrv.Close)
+		return srv, httpSrv
+	}
+	srvA, httpA := newNode()
+	srvB, httpB := newNode()
+	user := func(id string) http.Header {
+		return http.Header{"X-User": {id}}
+	}
+	// 建立cluster前已開啟的連線
+	early := dialTest(t, httpA, user("a"))
+	assert.Eventually(t, func() bool {
+		srvA.hooksMu.RLock()
+		defer srvA.hooksMu.RUnlock()
+		return len(srvA.conns) == 1
+	}, 3*time.Second, 10*time.Millisecond)
+
+	deliveries, presences := &memoryBus[clusterDelivery]{}, &memoryBus[PresenceEvent]{}
+	config := ClusterConfig{Name: "test", UserKey: userKey}
+	var (
+		mu     sync.Mutex
+		events []PresenceEvent
+	)
+	nodeB, err := startCluster(srvB, config, deliveries, presences)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer nodeB.Close()
+	nodeB.OnPresence(func(event PresenceEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	})
+	nodeA, err := startCluster(srvA, config, deliveries, presences)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer nodeA.Close()
+	b := dialTest(t, httpB, user("b"))
+	assert.Eventually(t, func() bool { return len(nodeB.Conns()) == 1 }, 3*time.Second, 10*time.Millisecond)
+
+	t.Run("登記已開啟的連線", func(t *testing.T) {
+		assert.Len(t, nodeA.UserConns("a"), 1)
+		mu.Lock()
+		defer mu.Unlock()
+		if assert.Len(t, events, 2) {
+			assert.Equal(t, PresenceEvent{UserID: "a", Online: true, Node: nodeA.node, Connections: 1}, events[0])
+			assert.Equal(t, nodeB.node, events[1].Node)
+		}
+	})
+
+	t.Run("跨節點發送", func(t *testing.T) {
+		assert.Nil(t, nodeB.SendToUser("a", "news", "from b"))
+		assert.JSONEq(t, `"from b"`, string(early.receive(t).Data))
+		b.assertNoFrame(t)
+	})
+
+	t.Run("略過本節點發出的投遞", func(t *testing.T) {
+		assert.Nil(t, nodeA.SendToUser("a", "news", "from a"))
+		assert.JSONEq(t, `"from a"`, string(early.receive(t).Data))
+		early.assertNoFrame(t)
+	})
+
+	t.Run("跨節點廣播僅發送給訂閱者", func(t *testing.T) {
+		assert.Empty(t, early.subscribe(t, "news").Errors)
+		assert.Nil(t, nodeB.BroadcastTopic("news", "headline"))
+		assert.JSONEq(t, `"headline"`, string(early.receive(t).Data))
+		b.assertNoFrame(t)
+	})
+
+	t.Run("關閉後不再接收", func(t *testing.T) {
+		nodeA.Close()
+		assert.Nil(t, nodeB.SendToUser("a", "news", "closed"))
+		early.assertNoFrame(t)
+	})
+}
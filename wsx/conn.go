@@ -2,6 +2,7 @@ package wsx
 
 import (
 	"github.com/tencent-go/pkg/errx"
+	"github.com/tencent-go/pkg/types"
 	"github.com/tencent-go/pkg/util"
 	"github.com/lxzan/gws"
 	"github.com/sirupsen/logrus"
@@ -16,6 +17,10 @@ type Conn interface {
 	RemoteAddr() net.Addr
 	Subscriptions() SubscriptionManager
 	Storage() util.Storage
+	Locale() types.Locale // 連線時由 Accept-Language 解析，用於本地化錯誤訊息
+	Format() Format       // 連線時協商的訊息格式
+	QueueDepth() int      // 排隊中待發送的訊息數, 未啟用佇列時為0
+	Dropped() uint64      // 佇列溢出時丟棄的訊息數
 	Close()
 }
 
@@ -23,30 +28,84 @@ type connWrapper struct {
 	*gws.Conn
 	subscriptions subscriptionManager
 	storage       sync.Map
+	locale        types.Locale
+	format        Format
 	closed        bool
+	queue         *outboundQueue // nil when the server has no outbound queue, messages are written at once
+	sessions      *sessionStore  // nil when the server has no session
+	session       *session       // guarded by the session store
+	onDropped     func(conn Conn, event DropEvent)
+}
+
+func (c *connWrapper) Locale() types.Locale {
+	return c.locale
 }
 
 func (c *connWrapper) Storage() util.Storage {
 	return &c.storage
 }
 
+// Format is the framing negotiated when upgrading.
+func (c *connWrapper) Format() Format {
+	return c.format
+}
+
 func (c *connWrapper) Subscriptions() SubscriptionManager {
 	return &c.subscriptions
 }
 
+func (c *connWrapper) QueueDepth() int {
+	if c.queue == nil {
+		return 0
+	}
+	return c.queue.depth()
+}
+
+func (c *connWrapper) Dropped() uint64 {
+	if c.queue == nil {
+		return 0
+	}
+	return c.queue.droppedCount()
+}
+
+// Send writes at once without outbound queue, otherwise it only queues the message. A message rejected by
+// the queue, e.g. by OverflowDropNewest, returns an error; the messages dropped later, once queued, are only
+// reported to the OnDropped hook of the server.
 func (c *connWrapper) Send(topic string, data any) errx.Error {
 	m := sendMsgWrapper{
 		Topic: topic,
 		Data:  data,
 	}
-	d, err := util.Msgpack().Marshal(m)
+	d, err := c.format.encode(m)
 	if err != nil {
 		return err
 	}
-	if e := c.WriteMessage(gws.OpcodeBinary, d); e != nil {
-		return errx.Wrap(e).Err()
+	return c.write(outbound{key: topic, topic: topic, opcode: c.format.opcode(), payload: d})
+}
+
+func (c *connWrapper) sendSeq(topic string, seq uint64, data any) errx.Error {
+	d, err := c.format.encode(sendMsgWrapper{
+		Topic: topic,
+		Seq:   seq,
+		Data:  data,
+	})
+	if err != nil {
+		return err
 	}
-	return nil
+	return c.write(outbound{key: topic, topic: topic, opcode: c.format.opcode(), payload: d})
+}
+
+func (c *connWrapper) reply(topic, id string, data any, detail *ErrorDetail) errx.Error {
+	d, err := c.format.encode(sendMsgWrapper{
+		Topic: topic,
+		ID:    id,
+		Data:  data,
+		Error: detail,
+	})
+	if err != nil {
+		return err
+	}
+	return c.write(outbound{key: topic + "#" + id, topic: topic, opcode: c.format.opcode(), payload: d})
 }
 
 func (c *connWrapper) AsyncSend(topic string, data any, callback func(err errx.Error)) {
@@ -54,19 +113,106 @@ func (c *connWrapper) AsyncSend(topic string, data any, callback func(err errx.E
 		Topic: topic,
 		Data:  data,
 	}
-	d, err := util.Msgpack().Marshal(m)
+	d, err := c.format.encode(m)
 	if err != nil {
 		if callback != nil {
 			callback(err)
 		}
+		return
+	}
+	if c.queue != nil {
+		_ = c.enqueue(outbound{key: topic, topic: topic, opcode: c.format.opcode(), payload: d, callback: callback})
+		return
 	}
-	c.WriteAsync(gws.OpcodeBinary, d, func(err error) {
+	c.WriteAsync(c.format.opcode(), d, func(err error) {
 		if callback != nil {
 			callback(errx.Wrap(err).Err())
 		}
 	})
 }
 
+func (c *connWrapper) write(o outbound) errx.Error {
+	if c.queue != nil {
+		return c.enqueue(o)
+	}
+	if e := c.WriteMessage(o.opcode, o.payload); e != nil {
+		return errx.Wrap(e).Err()
+	}
+	return nil
+}
+
+// enqueue returns an error when o itself is dropped, its callback gets the same error.
+func (c *connWrapper) enqueue(o outbound) errx.Error {
+	dropped, rejected, count, disconnect := c.queue.push(o)
+	if dropped == nil {
+		if !rejected {
+			return nil
+		}
+		err := errConnClosed(o.topic)
+		if o.callback != nil {
+			o.callback(err)
+		}
+		return err
+	}
+	err := errx.Define().WithType(errx.TypeRateLimit).WithMsgf("message of topic %s dropped", dropped.topic).Err()
+	if dropped.callback != nil {
+		dropped.callback(err)
+	}
+	if c.onDropped != nil {
+		c.onDropped(c, DropEvent{Topic: dropped.topic, Policy: c.queue.policy, Dropped: count})
+	}
+	if disconnect {
+		logrus.Warnf("websocket consumer %s too slow, disconnecting", c.RemoteAddr())
+		// 不寫close frame, 避免阻塞於過慢的連線; ReadLoop因此結束並觸發OnClose
+		c.discard()
+		_ = c.NetConn().Close()
+	}
+	if rejected {
+		return err
+	}
+	return nil
+}
+
+// writeLoop writes the queued messages in order until the queue is closed.
+func (c *connWrapper) writeLoop() {
+	for {
+		select {
+		case <-c.queue.notify:
+		case <-c.queue.done:
+			return
+		}
+		for {
+			o, ok := c.queue.pop()
+			if !ok {
+				break
+			}
+			var err errx.Error
+			if e := c.WriteMessage(o.opcode, o.payload); e != nil {
+				err = errx.Wrap(e).Err()
+			}
+			if o.callback != nil {
+				o.callback(err)
+			}
+		}
+	}
+}
+
+// discard closes the queue, the callbacks of the messages left get an error.
+func (c *connWrapper) discard() {
+	if c.queue == nil {
+		return
+	}
+	for _, o := range c.queue.close() {
+		if o.callback != nil {
+			o.callback(errConnClosed(o.topic))
+		}
+	}
+}
+
+func errConnClosed(topic string) errx.Error {
+	return errx.Define().WithType(errx.TypeNetwork).WithMsgf("connection closed before sending topic %s", topic).Err()
+}
+
 func (c *connWrapper) Close() {
 	if !c.closed {
 		c.closed = true
@@ -74,7 +220,11 @@ func (c *connWrapper) Close() {
 			logrus.WithError(e).Error("write close failed")
 		}
 	}
-	c.subscriptions.ClearAll()
+	c.discard()
+	if c.sessions == nil {
+		c.subscriptions.ClearAll()
+	}
+	// 啟用session時由OnClose決定是否保留訂閱
 }
 
 type SubscriptionManager interface {
@@ -125,7 +275,9 @@ func (s *subscriptionManager) Remove(topic string) {
 	if !ok {
 		return
 	}
-	v.Unsubscribe()
+	if v != nil {
+		v.Unsubscribe()
+	}
 	delete(s.subscriptions, topic)
 }
 
@@ -136,11 +288,35 @@ func (s *subscriptionManager) ClearAll() {
 		return
 	}
 	for _, sub := range s.subscriptions {
-		sub.Unsubscribe()
+		if sub != nil {
+			sub.Unsubscribe()
+		}
 	}
 	s.subscriptions = nil
 }
 
+// adopt moves the subscriptions of from, those already subscribed are unsubscribed.
+func (s *subscriptionManager) adopt(from *subscriptionManager) {
+	from.mu.Lock()
+	moved := from.subscriptions
+	from.subscriptions = nil
+	from.mu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.subscriptions == nil {
+		s.subscriptions = make(map[string]Subscription)
+	}
+	for topic, sub := range moved {
+		if _, ok := s.subscriptions[topic]; ok {
+			if sub != nil {
+				sub.Unsubscribe()
+			}
+			continue
+		}
+		s.subscriptions[topic] = sub
+	}
+}
+
 func (s *subscriptionManager) Retain(topics []string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
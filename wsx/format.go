@@ -0,0 +1,94 @@
+package wsx
+
+import (
+	"net/http"
+
+	"github.com/tencent-go/pkg/errx"
+	"github.com/tencent-go/pkg/util"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/lxzan/gws"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Format is the framing of a connection, negotiated by Upgrade.
+type Format string
+
+const (
+	FormatMsgpack Format = "msgpack" // binary frames, 預設
+	FormatJson    Format = "json"    // text frames, 便於瀏覽器除錯及第三方客戶端
+)
+
+const (
+	SubprotocolMsgpack = "wsx.msgpack"
+	SubprotocolJson    = "wsx.json"
+	FormatQueryKey     = "format" // 未使用subprotocol時, 以 ?format=json 選擇格式
+)
+
+// negotiateFormat prefers the subprotocol accepted by the upgrader over the query parameter.
+func negotiateFormat(subprotocol string, req *http.Request) Format {
+	switch subprotocol {
+	case SubprotocolJson:
+		return FormatJson
+	case SubprotocolMsgpack:
+		return FormatMsgpack
+	}
+	if Format(req.URL.Query().Get(FormatQueryKey)) == FormatJson {
+		return FormatJson
+	}
+	return FormatMsgpack
+}
+
+func (f Format) serializer() util.Serializer {
+	if f == FormatJson {
+		return util.Json()
+	}
+	return util.Msgpack()
+}
+
+func (f Format) opcode() gws.Opcode {
+	if f == FormatJson {
+		return gws.OpcodeText
+	}
+	return gws.OpcodeBinary
+}
+
+// encode marshals a frame, msgpack encoded data such as the payloads of the cluster is converted for json.
+func (f Format) encode(m sendMsgWrapper) ([]byte, errx.Error) {
+	if raw, ok := m.Data.(msgpack.RawMessage); ok && f == FormatJson {
+		var data any
+		if err := util.Msgpack().Unmarshal(raw, &data); err != nil {
+			return nil, err
+		}
+		m.Data = data
+	}
+	return f.serializer().Marshal(m)
+}
+
+// decode unmarshals a frame, the data is left encoded in the format of the connection.
+func (f Format) decode(payload []byte) (*receiveMsgWrapper, errx.Error) {
+	if f != FormatJson {
+		msg := &receiveMsgWrapper{}
+		if err := util.Msgpack().Unmarshal(payload, msg); err != nil {
+			return nil, err
+		}
+		return msg, nil
+	}
+	var msg struct {
+		Topic string              `json:"topic"`
+		ID    string              `json:"id,omitempty"`
+		Data  jsoniter.RawMessage `json:"data"`
+	}
+	if err := util.Json().Unmarshal(payload, &msg); err != nil {
+		return nil, err
+	}
+	return &receiveMsgWrapper{Topic: msg.Topic, ID: msg.ID, Data: msgpack.RawMessage(msg.Data)}, nil
+}
+
+// unmarshalData decodes the data of a frame received from conn.
+func unmarshalData(conn Conn, data []byte, dst any) errx.Error {
+	var f Format
+	if c, ok := conn.(*connWrapper); ok {
+		f = c.format
+	}
+	return f.serializer().Unmarshal(data, dst)
+}
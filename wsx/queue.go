@@ -0,0 +1,131 @@
+package wsx
+
+import (
+	"sync"
+
+	"github.com/tencent-go/pkg/errx"
+	"github.com/lxzan/gws"
+)
+
+// OverflowPolicy decides what happens when the outbound queue of a connection is full.
+type OverflowPolicy string
+
+const (
+	OverflowDropOldest OverflowPolicy = "drop_oldest" // 丟棄最早排隊的訊息
+	OverflowDropNewest OverflowPolicy = "drop_newest" // 丟棄新訊息
+	OverflowCoalesce   OverflowPolicy = "coalesce"    // 新訊息總是取代同topic排隊中的訊息, 佇列滿且無同topic時丟棄最早的
+	OverflowDisconnect OverflowPolicy = "disconnect"  // 斷開過慢的連線
+)
+
+// DropEvent is passed to the hook of Server.OnDropped for every message dropped by the queue.
+type DropEvent struct {
+	Topic   string
+	Policy  OverflowPolicy
+	Dropped uint64 // messages dropped on the connection so far
+}
+
+type outbound struct {
+	key      string // topic, or topic and id for replies, used by OverflowCoalesce
+	topic    string
+	opcode   gws.Opcode
+	payload  []byte
+	callback func(err errx.Error)
+}
+
+type outboundQueue struct {
+	mu      sync.Mutex
+	size    int
+	policy  OverflowPolicy
+	items   []outbound
+	dropped uint64
+	closed  bool
+	notify  chan struct{}
+	done    chan struct{}
+}
+
+func newOutboundQueue(size int, policy OverflowPolicy) *outboundQueue {
+	return &outboundQueue{
+		size:   size,
+		policy: policy,
+		items:  make([]outbound, 0, size),
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+}
+
+// push queues o, it returns the message dropped, rejected when it is o itself, and whether the connection
+// should be closed. A message replaced by OverflowCoalesce counts as dropped. o is rejected without being
+// dropped once the queue is closed.
+func (q *outboundQueue) push(o outbound) (dropped *outbound, rejected bool, count uint64, disconnect bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil, true, q.dropped, false
+	}
+	if q.policy == OverflowCoalesce {
+		for i := range q.items {
+			if q.items[i].key == o.key {
+				q.dropped++
+				d := q.items[i]
+				q.items[i] = o
+				return &d, false, q.dropped, false
+			}
+		}
+	}
+	if len(q.items) < q.size {
+		q.items = append(q.items, o)
+		select {
+		case q.notify <- struct{}{}:
+		default:
+		}
+		return nil, false, q.dropped, false
+	}
+	q.dropped++
+	switch q.policy {
+	case OverflowDropNewest:
+		return &o, true, q.dropped, false
+	case OverflowDisconnect:
+		return &o, true, q.dropped, true
+	}
+	d := q.items[0]
+	q.items = append(q.items[1:], o)
+	return &d, false, q.dropped, false
+}
+
+func (q *outboundQueue) pop() (outbound, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.items) == 0 {
+		return outbound{}, false
+	}
+	o := q.items[0]
+	q.items[0] = outbound{}
+	q.items = q.items[1:]
+	return o, true
+}
+
+func (q *outboundQueue) depth() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+func (q *outboundQueue) droppedCount() uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.dropped
+}
+
+// close stops the writer, the messages left are discarded with their callbacks.
+func (q *outboundQueue) close() []outbound {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil
+	}
+	q.closed = true
+	close(q.done)
+	items := q.items
+	q.items = nil
+	return items
+}
@@ -0,0 +1,117 @@
+package wsx
+
+import (
+	"testing"
+
+	"github.com/tencent-go/pkg/errx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutboundQueue(t *testing.T) {
+	msg := func(key string) outbound {
+		return outbound{key: key, topic: key, payload: []byte(key)}
+	}
+	keys := func(q *outboundQueue) []string {
+		var res []string
+		for _, o := range q.items {
+			res = append(res, string(o.payload))
+		}
+		return res
+	}
+
+	t.Run("丟棄最早", func(t *testing.T) {
+		q := newOutboundQueue(2, OverflowDropOldest)
+		q.push(msg("a"))
+		q.push(msg("b"))
+		dropped, rejected, count, disconnect := q.push(msg("c"))
+		assert.Equal(t, "a", dropped.topic)
+		assert.False(t, rejected)
+		assert.Equal(t, uint64(1), count)
+		assert.False(t, disconnect)
+		assert.Equal(t, []string{"b", "c"}, keys(q))
+	})
+
+	t.Run("丟棄最新", func(t *testing.T) {
+		q := newOutboundQueue(1, OverflowDropNewest)
+		dropped, rejected, _, _ := q.push(msg("a"))
+		assert.Nil(t, dropped)
+		assert.False(t, rejected)
+		dropped, rejected, _, _ = q.push(msg("b"))
+		assert.Equal(t, "b", dropped.topic)
+		assert.True(t, rejected)
+		assert.Equal(t, []string{"a"}, keys(q))
+	})
+
+	t.Run("斷線", func(t *testing.T) {
+		q := newOutboundQueue(1, OverflowDisconnect)
+		q.push(msg("a"))
+		_, rejected, _, disconnect := q.push(msg("b"))
+		assert.True(t, rejected)
+		assert.True(t, disconnect)
+	})
+
+	t.Run("合併未滿時也取代同topic", func(t *testing.T) {
+		q := newOutboundQueue(3, OverflowCoalesce)
+		q.push(msg("a"))
+		q.push(msg("b"))
+		o := msg("a")
+		o.payload = []byte("a2")
+		dropped, rejected, count, _ := q.push(o)
+		assert.Equal(t, "a", string(dropped.payload))
+		assert.False(t, rejected)
+		assert.Equal(t, uint64(1), count)
+		assert.Equal(t, []string{"a2", "b"}, keys(q))
+
+		q.push(msg("c"))
+		dropped, _, _, _ = q.push(msg("d"))
+		assert.Equal(t, "a2", string(dropped.payload), "no message of the topic, the oldest is dropped")
+		assert.Equal(t, []string{"b", "c", "d"}, keys(q))
+	})
+
+	t.Run("關閉後拒絕", func(t *testing.T) {
+		q := newOutboundQueue(2, OverflowDropOldest)
+		q.push(msg("a"))
+		assert.Len(t, q.close(), 1)
+		dropped, rejected, count, _ := q.push(msg("b"))
+		assert.Nil(t, dropped, "未丟棄")
+		assert.True(t, rejected)
+		assert.Zero(t, count)
+		_, ok := q.pop()
+		assert.False(t, ok)
+	})
+}
+
+func TestConnSendQueued(t *testing.T) {
+	var events []DropEvent
+	conn := &connWrapper{
+		format:    FormatJson,
+		queue:     newOutboundQueue(1, OverflowDropNewest),
+		onDropped: func(conn Conn, event DropEvent) { events = append(events, event) },
+	}
+	assert.Nil(t, conn.Send("a", 1))
+	err := conn.Send("b", 2)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, errx.TypeRateLimit, err.Type())
+	}
+	assert.Equal(t, []DropEvent{{Topic: "b", Policy: OverflowDropNewest, Dropped: 1}}, events)
+
+	var callbackErr errx.Error
+	conn.AsyncSend("c", 3, func(err errx.Error) { callbackErr = err })
+	assert.NotNil(t, callbackErr)
+	assert.Equal(t, 1, conn.QueueDepth())
+	assert.Equal(t, uint64(2), conn.Dropped())
+
+	t.Run("關閉後回報連線已關閉", func(t *testing.T) {
+		conn.discard()
+		err := conn.Send("d", 4)
+		if assert.NotNil(t, err) {
+			assert.Equal(t, errx.TypeNetwork, err.Type())
+		}
+		conn.AsyncSend("e", 5, func(err errx.Error) { callbackErr = err })
+		if assert.NotNil(t, callbackErr) {
+			assert.Equal(t, errx.TypeNetwork, callbackErr.Type())
+		}
+		assert.Len(t, events, 2, "不觸發OnDropped")
+		assert.Equal(t, uint64(2), conn.Dropped())
+	})
+}
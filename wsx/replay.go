@@ -0,0 +1,185 @@
+package wsx
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+
+	"github.com/tencent-go/pkg/errx"
+	"github.com/tencent-go/pkg/natsx"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// ReplayBuffer keeps the recent messages of each topic of a session for the session resuming after a
+// disconnection. The sequences of a topic of a session start at 1 and have no gap.
+type ReplayBuffer interface {
+	Append(session, topic string, data []byte) (seq uint64, err errx.Error) // data為msgpack編碼
+	Since(session, topic string, seq uint64) ([]ReplayMessage, errx.Error)  // seq之後仍保留的訊息, 由舊至新
+	Remove(session string) errx.Error                                       // session過期時刪除其訊息
+}
+
+type ReplayMessage struct {
+	Seq  uint64
+	Data []byte // msgpack encoded
+}
+
+// NewMemoryReplayBuffer keeps the last size messages of each topic of a session in memory.
+func NewMemoryReplayBuffer(size int) ReplayBuffer {
+	if size <= 0 {
+		size = defaultReplaySize
+	}
+	return &memoryReplayBuffer{size: size, sessions: make(map[string]map[string]*topicReplay)}
+}
+
+const defaultReplaySize = 100
+
+type memoryReplayBuffer struct {
+	mu       sync.Mutex
+	size     int
+	sessions map[string]map[string]*topicReplay
+}
+
+type topicReplay struct {
+	seq      uint64
+	messages []ReplayMessage
+}
+
+func (b *memoryReplayBuffer) Append(session, topic string, data []byte) (uint64, errx.Error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	topics, ok := b.sessions[session]
+	if !ok {
+		topics = make(map[string]*topicReplay)
+		b.sessions[session] = topics
+	}
+	t, ok := topics[topic]
+	if !ok {
+		t = &topicReplay{}
+		topics[topic] = t
+	}
+	t.seq++
+	if len(t.messages) == b.size {
+		copy(t.messages, t.messages[1:])
+		t.messages = t.messages[:b.size-1]
+	}
+	t.messages = append(t.messages, ReplayMessage{Seq: t.seq, Data: data})
+	return t.seq, nil
+}
+
+func (b *memoryReplayBuffer) Since(session, topic string, seq uint64) ([]ReplayMessage, errx.Error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t, ok := b.sessions[session][topic]
+	if !ok {
+		return nil, nil
+	}
+	var res []ReplayMessage
+	for _, m := range t.messages {
+		if m.Seq > seq {
+			res = append(res, m)
+		}
+	}
+	return res, nil
+}
+
+func (b *memoryReplayBuffer) Remove(session string) errx.Error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.sessions, session)
+	return nil
+}
+
+const replaySeqHeader = "Wsx-Seq"
+
+// NewStreamReplayBuffer stores the messages of topic t of session s on the subject <prefix>.s.t of stream,
+// the stream must contain <prefix>.> and bounds the buffer, e.g. with MaxMsgsPerSubject.
+func NewStreamReplayBuffer(stream natsx.Stream, prefix string) ReplayBuffer {
+	return &streamReplayBuffer{stream: stream, prefix: prefix}
+}
+
+type streamReplayBuffer struct {
+	stream natsx.Stream
+	prefix string
+}
+
+// Append numbers the message after the last one of the subject, concurrent appends of other nodes are
+// detected by the expected last subject sequence and retried.
+func (b *streamReplayBuffer) Append(session, topic string, data []byte) (uint64, errx.Error) {
+	s, err := b.stream.Stream()
+	if err != nil {
+		return 0, err
+	}
+	ctx := context.Background()
+	subject := b.prefix + "." + session + "." + topic
+	for attempt := 0; attempt < 10; attempt++ {
+		var seq, last uint64
+		msg, e := s.GetLastMsgForSubject(ctx, subject)
+		if e == nil {
+			last = msg.Sequence
+			seq, _ = strconv.ParseUint(msg.Header.Get(replaySeqHeader), 10, 64)
+		} else if !errors.Is(e, jetstream.ErrMsgNotFound) {
+			return 0, errx.Wrap(e).AppendMsgf("get last replay message of %s failed", topic).Err()
+		}
+		seq++
+		m := &nats.Msg{Subject: subject, Data: data, Header: nats.Header{}}
+		m.Header.Set(replaySeqHeader, strconv.FormatUint(seq, 10))
+		_, e = b.stream.JetStream().PublishMsg(ctx, m, jetstream.WithExpectLastSequencePerSubject(last))
+		if e == nil {
+			return seq, nil
+		}
+		var apiErr *jetstream.APIError
+		if !errors.As(e, &apiErr) || apiErr.ErrorCode != jetstream.JSErrCodeStreamWrongLastSequence {
+			return 0, errx.Wrap(e).AppendMsgf("append replay message of %s failed", topic).Err()
+		}
+	}
+	return 0, errx.Newf("append replay message of %s failed, too many concurrent appends", topic)
+}
+
+func (b *streamReplayBuffer) Since(session, topic string, seq uint64) ([]ReplayMessage, errx.Error) {
+	s, err := b.stream.Stream()
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	consumer, e := s.OrderedConsumer(ctx, jetstream.OrderedConsumerConfig{
+		FilterSubjects: []string{b.prefix + "." + session + "." + topic},
+		DeliverPolicy:  jetstream.DeliverAllPolicy,
+	})
+	if e != nil {
+		return nil, errx.Wrap(e).AppendMsgf("create replay consumer of %s failed", topic).Err()
+	}
+	var res []ReplayMessage
+	for {
+		batch, e := consumer.FetchNoWait(100)
+		if e != nil {
+			return nil, errx.Wrap(e).AppendMsgf("fetch replay messages of %s failed", topic).Err()
+		}
+		count := 0
+		for msg := range batch.Messages() {
+			count++
+			n, _ := strconv.ParseUint(msg.Headers().Get(replaySeqHeader), 10, 64)
+			if n > seq {
+				res = append(res, ReplayMessage{Seq: n, Data: msg.Data()})
+			}
+		}
+		if e = batch.Error(); e != nil {
+			return nil, errx.Wrap(e).AppendMsgf("fetch replay messages of %s failed", topic).Err()
+		}
+		if count < 100 {
+			return res, nil
+		}
+	}
+}
+
+func (b *streamReplayBuffer) Remove(session string) errx.Error {
+	s, err := b.stream.Stream()
+	if err != nil {
+		return err
+	}
+	if e := s.Purge(context.Background(), jetstream.WithPurgeSubject(b.prefix+"."+session+".>")); e != nil {
+		return errx.Wrap(e).AppendMsgf("remove replay messages of session %s failed", session).Err()
+	}
+	return nil
+}
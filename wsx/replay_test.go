@@ -0,0 +1,208 @@
+package wsx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tencent-go/pkg/errx"
+	"github.com/tencent-go/pkg/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryReplayBuffer(t *testing.T) {
+	b := NewMemoryReplayBuffer(2)
+	for _, data := range []string{"a", "b", "c"} {
+		_, err := b.Append("s1", "news", []byte(data))
+		assert.Nil(t, err)
+	}
+	seq, err := b.Append("s2", "news", []byte("x"))
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), seq, "每個session分別編號")
+
+	t.Run("僅保留最後的訊息", func(t *testing.T) {
+		messages, err := b.Since("s1", "news", 0)
+		assert.Nil(t, err)
+		assert.Equal(t, []ReplayMessage{{Seq: 2, Data: []byte("b")}, {Seq: 3, Data: []byte("c")}}, messages)
+		messages, err = b.Since("s1", "news", 2)
+		assert.Nil(t, err)
+		assert.Equal(t, []ReplayMessage{{Seq: 3, Data: []byte("c")}}, messages)
+	})
+
+	t.Run("session互不可見", func(t *testing.T) {
+		messages, err := b.Since("s2", "news", 0)
+		assert.Nil(t, err)
+		assert.Equal(t, []ReplayMessage{{Seq: 1, Data: []byte("x")}}, messages)
+		messages, err = b.Since("s3", "news", 0)
+		assert.Nil(t, err)
+		assert.Empty(t, messages)
+	})
+
+	t.Run("刪除session", func(t *testing.T) {
+		assert.Nil(t, b.Remove("s1"))
+		messages, err := b.Since("s1", "news", 0)
+		assert.Nil(t, err)
+		assert.Empty(t, messages)
+		seq, err := b.Append("s1", "news", []byte("d"))
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(1), seq)
+	})
+}
+
+func TestResume(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		conns []Conn
+		feeds = make(map[Conn]func(message string) errx.Error)
+	)
+	news := NewEventChannel[string]("news")
+	// 如同nats橋接, 訂閱期間持有send
+	feed := NewEventChannel[string]("feed").WithSubscriber(func(conn Conn, send func(message string) errx.Error) (func(), errx.Error) {
+		mu.Lock()
+		defer mu.Unlock()
+		feeds[conn] = send
+		return func() {}, nil
+	})
+	userKey := util.NewStorageValue[string]("user")
+	secret := NewEventChannel[string]("secret").WithAuthorizer(func(conn Conn, params TopicParams) errx.Error {
+		if user, _ := userKey.Get(conn.Storage()); user != "b" {
+			return errx.Define().WithType(errx.TypeAuthorization).WithMsg("secret is only for b").Err()
+		}
+		return nil
+	})
+	srv := NewServer().(*server)
+	srv.SetResume(ResumeConfig{})
+	srv.RegisterSubscribableChannels(news, feed, secret)
+	srv.SetAuthorizer(func(r *http.Request, storage util.Storage) bool {
+		userKey.Set(storage, r.Header.Get("X-User"))
+		return true
+	})
+	srv.OnConnected(func(conn Conn) {
+		mu.Lock()
+		defer mu.Unlock()
+		conns = append(conns, conn)
+	})
+	httpSrv := httptest.NewServer(http.HandlerFunc(srv.Upgrade))
+	defer httpSrv.Close()
+	c := newCluster(srv, ClusterConfig{Name: "test", UserKey: userKey})
+	c.deliveryPublisher, c.presencePublisher = &fakePublisher[clusterDelivery]{}, &fakePublisher[PresenceEvent]{}
+	c.removeHooks = srv.addHooks(c.register, c.unregister)
+	defer c.Close()
+
+	connect := func(user string) (*testClient, Conn, string) {
+		mu.Lock()
+		count := len(conns)
+		mu.Unlock()
+		client := dialTest(t, httpSrv, http.Header{"X-User": {user}})
+		f := client.receive(t)
+		assert.Equal(t, SessionTopic, f.Topic)
+		var ev SessionEvent
+		assert.NoError(t, json.Unmarshal(f.Data, &ev))
+		assert.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(conns) == count+1
+		}, 3*time.Second, 10*time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		return client, conns[count], ev.Token
+	}
+	send := func(conn Conn, message string) {
+		mu.Lock()
+		fn := feeds[conn]
+		mu.Unlock()
+		assert.Nil(t, fn(message))
+	}
+	expect := func(client *testClient, topic string, seq uint64, data string) {
+		f := client.receive(t)
+		assert.Equal(t, topic, f.Topic)
+		assert.Equal(t, seq, f.Seq)
+		assert.JSONEq(t, data, string(f.Data))
+	}
+
+	a, connA, tokenA := connect("a")
+	b, connB, _ := connect("b")
+	assert.Empty(t, a.subscribe(t, "news", "feed").Errors)
+	assert.Empty(t, b.subscribe(t, "news").Errors)
+
+	t.Run("每個session分別編號", func(t *testing.T) {
+		Broadcast(news, []Conn{connA}, "x")
+		expect(a, "news", 1, `"x"`)
+		b.assertNoFrame(t)
+		Broadcast(news, []Conn{connA, connB}, "y")
+		expect(a, "news", 2, `"y"`)
+		expect(b, "news", 1, `"y"`)
+		send(connA, "f1")
+		expect(a, "feed", 1, `"f1"`)
+	})
+
+	t.Run("斷線後訊息仍進入buffer", func(t *testing.T) {
+		a.close()
+		b.close()
+		assert.Eventually(t, func() bool { return len(srv.sessions.detached()) == 2 }, 3*time.Second, 10*time.Millisecond)
+		assert.Empty(t, c.Conns())
+		assert.True(t, connA.Subscriptions().Exists("feed"), "訂閱保留至session過期")
+		assert.Nil(t, c.BroadcastTopic("news", "z"))
+		Broadcast(news, []Conn{connB}, "only b")
+		send(connA, "f2")
+	})
+
+	t.Run("恢復後依序重播", func(t *testing.T) {
+		a2, connA2, _ := connect("a")
+		a2.publish(t, ResumeTopic, ResumeEvent{Token: tokenA, Sequences: map[string]uint64{"news": 2, "feed": 1}})
+		assert.Equal(t, SubscribeTopicsTopic, a2.receive(t).Topic)
+		expect(a2, "feed", 2, `"f2"`)
+		expect(a2, "news", 3, `"z"`)
+		f := a2.receive(t)
+		assert.Equal(t, SessionTopic, f.Topic)
+		assert.JSONEq(t, `{"token":"`+tokenA+`","resumed":true}`, string(f.Data))
+		a2.assertNoFrame(t)
+
+		// 原訂閱的send送往新連線
+		send(connA, "f3")
+		expect(a2, "feed", 3, `"f3"`)
+		assert.Equal(t, []string{"feed", "news", SubscribeTopicsTopic}, connA2.Subscriptions().Topics())
+		assert.Empty(t, connA.Subscriptions().Topics())
+	})
+
+	t.Run("其他身分恢復時重新授權", func(t *testing.T) {
+		owner, connOwner, token := connect("b")
+		assert.Empty(t, owner.subscribe(t, "news", "secret").Errors)
+		owner.close()
+		assert.Eventually(t, func() bool { return len(srv.sessions.detached()) == 2 }, 3*time.Second, 10*time.Millisecond)
+		Broadcast(secret, []Conn{connOwner}, "classified")
+
+		other, connOther, _ := connect("c")
+		other.publish(t, ResumeTopic, ResumeEvent{Token: token})
+		f := other.receive(t)
+		assert.Equal(t, SubscribeTopicsTopic, f.Topic)
+		var ev SubscribeTopicsEvent
+		assert.NoError(t, json.Unmarshal(f.Data, &ev))
+		assert.Contains(t, ev.Errors, "secret")
+		assert.NotContains(t, ev.Topics, "secret")
+		f = other.receive(t)
+		assert.Equal(t, SessionTopic, f.Topic, "未授權的topic不重播")
+		other.assertNoFrame(t)
+		assert.False(t, connOther.Subscriptions().Exists("secret"))
+		assert.True(t, connOther.Subscriptions().Exists("news"))
+	})
+
+	t.Run("過期後釋放訂閱及buffer", func(t *testing.T) {
+		s := srv.sessions.find(connB.(*connWrapper))
+		if !assert.NotNil(t, s) {
+			return
+		}
+		srv.sessions.mu.Lock()
+		s.expires = time.Now()
+		srv.sessions.mu.Unlock()
+		srv.sessions.expire(s)
+		assert.Nil(t, srv.sessions.find(connB.(*connWrapper)))
+		assert.Empty(t, connB.Subscriptions().Topics())
+		messages, err := srv.sessions.buffer.Since(s.token, "news", 0)
+		assert.Nil(t, err)
+		assert.Empty(t, messages)
+	})
+}
@@ -1,12 +1,13 @@
 package wsx
 
 import (
-	"fmt"
 	"net/http"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/tencent-go/pkg/errx"
+	"github.com/tencent-go/pkg/types"
 	"github.com/tencent-go/pkg/util"
 	"github.com/lxzan/gws"
 	"github.com/sirupsen/logrus"
@@ -15,6 +16,10 @@ import (
 type Server interface {
 	SetKeepaliveInterval(duration time.Duration)
 	SetAuthorizer(func(request *http.Request, storage util.Storage) bool)
+	SetOutboundQueue(size int, policy OverflowPolicy) // 每個連線的發送佇列長度, 0為直接寫入(預設)
+	SetCompression(threshold int)                     // 啟用permessage-deflate, 小於threshold位元組的訊息不壓縮
+	SetResume(config ResumeConfig)                    // 啟用session, 斷線重連後可恢復訂閱並重播錯過的訊息
+	OnDropped(func(conn Conn, event DropEvent))
 	OnConnected(func(conn Conn))
 	OnDisconnected(func(conn Conn))
 	RegisterSubscribableChannels(events ...EventChannel) //客戶端可訂閱的channel
@@ -26,8 +31,8 @@ type Server interface {
 
 const (
 	wrapperKey           = "wrapped"
-	subscribeTopicsTopic = "subscribe_topics"
-	errorTopic           = "error_notification"
+	SubscribeTopicsTopic = "subscribe_topics"   // 客戶端發佈完整的訂閱列表, 伺服器回覆生效的列表
+	ErrorTopic           = "error_notification" // 無需訂閱
 )
 
 func NewServer() Server {
@@ -37,18 +42,28 @@ func NewServer() Server {
 	}
 
 	var (
-		subscribeTopicsChannel = NewEventChannel[SubscribeTopicsEvent](subscribeTopicsTopic)
-		errorChannel           = NewEventChannel[ErrorEvent](errorTopic)
+		subscribeTopicsChannel = NewEventChannel[SubscribeTopicsEvent](SubscribeTopicsTopic)
+		errorChannel           = NewEventChannel[ErrorEvent](ErrorTopic)
 	)
 
 	srv.RegisterPublishableChannels(subscribeTopicsChannel.WithPublisher(func(conn Conn, data SubscribeTopicsEvent) errx.Error {
 		return srv.updateTopics(conn, data)
 	}))
 	srv.RegisterSubscribableChannels(subscribeTopicsChannel, errorChannel)
-	srv.upgrader = gws.NewUpgrader(srv, nil)
+	srv.upgrader = gws.NewUpgrader(srv, srv.upgraderOption())
 	return srv
 }
 
+func (srv *server) upgraderOption() *gws.ServerOption {
+	return &gws.ServerOption{
+		SubProtocols: []string{SubprotocolMsgpack, SubprotocolJson},
+		PermessageDeflate: gws.PermessageDeflate{
+			Enabled:   srv.deflate,
+			Threshold: srv.deflateThreshold,
+		},
+	}
+}
+
 type server struct {
 	keepaliveInterval    time.Duration
 	authorize            func(r *http.Request, session util.Storage) bool
@@ -57,12 +72,86 @@ type server struct {
 	onDisconnect         func(conn Conn)
 	subscribableChannels map[string]EventChannel
 	publishableChannels  map[string]EventChannel
+	templateChannels     []templateChannel // subscribable channels with placeholders, in registration order
+	resumeConfig         ResumeConfig
+	sessions             *sessionStore // nil when resume is disabled
+	deflate              bool
+	deflateThreshold     int
+	queueSize            int
+	overflowPolicy       OverflowPolicy
+	onDropped            func(conn Conn, event DropEvent)
+	hooksMu              sync.RWMutex
+	hooks                []*connHooks      // internal listeners, e.g. the cluster registry
+	conns                map[Conn]struct{} // open connections, guarded by hooksMu
+}
+
+type connHooks struct {
+	connect    func(conn Conn)
+	disconnect func(conn Conn)
+}
+
+// addHooks registers internal listeners of the connections, connect is called at once for the open
+// connections. The returned func removes them.
+func (srv *server) addHooks(connect, disconnect func(conn Conn)) func() {
+	h := &connHooks{connect: connect, disconnect: disconnect}
+	srv.hooksMu.Lock()
+	srv.hooks = append(srv.hooks, h)
+	// 持有鎖, 以免連線在登記前關閉
+	for conn := range srv.conns {
+		connect(conn)
+	}
+	srv.hooksMu.Unlock()
+	return func() {
+		srv.hooksMu.Lock()
+		defer srv.hooksMu.Unlock()
+		for i, it := range srv.hooks {
+			if it == h {
+				// 複製而非原地刪除, 不影響OnOpen及OnClose正在走訪的slice
+				srv.hooks = append(srv.hooks[:i:i], srv.hooks[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func (srv *server) connHooks() []*connHooks {
+	srv.hooksMu.RLock()
+	defer srv.hooksMu.RUnlock()
+	return srv.hooks
+}
+
+// trackConn adds or removes conn of the open connections and returns the hooks to be called, a hook added
+// afterwards sees conn through addHooks instead.
+func (srv *server) trackConn(conn Conn, open bool) []*connHooks {
+	srv.hooksMu.Lock()
+	defer srv.hooksMu.Unlock()
+	if open {
+		if srv.conns == nil {
+			srv.conns = make(map[Conn]struct{})
+		}
+		srv.conns[conn] = struct{}{}
+	} else {
+		delete(srv.conns, conn)
+	}
+	return srv.hooks
+}
+
+type templateChannel struct {
+	template *topicTemplate
+	channel  EventChannel
 }
 
 func (srv *server) OnOpen(socket *gws.Conn) {
 	logrus.Debug("websocket connection opened")
+	c := getWrappedConn(socket)
+	if srv.sessions != nil {
+		srv.openSession(c)
+	}
+	for _, hook := range srv.trackConn(c, true) {
+		hook.connect(c)
+	}
 	if srv.onConnect != nil {
-		srv.onConnect(getWrappedConn(socket))
+		srv.onConnect(c)
 	}
 }
 
@@ -70,10 +159,18 @@ func (srv *server) OnClose(socket *gws.Conn, err error) {
 	logrus.WithError(err).Debug("websocket connection closed")
 	c := getWrappedConn(socket)
 	c.closed = true
+	// 保留的session仍持有訂閱, 其訊息進入buffer直到恢復或過期
+	kept := srv.sessions != nil && srv.sessions.detach(c)
+	for _, hook := range srv.trackConn(c, false) {
+		hook.disconnect(c)
+	}
 	if srv.onDisconnect != nil {
 		srv.onDisconnect(c)
 	}
-	c.Close()
+	c.discard()
+	if !kept {
+		c.subscriptions.ClearAll()
+	}
 	socket.Session().Delete(wrapperKey)
 }
 
@@ -98,26 +195,38 @@ func (srv *server) OnPong(socket *gws.Conn, payload []byte) {
 func (srv *server) updateTopics(conn Conn, ev SubscribeTopicsEvent) errx.Error {
 	manager := conn.Subscriptions()
 	manager.Retain(ev.Topics)
+	errs := make(map[string]*ErrorDetail)
 	for _, topic := range ev.Topics {
-		if !manager.Exists(topic) {
-			if sub, ok := srv.subscribableChannels[topic]; ok {
-				manager.SetIfAbsent(topic, func() (Subscription, bool) {
-					res, err := sub.Subscribe(conn)
-					if err != nil {
-						logrus.WithError(err).Error("subscribe failed")
-						return nil, false
-					}
-					return res, true
-				})
-			}
+		if manager.Exists(topic) {
+			continue
 		}
-	}
-	if manager.Exists(subscribeTopicsTopic) {
-		return conn.Send(subscribeTopicsTopic, SubscribeTopicsEvent{
-			Topics: manager.Topics(),
+		channel, ok := srv.findSubscribable(topic)
+		if !ok {
+			errs[topic] = newErrorDetail(errx.Define().WithType(errx.TypeNotFound).WithMsgf("unknown topic: %s", topic).Err(), conn.Locale())
+			continue
+		}
+		if err := channel.Authorize(conn, topic); err != nil {
+			errs[topic] = newErrorDetail(err, conn.Locale())
+			continue
+		}
+		manager.SetIfAbsent(topic, func() (Subscription, bool) {
+			res, err := channel.Subscribe(conn, topic)
+			if err != nil {
+				logrus.WithError(err).Error("subscribe failed")
+				errs[topic] = newErrorDetail(err, conn.Locale())
+				return nil, false
+			}
+			return res, true
 		})
 	}
-	return errx.Newf("no topic %s found", subscribeTopicsTopic)
+	if manager.Exists(SubscribeTopicsTopic) {
+		res := SubscribeTopicsEvent{Topics: manager.Topics()}
+		if len(errs) > 0 {
+			res.Errors = errs
+		}
+		return conn.Send(SubscribeTopicsTopic, res)
+	}
+	return errx.Newf("no topic %s found", SubscribeTopicsTopic)
 }
 
 func (srv *server) OnMessage(socket *gws.Conn, message *gws.Message) {
@@ -127,32 +236,68 @@ func (srv *server) OnMessage(socket *gws.Conn, message *gws.Message) {
 			return
 		}
 	}
-	msg := &receiveMsgWrapper{}
-	if err := util.Msgpack().Unmarshal(message.Bytes(), msg); err != nil {
+	conn := getWrappedConn(socket)
+	msg, err := conn.format.decode(message.Bytes())
+	if err != nil {
 		logrus.WithError(err).Error("unmarshal msg failed")
 		return
 	}
-	conn := getWrappedConn(socket)
 	channel, ok := srv.publishableChannels[msg.Topic]
 	if !ok {
-		errMsg := fmt.Sprintf("unknown topic: %s", msg.Topic)
-		_ = conn.Send(errorTopic, ErrorEvent{Message: errMsg})
+		err := errx.Define().WithType(errx.TypeNotFound).WithMsgf("unknown topic: %s", msg.Topic).Err()
+		if msg.ID != "" {
+			srv.replyError(conn, msg, err)
+			return
+		}
+		_ = conn.Send(ErrorTopic, ErrorEvent{Message: errx.Localize(err, conn.Locale())})
+		return
+	}
+	if err := channel.Authorize(conn, msg.Topic); err != nil {
+		srv.replyError(conn, msg, err)
+		return
+	}
+	if msg.ID == "" {
+		if err := channel.Publish(conn, msg.Data); err != nil {
+			srv.replyError(conn, msg, err)
+		}
+		return
+	}
+	var res any
+	if rc, ok := channel.(RequestChannel); ok {
+		res, err = rc.Handle(conn, msg.Data)
+	} else {
+		err = channel.Publish(conn, msg.Data)
+	}
+	if err != nil {
+		srv.replyError(conn, msg, err)
 		return
 	}
-	if err := channel.Publish(conn, msg.Data); err != nil {
-		var errMsg string
-		if err.Type() != errx.TypeInternal {
-			errMsg = fmt.Sprintf("topic: %s %s", msg.Topic, err.Error())
-		} else {
-			errMsg = fmt.Sprintf("process message failed, topic: %s", msg.Topic)
+	if e := conn.reply(msg.Topic, msg.ID, res, nil); e != nil {
+		logrus.WithError(e).WithField("topic", msg.Topic).Error("reply failed")
+	}
+}
+
+// replyError replies on the id of the message when given, otherwise notifies on ErrorTopic.
+func (srv *server) replyError(conn *connWrapper, msg *receiveMsgWrapper, err errx.Error) {
+	if err.Type() == errx.TypeInternal {
+		logrus.WithError(err).WithField("topic", msg.Topic).Error("process message failed")
+	}
+	if msg.ID != "" {
+		if e := conn.reply(msg.Topic, msg.ID, nil, newErrorDetail(err, conn.Locale())); e != nil {
+			logrus.WithError(e).WithField("topic", msg.Topic).Error("reply failed")
 		}
-		_ = conn.Send(errorTopic, ErrorEvent{Message: errMsg})
 		return
 	}
+	if err.Type() != errx.TypeInternal {
+		err = errx.Wrap(err).AppendMsgf("topic: %s", msg.Topic).Err()
+	} else {
+		err = errx.Newf("process message failed, topic: %s", msg.Topic)
+	}
+	_ = conn.Send(ErrorTopic, ErrorEvent{Message: errx.Localize(err, conn.Locale())})
 }
 
 func (srv *server) Upgrade(res http.ResponseWriter, req *http.Request) {
-	wrapped := &connWrapper{}
+	wrapped := &connWrapper{locale: types.ParseAcceptLanguage(req.Header.Get("Accept-Language"))}
 	if srv.authorize != nil {
 		if !srv.authorize(req, wrapped.Storage()) {
 			return
@@ -164,6 +309,13 @@ func (srv *server) Upgrade(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 	wrapped.Conn = conn
+	wrapped.format = negotiateFormat(conn.SubProtocol(), req)
+	wrapped.sessions = srv.sessions
+	if srv.queueSize > 0 {
+		wrapped.queue = newOutboundQueue(srv.queueSize, srv.overflowPolicy)
+		wrapped.onDropped = srv.onDropped
+		go wrapped.writeLoop()
+	}
 	conn.Session().Store(wrapperKey, wrapped)
 	go func() {
 		end := make(chan struct{})
@@ -204,6 +356,36 @@ func (srv *server) SetKeepaliveInterval(interval time.Duration) {
 	srv.keepaliveInterval = interval
 }
 
+func (srv *server) SetOutboundQueue(size int, policy OverflowPolicy) {
+	if policy == "" {
+		policy = OverflowDropOldest
+	}
+	srv.queueSize = size
+	srv.overflowPolicy = policy
+}
+
+func (srv *server) SetResume(config ResumeConfig) {
+	if config.Buffer == nil {
+		config.Buffer = NewMemoryReplayBuffer(defaultReplaySize)
+	}
+	if srv.sessions == nil {
+		srv.RegisterPublishableChannels(NewEventChannel[ResumeEvent](ResumeTopic).WithPublisher(srv.resume))
+		srv.RegisterSubscribableChannels(NewEventChannel[SessionEvent](SessionTopic))
+	}
+	srv.resumeConfig = config
+	srv.sessions = newSessionStore(config.TTL, config.Buffer)
+}
+
+func (srv *server) SetCompression(threshold int) {
+	srv.deflate = true
+	srv.deflateThreshold = threshold
+	srv.upgrader = gws.NewUpgrader(srv, srv.upgraderOption())
+}
+
+func (srv *server) OnDropped(fn func(conn Conn, event DropEvent)) {
+	srv.onDropped = fn
+}
+
 func (srv *server) SetAuthorizer(fn func(r *http.Request, session util.Storage) bool) {
 	srv.authorize = fn
 }
@@ -215,7 +397,23 @@ func (srv *server) RegisterSubscribableChannels(events ...EventChannel) {
 			logrus.Panicf("duplicate subscribable channel for topic: %s", topic)
 		}
 		srv.subscribableChannels[topic] = event
+		if t := parseTopicTemplate(topic); t != nil {
+			srv.templateChannels = append(srv.templateChannels, templateChannel{template: t, channel: event})
+		}
+	}
+}
+
+// findSubscribable looks up the channel of topic, static topics take precedence over templates.
+func (srv *server) findSubscribable(topic string) (EventChannel, bool) {
+	if c, ok := srv.subscribableChannels[topic]; ok {
+		return c, true
+	}
+	for _, c := range srv.templateChannels {
+		if _, ok := c.template.match(topic); ok {
+			return c.channel, true
+		}
 	}
+	return nil, false
 }
 
 func (srv *server) RegisterPublishableChannels(events ...EventChannel) {
@@ -224,6 +422,9 @@ func (srv *server) RegisterPublishableChannels(events ...EventChannel) {
 		if _, ok := srv.publishableChannels[topic]; ok {
 			logrus.Panicf("duplicate publishable channel for topic: %s", topic)
 		}
+		if parseTopicTemplate(topic) != nil {
+			logrus.Panicf("publishable channel topic %s must not have placeholders", topic)
+		}
 		srv.publishableChannels[topic] = event
 	}
 }
@@ -0,0 +1,284 @@
+package wsx
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/tencent-go/pkg/errx"
+	"github.com/tencent-go/pkg/util"
+	"github.com/sirupsen/logrus"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	SessionTopic = "session" // 無需訂閱, 連線及恢復後伺服器發送 SessionEvent
+	ResumeTopic  = "resume"  // 客戶端重連後發佈 ResumeEvent, 恢復斷線前的訂閱並重播錯過的訊息
+)
+
+// ResumeConfig enables the sessions, the messages sent to the subscriptions of a session are numbered per
+// topic and kept by Buffer for the session resuming. The subscriptions of a closed connection stay alive
+// until its session expires, so the messages sent meanwhile are buffered as well.
+// A resuming connection keeps only the topics its own authorization allows.
+type ResumeConfig struct {
+	TTL    time.Duration // 斷線後保留session的時間, 預設1分鐘
+	Buffer ReplayBuffer  // 預設 NewMemoryReplayBuffer(100)
+}
+
+type SessionEvent struct {
+	Token   string `json:"token"`
+	Resumed bool   `json:"resumed,omitempty"`
+}
+
+type ResumeEvent struct {
+	Token     string            `json:"token"`
+	Sequences map[string]uint64 `json:"sequences,omitempty"` // 各topic最後收到的seq
+}
+
+type session struct {
+	token   string
+	conn    *connWrapper // nil when detached or resuming
+	holder  *connWrapper // connection owning the subscriptions, the closed one when detached
+	expires time.Time
+	timer   *time.Timer // expires the session, nil when attached
+	expired bool
+	sendMu  sync.Mutex // orders the numbering and the sending of the messages
+}
+
+type sessionStore struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	buffer   ReplayBuffer
+	sessions map[string]*session
+}
+
+func newSessionStore(ttl time.Duration, buffer ReplayBuffer) *sessionStore {
+	if ttl <= 0 {
+		ttl = time.Minute
+	}
+	return &sessionStore{ttl: ttl, buffer: buffer, sessions: make(map[string]*session)}
+}
+
+func newSessionToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// open issues a session to a new connection.
+func (st *sessionStore) open(conn *connWrapper) *session {
+	s := &session{token: newSessionToken(), conn: conn, holder: conn}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	conn.session = s
+	st.sessions[s.token] = s
+	return s
+}
+
+// detach keeps the subscriptions of a closed connection until the session expires, it returns false when
+// the connection does not own a session.
+func (st *sessionStore) detach(conn *connWrapper) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	s := conn.session
+	if s == nil || s.expired || s.holder != conn {
+		return false
+	}
+	s.conn = nil
+	s.expires = time.Now().Add(st.ttl)
+	s.timer = time.AfterFunc(st.ttl, func() { st.expire(s) })
+	return true
+}
+
+// expire drops a detached session, its subscriptions and buffered messages.
+func (st *sessionStore) expire(s *session) {
+	st.mu.Lock()
+	if s.expired || s.timer == nil || time.Now().Before(s.expires) {
+		st.mu.Unlock()
+		return
+	}
+	st.drop(s)
+	st.mu.Unlock()
+	st.release(s)
+}
+
+// drop removes s from the store, st.mu must be held.
+func (st *sessionStore) drop(s *session) {
+	delete(st.sessions, s.token)
+	s.expired = true
+	s.conn = nil
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+}
+
+// release frees what a dropped session holds, st.mu must not be held.
+func (st *sessionStore) release(s *session) {
+	if s.holder != nil && s.holder.session == s {
+		s.holder.subscriptions.ClearAll()
+	}
+	if err := st.buffer.Remove(s.token); err != nil {
+		logrus.WithError(err).Error("remove replay messages failed")
+	}
+}
+
+// resume moves the session of token to conn, the session issued to conn on connect is dropped. It returns
+// the connection holding the subscriptions of the session, which is still open when half open after a
+// network change. The session stays detached until attach, so the messages sent meanwhile are only
+// buffered and replayed in order.
+func (st *sessionStore) resume(conn *connWrapper, token string) (*session, *connWrapper, bool) {
+	st.mu.Lock()
+	s, ok := st.sessions[token]
+	if !ok || s == conn.session {
+		st.mu.Unlock()
+		return nil, nil, false
+	}
+	from := s.holder
+	current := conn.session
+	if current != nil {
+		st.drop(current)
+	}
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	s.conn = nil
+	s.holder = conn
+	conn.session = s
+	st.mu.Unlock()
+	if current != nil {
+		if err := st.buffer.Remove(current.token); err != nil {
+			logrus.WithError(err).Error("remove replay messages failed")
+		}
+	}
+	return s, from, true
+}
+
+// attach sends the next messages of s to conn, unless conn closed in the meantime.
+func (st *sessionStore) attach(s *session, conn *connWrapper) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if s.holder == conn && s.timer == nil && !s.expired {
+		s.conn = conn
+	}
+}
+
+// find returns the live session of conn, nil when conn has none.
+func (st *sessionStore) find(conn *connWrapper) *session {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if conn.session == nil || conn.session.expired {
+		return nil
+	}
+	return conn.session
+}
+
+func (st *sessionStore) token(conn *connWrapper) string {
+	if s := st.find(conn); s != nil {
+		return s.token
+	}
+	return ""
+}
+
+// detached returns the connections holding the subscriptions of the sessions not attached.
+func (st *sessionStore) detached() []Conn {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	var res []Conn
+	for _, s := range st.sessions {
+		if s.conn == nil && s.holder != nil {
+			res = append(res, s.holder)
+		}
+	}
+	return res
+}
+
+// send numbers the message of a subscription of s and keeps it for s resuming, it is sent at once when s
+// is attached.
+func (st *sessionStore) send(s *session, topic string, data any) errx.Error {
+	raw, err := util.Msgpack().Marshal(data)
+	if err != nil {
+		return err
+	}
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	seq, err := st.buffer.Append(s.token, topic, raw)
+	if err != nil {
+		return err
+	}
+	st.mu.Lock()
+	target := s.conn
+	st.mu.Unlock()
+	if target == nil {
+		return nil
+	}
+	return target.sendSeq(topic, seq, data)
+}
+
+// sendSubscribed sends a message of a subscription of conn, numbered and buffered when conn has a session.
+func sendSubscribed(conn Conn, topic string, data any) errx.Error {
+	c, ok := conn.(*connWrapper)
+	if !ok || c.sessions == nil {
+		return conn.Send(topic, data)
+	}
+	s := c.sessions.find(c)
+	if s == nil {
+		return conn.Send(topic, data)
+	}
+	return c.sessions.send(s, topic, data)
+}
+
+func (srv *server) openSession(conn *connWrapper) {
+	s := srv.sessions.open(conn)
+	if err := conn.Send(SessionTopic, SessionEvent{Token: s.token}); err != nil {
+		logrus.WithError(err).Error("send websocket session failed")
+	}
+}
+
+func (srv *server) resume(conn Conn, ev ResumeEvent) errx.Error {
+	c, ok := conn.(*connWrapper)
+	if !ok || srv.sessions == nil {
+		return errx.New("session resume is not enabled")
+	}
+	s, from, ok := srv.sessions.resume(c, ev.Token)
+	if !ok {
+		return conn.Send(SessionTopic, SessionEvent{Token: srv.sessions.token(c)})
+	}
+	if from != c {
+		c.subscriptions.adopt(&from.subscriptions)
+		from.Close()
+	}
+	// 訂閱係依原連線授權, 新連線未獲授權的topic不予保留, 由updateTopics回報錯誤
+	adopted := c.Subscriptions().Topics()
+	var authorized []string
+	for _, topic := range adopted {
+		if channel, ok := srv.findSubscribable(topic); ok && channel.Authorize(conn, topic) == nil {
+			authorized = append(authorized, topic)
+		}
+	}
+	c.Subscriptions().Retain(authorized)
+	topics := append([]string{SubscribeTopicsTopic}, adopted...)
+	if err := srv.updateTopics(conn, SubscribeTopicsEvent{Topics: topics}); err != nil {
+		return err
+	}
+	// 重播完成前即時訊息僅進入buffer, 由重播依序送出
+	s.sendMu.Lock()
+	for _, topic := range conn.Subscriptions().Topics() {
+		messages, err := srv.sessions.buffer.Since(s.token, topic, ev.Sequences[topic])
+		if err != nil {
+			logrus.WithError(err).WithField("topic", topic).Error("load replay messages failed")
+			continue
+		}
+		for _, m := range messages {
+			if err = c.sendSeq(topic, m.Seq, msgpack.RawMessage(m.Data)); err != nil {
+				s.sendMu.Unlock()
+				return err
+			}
+		}
+	}
+	srv.sessions.attach(s, c)
+	s.sendMu.Unlock()
+	return conn.Send(SessionTopic, SessionEvent{Token: s.token, Resumed: true})
+}
@@ -0,0 +1,71 @@
+package wsx
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/tencent-go/pkg/util"
+)
+
+// TopicParams are the values of the placeholders of a topic template, e.g. orderId of "order.{orderId}".
+type TopicParams map[string]string
+
+// topicTemplate matches the topics of a template, a placeholder matches one dot separated segment.
+type topicTemplate struct {
+	names []string
+	re    *regexp.Regexp
+}
+
+// parseTopicTemplate returns nil when the topic has no placeholder.
+func parseTopicTemplate(topic string) *topicTemplate {
+	matches := util.PlaceholderRegex.FindAllStringSubmatchIndex(topic, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+	t := &topicTemplate{}
+	var b strings.Builder
+	b.WriteString("^")
+	last := 0
+	for _, m := range matches {
+		b.WriteString(regexp.QuoteMeta(topic[last:m[0]]))
+		b.WriteString(`([^.]+)`)
+		t.names = append(t.names, topic[m[2]:m[3]])
+		last = m[1]
+	}
+	b.WriteString(regexp.QuoteMeta(topic[last:]))
+	b.WriteString("$")
+	t.re = regexp.MustCompile(b.String())
+	return t
+}
+
+// validTopicParam rejects the wildcards of nats subjects, the braces of placeholders and whitespaces, so that
+// a client can not subscribe e.g. "order.*" or the template "order.{orderId}" itself.
+func validTopicParam(value string) bool {
+	return !strings.ContainsAny(value, "*>{}") && strings.IndexFunc(value, unicode.IsSpace) < 0
+}
+
+func (t *topicTemplate) match(topic string) (TopicParams, bool) {
+	values := t.re.FindStringSubmatch(topic)
+	if values == nil {
+		return nil, false
+	}
+	params := make(TopicParams, len(t.names))
+	for i, name := range t.names {
+		params[name] = values[i+1]
+	}
+	return params, true
+}
+
+// formatTopic replaces the placeholders by args in order, placeholders without arg are kept.
+func formatTopic(template string, args ...string) string {
+	i := 0
+	return util.PlaceholderRegex.ReplaceAllStringFunc(template, func(match string) string {
+		if i < len(args) && args[i] != "" {
+			i++
+			return args[i-1]
+		}
+		i++
+		return match
+	})
+}
@@ -0,0 +1,68 @@
+package wsx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tencent-go/pkg/errx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopicTemplate(t *testing.T) {
+	tpl := parseTopicTemplate("order.{orderId}.item.{itemId}")
+	if !assert.NotNil(t, tpl) {
+		return
+	}
+	params, ok := tpl.match("order.1.item.2")
+	assert.True(t, ok)
+	assert.Equal(t, TopicParams{"orderId": "1", "itemId": "2"}, params)
+	_, ok = tpl.match("order.1.2.item.3")
+	assert.False(t, ok)
+	assert.Nil(t, parseTopicTemplate("order"))
+	assert.Equal(t, "order.1.item.{itemId}", formatTopic("order.{orderId}.item.{itemId}", "1"))
+}
+
+func TestTopicParamsRejected(t *testing.T) {
+	var authorized, subscribed int
+	channel := NewEventChannel[string]("order.{orderId}").
+		WithAuthorizer(func(conn Conn, params TopicParams) errx.Error {
+			authorized++
+			return nil
+		}).
+		WithParamsSubscriber(func(conn Conn, params TopicParams, send func(message string) errx.Error) (func(), errx.Error) {
+			subscribed++
+			return func() {}, nil
+		})
+
+	for _, topic := range []string{"order.{orderId}", "order.*", "order.>", "order.a b", "order.a\tb", "order.}"} {
+		err := channel.Authorize(nil, topic)
+		if assert.NotNil(t, err, topic) {
+			assert.Equal(t, errx.TypeValidation, err.Type())
+		}
+		_, err = channel.Subscribe(nil, topic)
+		assert.NotNil(t, err, topic)
+	}
+	assert.Zero(t, authorized)
+	assert.Zero(t, subscribed)
+
+	assert.Nil(t, channel.Authorize(nil, "order.o-1"))
+	_, err := channel.Subscribe(nil, "order.o-1")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, authorized)
+	assert.Equal(t, 1, subscribed)
+
+	t.Run("伺服器回覆未生效的topic", func(t *testing.T) {
+		srv := NewServer()
+		srv.RegisterSubscribableChannels(channel)
+		httpSrv := httptest.NewServer(http.HandlerFunc(srv.Upgrade))
+		defer httpSrv.Close()
+		c := dialTest(t, httpSrv, nil)
+		res := c.subscribe(t, "order.*", "order.{orderId}", "order.o-2")
+		assert.Equal(t, []string{"order.o-2", SubscribeTopicsTopic}, res.Topics)
+		if assert.Len(t, res.Errors, 2) {
+			assert.Equal(t, errx.TypeValidation, res.Errors["order.*"].Type)
+			assert.Equal(t, errx.TypeValidation, res.Errors["order.{orderId}"].Type)
+		}
+	})
+}
@@ -2,27 +2,82 @@ package wsx
 
 import (
 	"github.com/tencent-go/pkg/errx"
-	"github.com/tencent-go/pkg/util"
+	"github.com/tencent-go/pkg/types"
+	"github.com/tencent-go/pkg/validation"
 	"github.com/lxzan/gws"
 	"github.com/sirupsen/logrus"
 	"github.com/vmihailenco/msgpack/v5"
 )
 
+// Broadcast sends data to the connections, which should be the subscribers of the topic. When the server
+// enables sessions, the message is numbered and kept per session, including for a closed connection whose
+// session is detached, so it is replayed when the session resumes.
 func Broadcast[T any](channel EventChannelBuilder[T], connections []Conn, data T) {
-	payload, err := util.Msgpack().Marshal(sendMsgWrapper{Topic: channel.Topic(), Data: data})
-	if err != nil {
-		logrus.WithError(err).Error("marshal msgpack fail")
+	broadcastTopic(channel.Topic(), data, connections)
+}
+
+// broadcastTopic sends the message once per session, the connections without session share the frames.
+func broadcastTopic(topic string, data any, connections []Conn) {
+	var plain []Conn
+	sent := make(map[*session]struct{})
+	for _, item := range connections {
+		conn, ok := item.(*connWrapper)
+		if !ok || conn.sessions == nil {
+			plain = append(plain, item)
+			continue
+		}
+		s := conn.sessions.find(conn)
+		if s == nil {
+			plain = append(plain, item)
+			continue
+		}
+		if _, ok = sent[s]; ok {
+			continue
+		}
+		sent[s] = struct{}{}
+		if err := conn.sessions.send(s, topic, data); err != nil {
+			logrus.WithError(err).WithField("topic", topic).Error("broadcast failed")
+		}
+	}
+	broadcast(sendMsgWrapper{Topic: topic, Data: data}, plain)
+}
+
+// broadcast encodes the frame once per format, gws caches the compressed frames of each broadcaster.
+func broadcast(m sendMsgWrapper, connections []Conn) {
+	if len(connections) == 0 {
 		return
 	}
-	var b = gws.NewBroadcaster(gws.OpcodeBinary, payload)
-	defer func() { _ = b.Close() }()
+	broadcasters := make(map[Format]*gws.Broadcaster)
+	payloads := make(map[Format][]byte)
+	defer func() {
+		for _, b := range broadcasters {
+			_ = b.Close()
+		}
+	}()
 	for _, item := range connections {
 		conn, ok := item.(*connWrapper)
 		if !ok {
 			logrus.Error("conn to connWrapper failed")
 			continue
 		}
-		_ = b.Broadcast(conn.Conn)
+		payload, ok := payloads[conn.format]
+		if !ok {
+			var err errx.Error
+			if payload, err = conn.format.encode(m); err != nil {
+				logrus.WithError(err).Errorf("marshal %s fail", conn.format)
+			} else {
+				broadcasters[conn.format] = gws.NewBroadcaster(conn.format.opcode(), payload)
+			}
+			payloads[conn.format] = payload
+		}
+		if payload == nil {
+			continue
+		}
+		if conn.queue != nil {
+			conn.enqueue(outbound{key: m.Topic, topic: m.Topic, opcode: conn.format.opcode(), payload: payload})
+			continue
+		}
+		_ = broadcasters[conn.format].Broadcast(conn.Conn)
 	}
 }
 
@@ -30,23 +85,48 @@ func SendMessage[T any](channel EventChannelBuilder[T], connection Conn, data T)
 	if !connection.Subscriptions().Exists(channel.Topic()) {
 		return errx.Newf("topic %s undescribe", channel.Topic())
 	}
-	return connection.Send(channel.Topic(), data)
+	return sendSubscribed(connection, channel.Topic(), data)
 }
 
 type receiveMsgWrapper struct {
 	Topic string             `json:"topic"`
+	ID    string             `json:"id,omitempty"` // 客戶端指定時, 伺服器以相同的id回覆結果或錯誤
 	Data  msgpack.RawMessage `json:"data"`
 }
 
 type sendMsgWrapper struct {
-	Topic string `json:"topic"`
-	Data  any    `json:"data"`
+	Topic string       `json:"topic"`
+	ID    string       `json:"id,omitempty"`
+	Seq   uint64       `json:"seq,omitempty"` // 啟用session時, 訂閱的訊息依session及topic編號, 客戶端可據此去重及發現遺漏
+	Data  any          `json:"data"`
+	Error *ErrorDetail `json:"error,omitempty"`
 }
 
 type SubscribeTopicsEvent struct {
-	Topics []string `json:"topics"`
+	Topics []string                `json:"topics"`
+	Errors map[string]*ErrorDetail `json:"errors,omitempty"` // 伺服器回覆: 未生效的topic及原因, 如未知或未授權
 }
 
 type ErrorEvent struct {
 	Message string `json:"message"`
 }
+
+// ErrorDetail is the error of a reply, it has the same fields as the error of rest and rpc.
+type ErrorDetail struct {
+	Code       int                   `json:"code"`
+	Message    string                `json:"message"`
+	Type       errx.Type             `json:"type"`
+	Violations validation.Violations `json:"violations,omitempty"`
+}
+
+func newErrorDetail(err errx.Error, locale types.Locale) *ErrorDetail {
+	d := &ErrorDetail{
+		Code: err.Code(),
+		Type: err.Type(),
+	}
+	if err.Type() != errx.TypeInternal {
+		d.Message = errx.Localize(err, locale)
+		d.Violations = validation.GetViolations(err).Localized(locale)
+	}
+	return d
+}
@@ -0,0 +1,465 @@
+package wsxclient
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tencent-go/pkg/ctxx"
+	"github.com/tencent-go/pkg/errx"
+	"github.com/tencent-go/pkg/util"
+	"github.com/tencent-go/pkg/wsx"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/lxzan/gws"
+	"github.com/sirupsen/logrus"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Client is a connection to a wsx server, the subscriptions are sent again after every reconnection. When
+// the server enables the sessions, the client resumes its session and the missed messages are replayed.
+type Client interface {
+	Topics() []string // topics confirmed by the server
+	Connected() bool
+	Unsubscribe(topics ...string) errx.Error
+	OnConnected(fn func())           // called after every connection once the subscriptions are sent
+	OnError(fn func(message string)) // error_notification of the server and topics the server refused to subscribe
+	Close()
+}
+
+type frame struct {
+	Topic string             `json:"topic"`
+	ID    string             `json:"id,omitempty"`
+	Seq   uint64             `json:"seq,omitempty"`
+	Data  msgpack.RawMessage `json:"data"`
+	Error *wsx.ErrorDetail   `json:"error,omitempty"`
+}
+
+type outFrame struct {
+	Topic string `json:"topic"`
+	ID    string `json:"id,omitempty"`
+	Data  any    `json:"data"`
+}
+
+// Dial connects to url, e.g. ws://localhost:8080/ws. It fails when the first connection fails, the
+// connections lost later are reestablished in the background.
+func Dial(url string, opts ...Option) (Client, errx.Error) {
+	c := &client{
+		url:          url,
+		options:      getOptions(opts...),
+		handlers:     make(map[string]func(data msgpack.RawMessage)),
+		pending:      make(map[string]chan frame),
+		sequences:    make(map[string]uint64),
+		disconnected: make(chan struct{}, 1),
+		done:         make(chan struct{}),
+	}
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+	go c.reconnectLoop()
+	return c, nil
+}
+
+// Subscribe decodes the messages of the channel and passes them to handler, it replaces the previous
+// handler of the topic.
+func Subscribe[T any](c Client, channel wsx.EventChannelBuilder[T], handler func(message T)) errx.Error {
+	cl, ok := c.(*client)
+	if !ok {
+		return errx.New("client is not created by Dial")
+	}
+	topic := channel.Topic()
+	cl.mu.Lock()
+	cl.handlers[topic] = func(data msgpack.RawMessage) {
+		var message T
+		if err := cl.options.serializer().Unmarshal(data, &message); err != nil {
+			logrus.WithError(err).Errorf("decode websocket message of topic %s failed", topic)
+			return
+		}
+		handler(message)
+	}
+	cl.mu.Unlock()
+	return cl.sendTopics()
+}
+
+// Publish sends data to a publishable channel of the server.
+func Publish[T any](c Client, channel wsx.EventChannelBuilder[T], data T) errx.Error {
+	cl, ok := c.(*client)
+	if !ok {
+		return errx.New("client is not created by Dial")
+	}
+	return cl.send(outFrame{Topic: channel.Topic(), Data: data})
+}
+
+// Request sends data to a request channel and waits for the reply until ctx is done.
+func Request[I any, O any](ctx ctxx.Context, c Client, channel wsx.RequestChannelBuilder[I, O], data I) (*O, errx.Error) {
+	cl, ok := c.(*client)
+	if !ok {
+		return nil, errx.New("client is not created by Dial")
+	}
+	id := strconv.FormatUint(cl.nextID.Add(1), 10)
+	reply := make(chan frame, 1)
+	cl.mu.Lock()
+	cl.pending[id] = reply
+	cl.mu.Unlock()
+	defer func() {
+		cl.mu.Lock()
+		delete(cl.pending, id)
+		cl.mu.Unlock()
+	}()
+	if err := cl.send(outFrame{Topic: channel.Topic(), ID: id, Data: data}); err != nil {
+		return nil, err
+	}
+	select {
+	case <-ctx.Done():
+		return nil, errx.Wrap(ctx.Err()).WithType(errx.TypeTimeout).AppendMsgf("request websocket topic %s failed", channel.Topic()).Err()
+	case f, ok := <-reply:
+		if !ok {
+			return nil, errx.Define().WithType(errx.TypeNetwork).WithMsg("websocket disconnected before the reply").Err()
+		}
+		if f.Error != nil {
+			return nil, detailError(f.Error)
+		}
+		var output O
+		if err := cl.options.serializer().Unmarshal(f.Data, &output); err != nil {
+			return nil, err
+		}
+		return &output, nil
+	}
+}
+
+func detailError(d *wsx.ErrorDetail) errx.Error {
+	if len(d.Violations) > 0 {
+		return errx.Wrap(d.Violations).WithMsg(d.Message).WithType(d.Type).WithCode(d.Code).Err()
+	}
+	return errx.Define().WithMsg(d.Message).WithType(d.Type).WithCode(d.Code).Err()
+}
+
+type client struct {
+	url          string
+	options      options
+	mu           sync.RWMutex
+	conn         *gws.Conn
+	handlers     map[string]func(data msgpack.RawMessage)
+	pending      map[string]chan frame // replies awaited by Request, keyed by id
+	nextID       atomic.Uint64
+	topics       []string
+	token        string            // session issued by the server, empty when sessions are disabled
+	sequences    map[string]uint64 // last seq received of each topic
+	onConnected  []func()
+	onError      []func(message string)
+	closed       bool
+	disconnected chan struct{}
+	done         chan struct{}
+}
+
+func (c *client) connect() errx.Error {
+	header := c.options.header.Clone()
+	header.Set("Sec-WebSocket-Protocol", c.options.subprotocol())
+	conn, _, e := gws.NewClient(&eventHandler{c}, &gws.ClientOption{
+		Addr:              c.url,
+		RequestHeader:     header,
+		HandshakeTimeout:  c.options.handshakeTimeout,
+		PermessageDeflate: gws.PermessageDeflate{Enabled: c.options.compression},
+	})
+	if e != nil {
+		return errx.Wrap(e).WithType(errx.TypeNetwork).AppendMsgf("dial websocket %s failed", c.url).Err()
+	}
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		_ = conn.WriteClose(1000, nil)
+		return errx.New("websocket client closed")
+	}
+	c.conn = conn
+	// 在ReadLoop收到新session之前取得舊session
+	resume := c.resumeEvent()
+	c.mu.Unlock()
+	go conn.ReadLoop()
+	if err := c.resume(resume); err != nil {
+		return err
+	}
+	if err := c.sendTopics(); err != nil {
+		return err
+	}
+	c.mu.RLock()
+	listeners := c.onConnected
+	c.mu.RUnlock()
+	for _, fn := range listeners {
+		fn()
+	}
+	return nil
+}
+
+func (c *client) reconnectLoop() {
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-c.disconnected:
+		}
+		if !c.options.reconnect {
+			c.Close()
+			return
+		}
+		for attempt := 0; ; attempt++ {
+			select {
+			case <-c.done:
+				return
+			case <-time.After(c.options.delay(attempt)):
+			}
+			err := c.connect()
+			if err == nil {
+				break
+			}
+			logrus.WithError(err).Warnf("reconnect websocket %s failed, attempt %d", c.url, attempt+1)
+		}
+	}
+}
+
+// resumeEvent is the previous session and its sequences, c.mu must be held.
+func (c *client) resumeEvent() wsx.ResumeEvent {
+	ev := wsx.ResumeEvent{Token: c.token, Sequences: make(map[string]uint64, len(c.sequences))}
+	for topic, seq := range c.sequences {
+		ev.Sequences[topic] = seq
+	}
+	return ev
+}
+
+// resume asks the server to move the previous session to the connection, the server handles it before
+// the topics sent next.
+func (c *client) resume(ev wsx.ResumeEvent) errx.Error {
+	if ev.Token == "" {
+		return nil
+	}
+	return c.send(outFrame{Topic: wsx.ResumeTopic, Data: ev})
+}
+
+// sendTopics sends the complete list of topics, the server keeps only those and confirms them.
+func (c *client) sendTopics() errx.Error {
+	c.mu.RLock()
+	topics := []string{wsx.SubscribeTopicsTopic}
+	for topic := range c.handlers {
+		topics = append(topics, topic)
+	}
+	c.mu.RUnlock()
+	sort.Strings(topics)
+	return c.send(outFrame{Topic: wsx.SubscribeTopicsTopic, Data: wsx.SubscribeTopicsEvent{Topics: topics}})
+}
+
+func (c *client) send(f outFrame) errx.Error {
+	c.mu.RLock()
+	conn := c.conn
+	c.mu.RUnlock()
+	if conn == nil {
+		return errx.Define().WithType(errx.TypeNetwork).WithMsg("websocket is not connected").Err()
+	}
+	payload, err := c.options.serializer().Marshal(f)
+	if err != nil {
+		return err
+	}
+	if e := conn.WriteMessage(c.options.opcode(), payload); e != nil {
+		return errx.Wrap(e).WithType(errx.TypeNetwork).AppendMsgf("send websocket message of topic %s failed", f.Topic).Err()
+	}
+	return nil
+}
+
+func (c *client) Topics() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]string(nil), c.topics...)
+}
+
+func (c *client) Connected() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.conn != nil
+}
+
+func (c *client) Unsubscribe(topics ...string) errx.Error {
+	c.mu.Lock()
+	for _, topic := range topics {
+		delete(c.handlers, topic)
+		delete(c.sequences, topic)
+	}
+	c.mu.Unlock()
+	return c.sendTopics()
+}
+
+func (c *client) OnConnected(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onConnected = append(c.onConnected, fn)
+}
+
+func (c *client) OnError(fn func(message string)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onError = append(c.onError, fn)
+}
+
+func (c *client) Close() {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.closed = true
+	conn := c.conn
+	c.conn = nil
+	close(c.done)
+	c.mu.Unlock()
+	if conn != nil {
+		_ = conn.WriteClose(1000, nil)
+	}
+}
+
+func (c *client) dispatch(f frame) {
+	if f.ID != "" {
+		c.mu.RLock()
+		reply, ok := c.pending[f.ID]
+		c.mu.RUnlock()
+		if ok {
+			select {
+			case reply <- f:
+			default:
+			}
+		}
+		return
+	}
+	switch f.Topic {
+	case wsx.SubscribeTopicsTopic:
+		var ev wsx.SubscribeTopicsEvent
+		if err := c.options.serializer().Unmarshal(f.Data, &ev); err != nil {
+			logrus.WithError(err).Error("decode websocket topics failed")
+			return
+		}
+		c.mu.Lock()
+		c.topics = ev.Topics
+		c.mu.Unlock()
+		topics := make([]string, 0, len(ev.Errors))
+		for topic := range ev.Errors {
+			topics = append(topics, topic)
+		}
+		sort.Strings(topics)
+		for _, topic := range topics {
+			c.notifyError(fmt.Sprintf("topic: %s %s", topic, ev.Errors[topic].Message))
+		}
+		return
+	case wsx.ErrorTopic:
+		var ev wsx.ErrorEvent
+		if err := c.options.serializer().Unmarshal(f.Data, &ev); err != nil {
+			logrus.WithError(err).Error("decode websocket error failed")
+			return
+		}
+		c.notifyError(ev.Message)
+		return
+	case wsx.SessionTopic:
+		var ev wsx.SessionEvent
+		if err := c.options.serializer().Unmarshal(f.Data, &ev); err != nil {
+			logrus.WithError(err).Error("decode websocket session failed")
+			return
+		}
+		c.mu.Lock()
+		if !ev.Resumed && ev.Token != c.token {
+			// seq依session編號, 新session從頭開始
+			c.sequences = make(map[string]uint64)
+		}
+		c.token = ev.Token
+		c.mu.Unlock()
+		return
+	}
+	c.mu.Lock()
+	handler, ok := c.handlers[f.Topic]
+	if ok && f.Seq > 0 {
+		// 重播與即時訊息可能重複
+		if f.Seq <= c.sequences[f.Topic] {
+			ok = false
+		} else {
+			c.sequences[f.Topic] = f.Seq
+		}
+	}
+	c.mu.Unlock()
+	if ok {
+		handler(f.Data)
+	}
+}
+
+func (c *client) notifyError(message string) {
+	c.mu.RLock()
+	listeners := c.onError
+	c.mu.RUnlock()
+	if len(listeners) == 0 {
+		logrus.Warnf("websocket error notification: %s", message)
+	}
+	for _, fn := range listeners {
+		fn(message)
+	}
+}
+
+// decodeFrame leaves the data encoded in the format of the connection.
+func (c *client) decodeFrame(payload []byte) (frame, errx.Error) {
+	if c.options.format != wsx.FormatJson {
+		var f frame
+		err := util.Msgpack().Unmarshal(payload, &f)
+		return f, err
+	}
+	var f struct {
+		Topic string              `json:"topic"`
+		ID    string              `json:"id,omitempty"`
+		Seq   uint64              `json:"seq,omitempty"`
+		Data  jsoniter.RawMessage `json:"data"`
+		Error *wsx.ErrorDetail    `json:"error,omitempty"`
+	}
+	if err := util.Json().Unmarshal(payload, &f); err != nil {
+		return frame{}, err
+	}
+	return frame{Topic: f.Topic, ID: f.ID, Seq: f.Seq, Data: msgpack.RawMessage(f.Data), Error: f.Error}, nil
+}
+
+type eventHandler struct {
+	c *client
+}
+
+func (h *eventHandler) OnOpen(socket *gws.Conn) {}
+
+func (h *eventHandler) OnClose(socket *gws.Conn, err error) {
+	c := h.c
+	c.mu.Lock()
+	if c.conn != socket {
+		c.mu.Unlock()
+		return
+	}
+	c.conn = nil
+	c.topics = nil
+	closed := c.closed
+	for id, reply := range c.pending {
+		close(reply)
+		delete(c.pending, id)
+	}
+	c.mu.Unlock()
+	if !closed {
+		logrus.WithError(err).Debugf("websocket %s disconnected", c.url)
+		select {
+		case c.disconnected <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (h *eventHandler) OnPing(socket *gws.Conn, payload []byte) {
+	_ = socket.WritePong(payload)
+}
+
+func (h *eventHandler) OnPong(socket *gws.Conn, payload []byte) {}
+
+func (h *eventHandler) OnMessage(socket *gws.Conn, message *gws.Message) {
+	defer func() { _ = message.Close() }()
+	f, err := h.c.decodeFrame(message.Bytes())
+	if err != nil {
+		logrus.WithError(err).Error("decode websocket frame failed")
+		return
+	}
+	h.c.dispatch(f)
+}
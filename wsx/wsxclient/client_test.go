@@ -0,0 +1,229 @@
+package wsxclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tencent-go/pkg/ctxx"
+	"github.com/tencent-go/pkg/errx"
+	"github.com/tencent-go/pkg/types"
+	"github.com/tencent-go/pkg/wsx"
+	"github.com/stretchr/testify/assert"
+)
+
+type sum struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+func TestClient(t *testing.T) {
+	greeting := wsx.NewEventChannel[string]("greeting").WithSubscriber(func(conn wsx.Conn, send func(message string) errx.Error) (func(), errx.Error) {
+		return func() {}, send("hello")
+	})
+	echo := wsx.NewEventChannel[string]("echo").WithPublisher(func(conn wsx.Conn, data string) errx.Error {
+		if data == "" {
+			return errx.Validation.WithMsg("empty message").Err()
+		}
+		return conn.Send(greeting.Topic(), "echo: "+data)
+	})
+	order := wsx.NewEventChannel[string]("order.{orderId}").
+		WithAuthorizer(func(conn wsx.Conn, params wsx.TopicParams) errx.Error {
+			if params["orderId"] == "secret" {
+				return errx.Define().WithType(errx.TypeAuthorization).WithMsg("forbidden").Err()
+			}
+			return nil
+		}).
+		WithParamsSubscriber(func(conn wsx.Conn, params wsx.TopicParams, send func(message string) errx.Error) (func(), errx.Error) {
+			return func() {}, send("order " + params["orderId"])
+		})
+	add := wsx.NewRequestChannel[sum, int]("add").WithHandler(func(conn wsx.Conn, data sum) (*int, errx.Error) {
+		if data.A < 0 || data.B < 0 {
+			return nil, errx.Define().WithType(errx.TypeValidation).WithMsg("negative").Err()
+		}
+		res := data.A + data.B
+		return &res, nil
+	})
+
+	for _, format := range []wsx.Format{wsx.FormatMsgpack, wsx.FormatJson} {
+		t.Run(string(format), func(t *testing.T) {
+			testClient(t, format, greeting, echo, order, add)
+		})
+	}
+}
+
+func testClient(t *testing.T, format wsx.Format, greeting, echo, order wsx.EventChannelBuilder[string], add wsx.RequestChannelBuilder[sum, int]) {
+	var (
+		mu    sync.Mutex
+		conns []wsx.Conn
+	)
+	srv := wsx.NewServer()
+	if format == wsx.FormatJson {
+		srv.SetCompression(0)
+	}
+	news := wsx.NewEventChannel[string]("news")
+	srv.SetResume(wsx.ResumeConfig{})
+	srv.RegisterSubscribableChannels(greeting, order, news)
+	srv.RegisterPublishableChannels(echo, add)
+	srv.OnConnected(func(conn wsx.Conn) {
+		mu.Lock()
+		defer mu.Unlock()
+		conns = append(conns, conn)
+		assert.Equal(t, format, conn.Format())
+	})
+	httpSrv := httptest.NewServer(http.HandlerFunc(srv.Upgrade))
+	defer httpSrv.Close()
+
+	connected := make(chan struct{}, 4)
+	errs := make(chan string, 4)
+	messages := make(chan string, 16)
+	c, err := Dial("ws"+strings.TrimPrefix(httpSrv.URL, "http"), WithBackoff(10*time.Millisecond, 100*time.Millisecond), WithFormat(format), WithCompression(), WithHeader("Accept-Language", "zh-TW"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer c.Close()
+	c.OnConnected(func() { connected <- struct{}{} })
+	c.OnError(func(message string) { errs <- message })
+
+	receive := func() string {
+		select {
+		case m := <-messages:
+			return m
+		case <-time.After(3 * time.Second):
+			t.Fatal("no message received")
+			return ""
+		}
+	}
+
+	t.Run("subscribe", func(t *testing.T) {
+		assert.NoError(t, Subscribe(c, greeting, func(message string) { messages <- message }))
+		assert.Equal(t, "hello", receive())
+		assert.Eventually(t, func() bool {
+			return len(c.Topics()) == 2
+		}, 3*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("publish", func(t *testing.T) {
+		assert.NoError(t, Publish(c, echo, "hi"))
+		assert.Equal(t, "echo: hi", receive())
+
+		errx.RegisterLocaleMsg("topic: %s", types.Locale("zh"), "主題 %s")
+		errx.RegisterLocaleMsg("empty message", types.Locale("zh"), "訊息為空")
+		t.Cleanup(func() {
+			errx.UnregisterLocaleMsg("topic: %s", types.Locale("zh"))
+			errx.UnregisterLocaleMsg("empty message", types.Locale("zh"))
+		})
+		assert.NoError(t, Publish(c, echo, ""))
+		select {
+		case m := <-errs:
+			assert.Equal(t, "主題 echo: 訊息為空", m)
+		case <-time.After(3 * time.Second):
+			t.Fatal("no error received")
+		}
+	})
+
+	t.Run("parameterized topic", func(t *testing.T) {
+		assert.NoError(t, Subscribe(c, order.WithArgs("42"), func(message string) { messages <- message }))
+		assert.Equal(t, "order 42", receive())
+		assert.NoError(t, Subscribe(c, order.WithArgs("secret"), func(message string) { messages <- message }))
+		select {
+		case m := <-errs:
+			assert.Equal(t, "topic: order.secret forbidden", m)
+		case <-time.After(3 * time.Second):
+			t.Fatal("no denial received")
+		}
+		assert.NoError(t, c.Unsubscribe("order.42", "order.secret"))
+	})
+
+	t.Run("request", func(t *testing.T) {
+		ctx, cancel := ctxx.WithTimeout(ctxx.Background(), 3*time.Second)
+		defer cancel()
+		res, err := Request(ctx, c, add, sum{A: 1, B: 2})
+		if assert.NoError(t, err) {
+			assert.Equal(t, 3, *res)
+		}
+		_, err = Request(ctx, c, add, sum{A: -1})
+		if assert.Error(t, err) {
+			assert.Equal(t, errx.TypeValidation, err.Type())
+			assert.Equal(t, "negative", err.Error())
+		}
+	})
+
+	t.Run("resume and replay", func(t *testing.T) {
+		received := make(chan string, 4)
+		assert.NoError(t, Subscribe(c, news, func(message string) { received <- message }))
+		assert.Eventually(t, func() bool {
+			return len(c.Topics()) == 3
+		}, 3*time.Second, 10*time.Millisecond)
+		mu.Lock()
+		current := append([]wsx.Conn(nil), conns...)
+		mu.Unlock()
+		wsx.Broadcast(news, current, "a")
+		select {
+		case m := <-received:
+			assert.Equal(t, "a", m)
+		case <-time.After(3 * time.Second):
+			t.Fatal("no message received")
+		}
+		for _, conn := range current {
+			conn.Close()
+		}
+		wsx.Broadcast(news, current, "b")
+		select {
+		case <-connected:
+		case <-time.After(3 * time.Second):
+			t.Fatal("not reconnected")
+		}
+		select {
+		case m := <-received:
+			assert.Equal(t, "b", m)
+		case <-time.After(3 * time.Second):
+			t.Fatal("missed message not replayed")
+		}
+		// 訂閱隨session保留, 不會重新訂閱
+		select {
+		case m := <-messages:
+			t.Fatalf("unexpected message %s", m)
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+
+	t.Run("reconnect and keep subscriptions", func(t *testing.T) {
+		received := make(chan string, 4)
+		assert.NoError(t, Subscribe(c, news, func(message string) { received <- message }))
+		mu.Lock()
+		current := append([]wsx.Conn(nil), conns...)
+		mu.Unlock()
+		for _, conn := range current {
+			conn.Close()
+		}
+		select {
+		case <-connected:
+		case <-time.After(3 * time.Second):
+			t.Fatal("not reconnected")
+		}
+		assert.True(t, c.Connected())
+		// 新連線持有訂閱時session已恢復
+		assert.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			current = append([]wsx.Conn(nil), conns...)
+			return current[len(current)-1].Subscriptions().Exists(news.Topic())
+		}, 3*time.Second, 10*time.Millisecond)
+		wsx.Broadcast(news, current, "c")
+		select {
+		case m := <-received:
+			assert.Equal(t, "c", m)
+		case <-time.After(3 * time.Second):
+			t.Fatal("no message received")
+		}
+		select {
+		case m := <-received:
+			t.Fatalf("duplicated message %s", m)
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+}
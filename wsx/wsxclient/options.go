@@ -0,0 +1,122 @@
+package wsxclient
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/tencent-go/pkg/util"
+	"github.com/tencent-go/pkg/wsx"
+	"github.com/lxzan/gws"
+)
+
+const (
+	defaultBackoff          = 500 * time.Millisecond
+	defaultMaxBackoff       = 30 * time.Second
+	defaultHandshakeTimeout = 5 * time.Second
+)
+
+type options struct {
+	header           http.Header
+	backoff          time.Duration
+	maxBackoff       time.Duration
+	handshakeTimeout time.Duration
+	reconnect        bool
+	format           wsx.Format
+	compression      bool
+}
+
+type Option func(*options)
+
+// WithHeader adds a header to the handshake request, e.g. Authorization.
+func WithHeader(key, value string) Option {
+	return func(o *options) {
+		if o.header == nil {
+			o.header = make(http.Header)
+		}
+		o.header.Add(key, value)
+	}
+}
+
+// WithBackoff sets the delay before the first reconnection, it doubles on every failed attempt
+// up to max. The defaults are 500ms and 30s.
+func WithBackoff(backoff, max time.Duration) Option {
+	return func(o *options) {
+		o.backoff = backoff
+		o.maxBackoff = max
+	}
+}
+
+// WithHandshakeTimeout bounds the dial and the handshake, the default is 5s.
+func WithHandshakeTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.handshakeTimeout = d
+	}
+}
+
+// WithoutReconnect leaves the client closed when the connection is lost.
+func WithoutReconnect() Option {
+	return func(o *options) {
+		o.reconnect = false
+	}
+}
+
+// WithFormat selects the framing through the subprotocol, the default is msgpack.
+func WithFormat(format wsx.Format) Option {
+	return func(o *options) {
+		o.format = format
+	}
+}
+
+// WithCompression offers permessage-deflate, it is used when the server enables it too.
+func WithCompression() Option {
+	return func(o *options) {
+		o.compression = true
+	}
+}
+
+func getOptions(opts ...Option) options {
+	o := options{
+		header:           make(http.Header),
+		backoff:          defaultBackoff,
+		maxBackoff:       defaultMaxBackoff,
+		handshakeTimeout: defaultHandshakeTimeout,
+		reconnect:        true,
+		format:           wsx.FormatMsgpack,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func (o options) subprotocol() string {
+	if o.format == wsx.FormatJson {
+		return wsx.SubprotocolJson
+	}
+	return wsx.SubprotocolMsgpack
+}
+
+func (o options) serializer() util.Serializer {
+	if o.format == wsx.FormatJson {
+		return util.Json()
+	}
+	return util.Msgpack()
+}
+
+func (o options) opcode() gws.Opcode {
+	if o.format == wsx.FormatJson {
+		return gws.OpcodeText
+	}
+	return gws.OpcodeBinary
+}
+
+func (o options) delay(attempt int) time.Duration {
+	if o.backoff <= 0 {
+		return 0
+	}
+	d := o.backoff << attempt
+	if d < o.backoff || d > o.maxBackoff {
+		return o.maxBackoff
+	}
+	return d
+}